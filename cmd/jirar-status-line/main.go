@@ -0,0 +1,52 @@
+// Package main prints the line of jirar status-line for shell prompts and
+// status bars. It imports only what the line needs, so it starts in a few
+// milliseconds where the jirar executable takes several times as long.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"jirar/internal/config"
+	"jirar/internal/statusline"
+)
+
+func main() {
+	var (
+		profile  = flag.String("profile", "", "Profile to use (default: $JIRAR_PROFILE or current_profile)")
+		format   = flag.String("format", "", "Go template of the line, or waybar (default: ui.status_line)")
+		path     = flag.String("config", "", "Config file (default: ~/.config/jirar/config.yaml)")
+		markRead = flag.Bool("mark-read", false, "Mark all comments read first")
+	)
+	flag.Parse()
+
+	if err := run(*profile, *format, *path, *markRead); err != nil {
+		fmt.Fprintln(os.Stderr, "jirar-status-line:", err)
+		os.Exit(1)
+	}
+}
+
+func run(profile, format, path string, markRead bool) error {
+	cfg, err := config.New()
+	if err != nil {
+		return err
+	}
+	if path != "" {
+		if err := cfg.Load(path); err != nil {
+			return err
+		}
+	}
+	if err := cfg.SelectProfile(profile); err != nil {
+		return err
+	}
+	if format == "" {
+		format = cfg.UI.StatusLine
+	}
+	return statusline.Print(os.Stdout, statusline.Options{
+		Profile:    cfg.ActiveProfile,
+		Format:     format,
+		StaleAfter: 3 * cfg.Daemon.Refresh,
+		MarkRead:   markRead,
+	})
+}
@@ -0,0 +1,238 @@
+// Package board arranges issues into the columns and swimlanes of an
+// agile board and renders them as lanes of cards.
+package board
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"jirar/internal/jira"
+)
+
+// Swimlane groupings.
+const (
+	SwimlaneNone     = "none"
+	SwimlaneAssignee = "assignee"
+	SwimlaneEpic     = "epic"
+)
+
+// Swimlanes are the values of jirar board --swimlanes.
+var Swimlanes = []string{SwimlaneNone, SwimlaneAssignee, SwimlaneEpic}
+
+// Lane titles for issues without an assignee or epic.
+const (
+	noAssignee = "Unassigned"
+	noEpic     = "No epic"
+)
+
+// Board is a board's issues arranged into columns.
+type Board struct {
+	ID      int      `json:"id"`
+	Name    string   `json:"name"`
+	Columns []Column `json:"columns"`
+	// Swimlanes are the lane titles in display order; empty without
+	// swimlanes
+	Swimlanes []string `json:"swimlanes,omitempty"`
+
+	config jira.BoardConfiguration
+}
+
+// Column is a board column with its cards in board rank order.
+type Column struct {
+	Name  string `json:"name"`
+	Cards []Card `json:"cards"`
+	// StatusIDs are the statuses mapped to the column
+	StatusIDs []string `json:"-"`
+}
+
+// Points sums the estimates of the cards in the column.
+func (c Column) Points() float64 {
+	var total float64
+	for _, card := range c.Cards {
+		total += card.Points
+	}
+	return total
+}
+
+// Card is an issue on the board.
+type Card struct {
+	Key      string  `json:"key"`
+	Summary  string  `json:"summary"`
+	Status   string  `json:"status"`
+	Assignee string  `json:"assignee,omitempty"`
+	Points   float64 `json:"points,omitempty"`
+	Swimlane string  `json:"swimlane,omitempty"`
+
+	Issue jira.Issue `json:"-"`
+}
+
+// Initials returns the initials of the assignee, e.g. "JD" for Jane Doe.
+func (c Card) Initials() string {
+	var initials []rune
+	for _, word := range strings.Fields(c.Assignee) {
+		for _, r := range word {
+			initials = append(initials, r)
+			break
+		}
+		if len(initials) == 2 {
+			break
+		}
+	}
+	return strings.ToUpper(string(initials))
+}
+
+// Fields returns the issue fields needed to build a board with cfg.
+func Fields(cfg *jira.BoardConfiguration) []string {
+	fields := []string{"parent"}
+	if id := cfg.Estimation.Field.FieldID; id != "" {
+		fields = append(fields, id)
+	}
+	return fields
+}
+
+// New arranges issues into the columns of cfg, grouping them into
+// swimlanes by assignee or epic. Issues whose status is not mapped to a
+// column are left out, as on the board itself.
+func New(board jira.Board, cfg *jira.BoardConfiguration, issues []jira.Issue, swimlane string) (*Board, error) {
+	switch swimlane {
+	case "", SwimlaneNone, SwimlaneAssignee, SwimlaneEpic:
+	default:
+		return nil, fmt.Errorf("invalid swimlane %q (use %s)", swimlane, strings.Join(Swimlanes, ", "))
+	}
+
+	b := &Board{ID: board.ID, Name: board.Name, config: *cfg}
+	for _, col := range cfg.ColumnConfig.Columns {
+		column := Column{Name: col.Name, Cards: []Card{}}
+		for _, s := range col.Statuses {
+			column.StatusIDs = append(column.StatusIDs, s.ID)
+		}
+		b.Columns = append(b.Columns, column)
+	}
+
+	var lanes, trailing []string
+	seen := map[string]bool{}
+	for _, issue := range issues {
+		i := b.columnOf(issue.Fields.Status.ID)
+		if i < 0 {
+			continue
+		}
+
+		card := newCard(issue, cfg.Estimation.Field.FieldID)
+		card.Swimlane = laneOf(issue, swimlane)
+		if card.Swimlane != "" && !seen[card.Swimlane] {
+			seen[card.Swimlane] = true
+			if card.Swimlane == noAssignee || card.Swimlane == noEpic {
+				trailing = append(trailing, card.Swimlane)
+			} else {
+				lanes = append(lanes, card.Swimlane)
+			}
+		}
+		b.Columns[i].Cards = append(b.Columns[i].Cards, card)
+	}
+	b.Swimlanes = append(lanes, trailing...)
+	return b, nil
+}
+
+// columnOf returns the index of the column a status is mapped to, or -1.
+func (b *Board) columnOf(statusID string) int {
+	for i, col := range b.config.ColumnConfig.Columns {
+		if col.HasStatus(statusID) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Find returns the column and card index of the issue with key.
+func (b *Board) Find(key string) (int, int, bool) {
+	for c, col := range b.Columns {
+		for i, card := range col.Cards {
+			if card.Key == key {
+				return c, i, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// Lane returns the cards of a column in a swimlane; with no swimlanes all
+// cards of the column.
+func (b *Board) Lane(column int, swimlane string) []Card {
+	if len(b.Swimlanes) == 0 {
+		return b.Columns[column].Cards
+	}
+	var cards []Card
+	for _, card := range b.Columns[column].Cards {
+		if card.Swimlane == swimlane {
+			cards = append(cards, card)
+		}
+	}
+	return cards
+}
+
+// Ordered returns the cards of a column in display order: by swimlane,
+// then by rank.
+func (b *Board) Ordered(column int) []Card {
+	if len(b.Swimlanes) == 0 {
+		return b.Columns[column].Cards
+	}
+	var cards []Card
+	for _, lane := range b.Swimlanes {
+		cards = append(cards, b.Lane(column, lane)...)
+	}
+	return cards
+}
+
+// Transition picks the transition that moves an issue into a column.
+func (b *Board) Transition(transitions []jira.Transition, column int) (jira.Transition, error) {
+	col := b.config.ColumnConfig.Columns[column]
+	for _, t := range transitions {
+		if col.HasStatus(t.To.ID) {
+			return t, nil
+		}
+	}
+	return jira.Transition{}, fmt.Errorf("no transition leads into column %q", col.Name)
+}
+
+// newCard builds the card of an issue, reading points from pointsField.
+func newCard(issue jira.Issue, pointsField string) Card {
+	f := issue.Fields
+	card := Card{
+		Key:      issue.Key,
+		Summary:  f.Summary,
+		Status:   f.Status.Name,
+		Assignee: f.Assignee.DisplayName,
+		Issue:    issue,
+	}
+	if raw, ok := f.Custom[pointsField]; ok {
+		var points json.Number
+		if json.Unmarshal(raw, &points) == nil {
+			card.Points, _ = points.Float64()
+		}
+	}
+	return card
+}
+
+// laneOf returns the swimlane title of an issue.
+func laneOf(issue jira.Issue, swimlane string) string {
+	switch swimlane {
+	case SwimlaneAssignee:
+		if name := issue.Fields.Assignee.DisplayName; name != "" {
+			return name
+		}
+		return noAssignee
+	case SwimlaneEpic:
+		if p := issue.Fields.Parent; p != nil && strings.EqualFold(p.Fields.IssueType.Name, "epic") {
+			return p.Key + " " + p.Fields.Summary
+		}
+		return noEpic
+	}
+	return ""
+}
+
+// FormatPoints formats an estimate without trailing zeros.
+func FormatPoints(points float64) string {
+	return strconv.FormatFloat(points, 'f', -1, 64)
+}
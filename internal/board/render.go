@@ -0,0 +1,206 @@
+package board
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	"github.com/muesli/termenv"
+)
+
+// Layout constants of the drawing.
+const (
+	minColumnWidth = 18
+	columnGap      = 1
+	summaryLines   = 2
+	// CardHeight is the height of a drawn card including its border
+	CardHeight = summaryLines + 3
+)
+
+// Options control how a board is drawn.
+type Options struct {
+	// Width is the width to fit the columns into
+	Width int
+	// Colors enables ANSI colors
+	Colors bool
+	// Cursor highlights the header of Column and the Selected card
+	Cursor   bool
+	Column   int
+	Selected string
+}
+
+// styles are the lipgloss styles of a drawing.
+type styles struct {
+	plain    lipgloss.Style
+	header   lipgloss.Style
+	active   lipgloss.Style
+	lane     lipgloss.Style
+	card     lipgloss.Style
+	selected lipgloss.Style
+	key      lipgloss.Style
+	meta     lipgloss.Style
+	empty    lipgloss.Style
+}
+
+// newStyles builds the styles, without colors unless colors is set.
+func newStyles(colors bool) styles {
+	r := lipgloss.NewRenderer(io.Discard)
+	if colors {
+		r.SetColorProfile(termenv.ANSI)
+	} else {
+		r.SetColorProfile(termenv.Ascii)
+	}
+
+	card := r.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("8")).Padding(0, 1)
+	return styles{
+		plain:    r.NewStyle(),
+		header:   r.NewStyle().Bold(true),
+		active:   r.NewStyle().Bold(true).Underline(true).Foreground(lipgloss.Color("5")),
+		lane:     r.NewStyle().Bold(true).Foreground(lipgloss.Color("4")),
+		card:     card,
+		selected: card.BorderForeground(lipgloss.Color("5")).Border(lipgloss.ThickBorder()),
+		key:      r.NewStyle().Bold(true),
+		meta:     r.NewStyle().Foreground(lipgloss.Color("6")),
+		empty:    r.NewStyle().Foreground(lipgloss.Color("8")),
+	}
+}
+
+// columnWidth returns the width of each column to fit width.
+func (b *Board) columnWidth(width int) int {
+	n := max(len(b.Columns), 1)
+	return max((width-(n-1)*columnGap)/n, minColumnWidth)
+}
+
+// Render draws the board as side-by-side columns of cards, in a band per
+// swimlane. It returns the drawing and the line the selected card starts
+// on, or -1.
+func Render(b *Board, opts Options) (string, int) {
+	st := newStyles(opts.Colors)
+	width := b.columnWidth(opts.Width)
+	total := len(b.Columns)*width + (len(b.Columns)-1)*columnGap
+
+	var lines []string
+	selectedLine := -1
+
+	headers := make([]string, len(b.Columns))
+	for i, col := range b.Columns {
+		title := fmt.Sprintf("%s (%d)", col.Name, len(col.Cards))
+		if points := col.Points(); points > 0 {
+			title += " · " + FormatPoints(points)
+		}
+		style := st.header
+		if opts.Cursor && i == opts.Column {
+			style = st.active
+		}
+		headers[i] = st.plain.Width(width).Render(style.Render(fit(title, width)))
+	}
+	lines = append(lines, strings.Join(headers, strings.Repeat(" ", columnGap)))
+	lines = append(lines, strings.Repeat("─", total))
+
+	lanes := b.Swimlanes
+	if len(lanes) == 0 {
+		lanes = []string{""}
+	}
+	for _, lane := range lanes {
+		if lane != "" {
+			count := 0
+			for c := range b.Columns {
+				count += len(b.Lane(c, lane))
+			}
+			lines = append(lines, "", st.lane.Render(fit(fmt.Sprintf("▸ %s (%d)", lane, count), total)))
+		}
+
+		start := len(lines)
+		stacks := make([]string, len(b.Columns))
+		for c := range b.Columns {
+			cards := b.Lane(c, lane)
+			var drawn []string
+			for i, card := range cards {
+				style := st.card
+				if opts.Cursor && card.Key == opts.Selected {
+					style = st.selected
+					selectedLine = start + i*CardHeight
+				}
+				drawn = append(drawn, style.Width(width-2).Render(cardBody(card, width-4, st)))
+			}
+			if len(drawn) == 0 {
+				drawn = append(drawn, st.empty.Render(fit("  —", width)))
+			}
+			stacks[c] = st.plain.Width(width).Render(strings.Join(drawn, "\n"))
+		}
+
+		var row []string
+		for c, stack := range stacks {
+			if c > 0 {
+				row = append(row, strings.Repeat(" ", columnGap))
+			}
+			row = append(row, stack)
+		}
+		lines = append(lines, strings.Split(lipgloss.JoinHorizontal(lipgloss.Top, row...), "\n")...)
+	}
+
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n") + "\n", selectedLine
+}
+
+// cardBody draws the content of a card: the key with the assignee's
+// initials and the points, then the summary.
+func cardBody(card Card, width int, st styles) string {
+	var meta []string
+	if initials := card.Initials(); initials != "" {
+		meta = append(meta, initials)
+	}
+	if card.Points > 0 {
+		meta = append(meta, FormatPoints(card.Points))
+	}
+	right := strings.Join(meta, " · ")
+
+	key := fit(card.Key, width-runewidth.StringWidth(right)-1)
+	pad := max(width-runewidth.StringWidth(key)-runewidth.StringWidth(right), 1)
+	first := st.key.Render(key) + strings.Repeat(" ", pad) + st.meta.Render(right)
+
+	return first + "\n" + strings.Join(wrap(card.Summary, width, summaryLines), "\n")
+}
+
+// wrap breaks text into at most n lines of width, ending with an
+// ellipsis when it is cut, and pads to n lines.
+func wrap(text string, width, n int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = word
+		case runewidth.StringWidth(line)+1+runewidth.StringWidth(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	lines = append(lines, line)
+
+	if len(lines) > n {
+		lines = lines[:n]
+		lines[n-1] = runewidth.Truncate(lines[n-1], width-1, "") + "…"
+	}
+	for i := range lines {
+		lines[i] = fit(lines[i], width)
+	}
+	for len(lines) < n {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// fit truncates s to width with an ellipsis.
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	return runewidth.Truncate(s, width, "…")
+}
@@ -0,0 +1,38 @@
+// Package browser opens URLs in the user's web browser.
+package browser
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Command returns the command that opens url. $BROWSER takes precedence
+// over the platform opener.
+func Command(url string) *exec.Cmd {
+	if browser := os.Getenv("BROWSER"); browser != "" {
+		args := strings.Fields(browser)
+		return exec.Command(args[0], append(args[1:], url)...)
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url)
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		return exec.Command("xdg-open", url)
+	}
+}
+
+// Open opens url without waiting for the browser to exit.
+func Open(url string) error {
+	cmd := Command(url)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("open browser: %w", err)
+	}
+	go cmd.Wait()
+	return nil
+}
@@ -0,0 +1,304 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"jirar/internal/jira"
+	"jirar/internal/output"
+)
+
+// buildAPICommand creates the api command.
+func (a *App) buildAPICommand() *cobra.Command {
+	var (
+		rawFields   []string
+		typedFields []string
+		headers     []string
+		input       string
+		include     bool
+		paginate    bool
+		jq          string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "api [method] <path>",
+		Short: "Make an authenticated Jira REST request",
+		Long: `Call any Jira REST endpoint with the configured domain and credentials
+and print the response body.
+
+The method defaults to GET, or POST when body fields or --input are given.
+Fields given with -f are strings and -F values are JSON literals (numbers,
+true, false, null, arrays and objects); dotted keys build nested objects.
+For GET requests fields become query parameters. --input sends a file, or
+stdin with "-", as the body and turns fields into query parameters.
+
+--paginate follows offset (startAt/total/isLast) and token (nextPageToken)
+pagination and prints the merged result.`,
+		Example: `  jirar api /rest/api/3/field
+  jirar api GET /rest/api/3/search/jql -f jql='project = PROJ' --paginate --jq '.issues[].key'
+  jirar api POST /rest/api/3/issue -f fields.project.key=PROJ -f fields.summary='New bug' -f fields.issuetype.name=Bug
+  jirar api PUT /rest/api/3/issue/PROJ-1 -F fields.customfield_10016=5
+  jirar api POST /rest/api/3/issue/PROJ-1/comment --input comment.json -i`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := output.New(output.Options{Format: output.FormatJSON, JQ: jq})
+			if err != nil {
+				return err
+			}
+
+			method, path := "", args[0]
+			if len(args) == 2 {
+				method, path = strings.ToUpper(args[0]), args[1]
+			}
+
+			req, err := a.apiRequest(method, path, headers)
+			if err != nil {
+				return err
+			}
+
+			fields, err := apiFields(rawFields, typedFields)
+			if err != nil {
+				return err
+			}
+			if err := apiBody(cmd, req, fields, input); err != nil {
+				return err
+			}
+
+			client, err := a.jiraClient()
+			if err != nil {
+				return err
+			}
+
+			var resp *jira.RawResponse
+			if paginate {
+				if req.Method != http.MethodGet {
+					return fmt.Errorf("--paginate only supports GET requests")
+				}
+				resp, err = jira.Paginate(a.ctx, client, req)
+			} else {
+				resp, err = client.Do(a.ctx, req)
+			}
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if include {
+				printResponseHeaders(out, resp)
+			}
+
+			ok := resp.StatusCode >= 200 && resp.StatusCode <= 299
+			if jq != "" && ok && len(resp.Body) > 0 {
+				if err := printer.Filter(out, resp.Body); err != nil {
+					return err
+				}
+			} else {
+				printResponseBody(out, resp.Body)
+			}
+
+			if !ok {
+				return fmt.Errorf("HTTP %s (%s %s)", resp.Status, req.Method, req.Path)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&rawFields, "raw-field", "f", nil, "Add a string field as key=value")
+	cmd.Flags().StringArrayVarP(&typedFields, "field", "F", nil, "Add a JSON-typed field as key=value")
+	cmd.Flags().StringArrayVarP(&headers, "header", "H", nil, "Add a request header as 'Name: value'")
+	cmd.Flags().StringVar(&input, "input", "", "File to send as the request body (- for stdin)")
+	cmd.Flags().BoolVarP(&include, "include", "i", false, "Print the response status line and headers")
+	cmd.Flags().BoolVar(&paginate, "paginate", false, "Fetch all pages and merge the results")
+	cmd.Flags().StringVar(&jq, "jq", "", "Filter the JSON response with a jq expression")
+
+	return cmd
+}
+
+// apiRequest builds a request for path, which may be relative to the Jira
+// domain or a full URL on it, and may carry a query string.
+func (a *App) apiRequest(method, path string, headers []string) (*jira.RawRequest, error) {
+	if strings.Contains(path, "://") {
+		rest, ok := strings.CutPrefix(path, a.config.Jira.Domain)
+		if !ok {
+			return nil, fmt.Errorf("%s is not on %s", path, a.config.Jira.Domain)
+		}
+		path = rest
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q: %w", path, err)
+	}
+
+	req := &jira.RawRequest{
+		Method: method,
+		Path:   u.Path,
+		Query:  u.Query(),
+		Header: http.Header{},
+	}
+	for _, h := range headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid header %q: use 'Name: value'", h)
+		}
+		req.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return req, nil
+}
+
+// apiFields parses -f and -F values into a nested object.
+func apiFields(raw, typed []string) (map[string]any, error) {
+	fields := map[string]any{}
+	set := func(pair string, parse bool) error {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid field %q: use key=value", pair)
+		}
+
+		var v any = value
+		if parse {
+			if err := json.Unmarshal([]byte(value), &v); err != nil {
+				v = value
+			}
+		}
+		return setField(fields, strings.Split(key, "."), v)
+	}
+
+	for _, pair := range raw {
+		if err := set(pair, false); err != nil {
+			return nil, err
+		}
+	}
+	for _, pair := range typed {
+		if err := set(pair, true); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// setField stores v under the dotted path, creating nested objects.
+func setField(obj map[string]any, path []string, v any) error {
+	for _, key := range path[:len(path)-1] {
+		next, ok := obj[key].(map[string]any)
+		if !ok {
+			if _, exists := obj[key]; exists {
+				return fmt.Errorf("field %q is both a value and an object", key)
+			}
+			next = map[string]any{}
+			obj[key] = next
+		}
+		obj = next
+	}
+	obj[path[len(path)-1]] = v
+	return nil
+}
+
+// apiBody sets the request body from --input or the fields, and picks the
+// method when none was given.
+func apiBody(cmd *cobra.Command, req *jira.RawRequest, fields map[string]any, input string) error {
+	if req.Method == "" {
+		req.Method = http.MethodGet
+		if input != "" || len(fields) > 0 {
+			req.Method = http.MethodPost
+		}
+	}
+
+	if input != "" {
+		var (
+			body []byte
+			err  error
+		)
+		if input == "-" {
+			body, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			body, err = os.ReadFile(input)
+		}
+		if err != nil {
+			return fmt.Errorf("read input: %w", err)
+		}
+		req.Body = body
+		return addQueryFields(req, fields)
+	}
+
+	if req.Method == http.MethodGet || req.Method == http.MethodDelete {
+		return addQueryFields(req, fields)
+	}
+	if len(fields) > 0 {
+		body, err := json.Marshal(fields)
+		if err != nil {
+			return fmt.Errorf("encode fields: %w", err)
+		}
+		req.Body = body
+	}
+	return nil
+}
+
+// addQueryFields adds top-level fields as query parameters.
+func addQueryFields(req *jira.RawRequest, fields map[string]any) error {
+	for key, v := range fields {
+		switch t := v.(type) {
+		case map[string]any:
+			return fmt.Errorf("nested field %q cannot be sent as a query parameter", key)
+		case []any:
+			for _, item := range t {
+				req.Query.Add(key, fmt.Sprint(item))
+			}
+		case nil:
+			req.Query.Add(key, "")
+		case float64:
+			req.Query.Add(key, strconv.FormatFloat(t, 'f', -1, 64))
+		default:
+			req.Query.Add(key, fmt.Sprint(t))
+		}
+	}
+	return nil
+}
+
+// printResponseHeaders writes the status line and headers in HTTP style.
+func printResponseHeaders(w io.Writer, resp *jira.RawResponse) {
+	fmt.Fprintf(w, "HTTP/1.1 %s\n", resp.Status)
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range resp.Header[name] {
+			fmt.Fprintf(w, "%s: %s\n", name, v)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// printResponseBody writes a JSON body indented and anything else as is.
+func printResponseBody(w io.Writer, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, body, "", "  "); err == nil {
+		buf.WriteByte('\n')
+		w.Write(buf.Bytes())
+		return
+	}
+
+	w.Write(body)
+	if !bytes.HasSuffix(body, []byte("\n")) {
+		fmt.Fprintln(w)
+	}
+}
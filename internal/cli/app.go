@@ -3,19 +3,62 @@ package cli
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"jirar/internal/config"
+	"jirar/internal/daemon"
+	"jirar/internal/httpcache"
+	"jirar/internal/jira"
+	"jirar/internal/store"
 )
 
+// annotationManagesProfiles marks commands that may run with a --profile or
+// current_profile that does not exist, because they create, switch or
+// remove profiles.
+const annotationManagesProfiles = "jirar/manages-profiles"
+
+// annotationLocal marks commands that only read local files, so the Jira
+// token is not looked up in a secret backend.
+const annotationLocal = "jirar/local"
+
 // App represents the CLI application.
 type App struct {
-	ctx    context.Context
-	logger *logrus.Logger
-	config *config.Config
-	root   *cobra.Command
+	ctx     context.Context
+	logger  *logrus.Logger
+	config  *config.Config
+	root    *cobra.Command
+	profile string
+	// configPath is an explicit config file given with --config
+	configPath string
+	// noColor disables ANSI colors, like NO_COLOR
+	noColor bool
+	// noDaemon sends requests to Jira even when a daemon runs
+	noDaemon bool
+	// cached reads from the local store, asking Jira for what it lacks
+	cached bool
+	// offline reads from the local store and never talks to Jira
+	offline bool
+	// noCache revalidates cached Jira metadata
+	noCache bool
+	// store is the local store, once opened
+	store *store.Store
+	// cache is the transport caching Jira metadata, once created
+	cache *httpcache.Transport
+	// client is the Jira client, once created
+	client jira.Client
+	// quiet is the Jira client that does not log, once created
+	quiet jira.Client
+	// storeNoticed is set once the age of the local store was told
+	storeNoticed bool
+	// location caches the timezone for absolute times
+	location *time.Location
 }
 
 // NewApp creates a new CLI application instance.
@@ -33,7 +76,12 @@ func NewApp(ctx context.Context, logger *logrus.Logger, cfg *config.Config) *App
 
 // Run executes the CLI application.
 func (a *App) Run() error {
-	return a.root.Execute()
+	err := a.root.Execute()
+	if a.store != nil {
+		a.store.Close()
+	}
+	a.flushCache()
+	return err
 }
 
 // buildRootCommand creates the root Cobra command.
@@ -49,14 +97,47 @@ Get started with:
   jirar list           # List your tickets`,
 		Version:      "0.1.0",
 		SilenceUsage: true,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if a.configPath != "" {
+				if err := a.config.Load(a.configPath); err != nil {
+					return err
+				}
+			}
 			a.setupLogging()
+			for _, layer := range a.config.Layers() {
+				if len(layer.Ignored) > 0 {
+					a.logger.WithField("path", layer.Path).Warnf("Ignoring %s in the %s config, which may only set %s",
+						strings.Join(layer.Ignored, ", "), layer.Scope, strings.Join(config.SharedSections, ", "))
+				}
+			}
+			activate := a.config.Activate
+			if cmd.Annotations[annotationLocal] == "true" || a.offline {
+				activate = func(_ context.Context, name string) error { return a.config.SelectProfile(name) }
+			}
+			err := activate(a.ctx, a.profile)
+			if errors.Is(err, config.ErrUnknownProfile) && cmd.Annotations[annotationManagesProfiles] == "true" {
+				return nil
+			}
+			return err
 		},
 	}
 
 	// Global flags
 	cmd.PersistentFlags().BoolVar(&a.config.Debug, "debug", a.config.Debug, "Enable debug logging")
 	cmd.PersistentFlags().StringVar(&a.config.LogLevel, "log-level", a.config.LogLevel, "Log level (debug, info, warn, error)")
+	cmd.PersistentFlags().StringVar(&a.profile, "profile", "", "Jira profile to use (default: current profile)")
+	cmd.PersistentFlags().StringVarP(&a.configPath, "config", "c", "", "Config file merged on top of all others (env: JIRA_CONFIG_PATH)")
+	cmd.PersistentFlags().BoolVar(&a.noColor, "no-color", false, "Disable colored output (env: NO_COLOR)")
+	cmd.PersistentFlags().BoolVar(&a.noDaemon, "no-daemon", false, "Talk to Jira directly even when jirar daemon is running")
+	cmd.PersistentFlags().BoolVar(&a.cached, "cached", false, "Read from the local store kept by jirar sync, asking Jira for what it lacks")
+	cmd.PersistentFlags().BoolVar(&a.offline, "offline", false, "Read from the local store kept by jirar sync without talking to Jira")
+	cmd.PersistentFlags().BoolVar(&a.noCache, "no-cache", false, "Ask Jira again for cached metadata such as fields and statuses")
+	cmd.PersistentFlags().StringVar(&a.config.UI.TimeFormat, "time-format", a.config.UI.TimeFormat, "Time display: relative, absolute, iso or a Go layout")
+
+	// Flags take precedence when the configuration is reloaded
+	for key, name := range configFlags {
+		viper.BindPFlag(key, cmd.PersistentFlags().Lookup(name))
+	}
 
 	// Add subcommands
 	cmd.AddCommand(
@@ -64,11 +145,85 @@ Get started with:
 		a.buildSearchCommand(),
 		a.buildOpenCommand(),
 		a.buildConfigCommand(),
+		a.buildProfileCommand(),
+		a.buildDoctorCommand(),
+		a.buildAPICommand(),
+		a.buildUICommand(),
+		a.buildBoardCommand(),
+		a.buildWatchCommand(),
+		a.buildWebhookCommand(),
+		a.buildDaemonCommand(),
+		a.buildStatusLineCommand(),
+		a.buildSyncCommand(),
+		a.buildCacheCommand(),
 	)
 
 	return cmd
 }
 
+// jiraClient validates the Jira configuration and returns a client for it,
+// served by jirar daemon when one runs for the profile, or by the local
+// store with --cached or --offline.
+func (a *App) jiraClient() (jira.Client, error) {
+	if a.client != nil {
+		return a.client, nil
+	}
+	client, err := a.newClient(a.logger)
+	if err != nil {
+		return nil, err
+	}
+	a.client = client
+	return client, nil
+}
+
+// newClient returns a client as jiraClient does, logging to logger.
+func (a *App) newClient(logger *logrus.Logger) (jira.Client, error) {
+	if a.cached || a.offline {
+		return a.storeClient(logger)
+	}
+	direct, err := a.newDirectClient(logger)
+	if err != nil {
+		return nil, err
+	}
+	return a.viaDaemon(direct), nil
+}
+
+// directClient validates the Jira configuration and returns a client that
+// talks to Jira itself, for commands that watch Jira or are the daemon.
+func (a *App) directClient() (jira.Client, error) {
+	return a.newDirectClient(a.logger)
+}
+
+// newDirectClient returns a client as directClient does, logging to
+// logger.
+func (a *App) newDirectClient(logger *logrus.Logger) (jira.Client, error) {
+	if err := a.config.Validate(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w (run 'jirar config init')", err)
+	}
+	var opts []jira.ClientOption
+	if a.config.Cache.Enabled {
+		opts = append(opts, jira.WithTransport(a.cacheTransport()))
+	}
+	return jira.NewClient(&a.config.Jira, logger, opts...), nil
+}
+
+// viaDaemon returns a client served by the daemon if one runs for the
+// profile, falling back to direct.
+func (a *App) viaDaemon(direct jira.Client) jira.Client {
+	if a.noDaemon {
+		return direct
+	}
+	return daemon.Connect(a.ctx, a.socketPath(), a.config.ActiveProfile, a.config.Jira.Domain, direct, a.logger)
+}
+
+// socketPath returns the socket of jirar daemon.
+func (a *App) socketPath() string {
+	if a.config.Daemon.Socket != "" {
+		return a.config.Daemon.Socket
+	}
+	return daemon.DefaultSocketPath()
+}
+
 // setupLogging configures the logger based on configuration.
 func (a *App) setupLogging() {
 	if a.config.IsDebug() {
@@ -0,0 +1,228 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"jirar/internal/board"
+	"jirar/internal/jira"
+	"jirar/internal/output"
+	"jirar/internal/tui"
+)
+
+// boardWidth is the drawing width when output is not a terminal.
+const boardWidth = 120
+
+// buildBoardCommand creates the board command.
+func (a *App) buildBoardCommand() *cobra.Command {
+	var (
+		swimlanes   string
+		sprint      string
+		jql         string
+		limit       int
+		interactive bool
+		opts        output.Options
+	)
+
+	cmd := &cobra.Command{
+		Use:   "board [board]",
+		Short: "Show an agile board as columns of cards",
+		Long: `Show an agile board with its configured columns side by side. Cards show
+the issue key, the assignee's initials, the estimate and the summary.
+
+The board is an ID or a name and defaults to defaults.board. Scrum boards
+show the active sprint unless --sprint is given.
+
+With --interactive, move the cursor with the arrow keys or h/j/k/l and
+move the selected card to the previous or next column with H/L (or < and
+>). Moving a card runs the transition into a status of that column.`,
+		Example: `  jirar board
+  jirar board 42 --swimlanes assignee
+  jirar board "Team board" --sprint 118 -i
+  jirar board --jql "component = API" -o json`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := a.printer(cmd, opts)
+			if err != nil {
+				return err
+			}
+
+			ref := a.config.Defaults.Board
+			if len(args) == 1 {
+				ref = args[0]
+			}
+			if ref == "" {
+				return fmt.Errorf("no board given: pass a board ID or name, or set defaults.board")
+			}
+
+			client, err := a.jiraClient()
+			if err != nil {
+				return err
+			}
+			b, err := a.findBoard(client, ref)
+			if err != nil {
+				return err
+			}
+			query, err := boardJQL(b, sprint, jql)
+			if err != nil {
+				return err
+			}
+
+			load := func(ctx context.Context) (*board.Board, error) {
+				return loadBoard(ctx, client, *b, query, limit, swimlanes)
+			}
+
+			if interactive {
+				if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+					return fmt.Errorf("--interactive needs an interactive terminal")
+				}
+				out := a.logger.Out
+				a.logger.SetOutput(io.Discard)
+				defer a.logger.SetOutput(out)
+
+				return tui.RunBoard(a.ctx, tui.BoardOptions{
+					Client: client,
+					Domain: a.config.Jira.Domain,
+					Colors: a.colorEnabled(os.Stdout),
+					Load:   load,
+				})
+			}
+
+			view, err := load(a.ctx)
+			if err != nil {
+				return err
+			}
+			return printer.Render(cmd.OutOrStdout(), a.boardResult(view, printer))
+		},
+	}
+
+	cmd.Flags().StringVar(&swimlanes, "swimlanes", board.SwimlaneNone, "Group cards into swimlanes: "+strings.Join(board.Swimlanes, ", "))
+	cmd.Flags().StringVar(&sprint, "sprint", "", "Sprint ID, active or all (default: active on scrum boards)")
+	cmd.Flags().StringVar(&jql, "jql", "", "Only show issues matching this JQL")
+	cmd.Flags().IntVarP(&limit, "limit", "l", 200, "Maximum number of issues to load")
+	cmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Browse the board and move cards between columns")
+	addOutputFlags(cmd, &opts)
+
+	return cmd
+}
+
+// findBoard resolves a board ID or name. Names match case-insensitively,
+// within defaults.project when it is set.
+func (a *App) findBoard(client jira.Client, ref string) (*jira.Board, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return client.GetBoard(a.ctx, id)
+	}
+
+	boards, err := client.ListBoards(a.ctx, a.config.Defaults.Project)
+	if err != nil {
+		return nil, err
+	}
+	var matches []jira.Board
+	for _, b := range boards {
+		if strings.EqualFold(b.Name, ref) {
+			return &b, nil
+		}
+		if strings.Contains(strings.ToLower(b.Name), strings.ToLower(ref)) {
+			matches = append(matches, b)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no board named %q", ref)
+	case 1:
+		return &matches[0], nil
+	}
+	names := make([]string, len(matches))
+	for i, b := range matches {
+		names[i] = fmt.Sprintf("%s (%d)", b.Name, b.ID)
+	}
+	return nil, fmt.Errorf("board %q is ambiguous: %s", ref, strings.Join(names, ", "))
+}
+
+// boardJQL narrows the board to a sprint and an extra query.
+func boardJQL(b *jira.Board, sprint, jql string) (string, error) {
+	if sprint == "" && b.Type == "scrum" {
+		sprint = "active"
+	}
+
+	var clauses []string
+	switch sprint {
+	case "", "all":
+	case "active":
+		clauses = append(clauses, "sprint in openSprints()")
+	default:
+		if _, err := strconv.Atoi(sprint); err != nil {
+			return "", fmt.Errorf("invalid sprint %q (use an ID, active or all)", sprint)
+		}
+		clauses = append(clauses, "sprint = "+sprint)
+	}
+	if jql != "" {
+		clauses = append(clauses, "("+jql+")")
+	}
+	return strings.Join(clauses, " AND "), nil
+}
+
+// loadBoard fetches the configuration and issues of a board and arranges
+// them.
+func loadBoard(ctx context.Context, client jira.Client, b jira.Board, jql string, limit int, swimlanes string) (*board.Board, error) {
+	cfg, err := client.GetBoardConfiguration(ctx, b.ID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.GetBoardIssues(ctx, b.ID, jql, jira.WithLimit(limit), jira.WithFields(board.Fields(cfg)...))
+	if err != nil {
+		return nil, err
+	}
+	return board.New(b, cfg, res.Issues, swimlanes)
+}
+
+// boardResult renders a board as lanes in table format and as one row per
+// card otherwise.
+func (a *App) boardResult(b *board.Board, printer *output.Printer) output.Result {
+	table := output.Table{Headers: []string{"COLUMN", "KEY", "SUMMARY", "ASSIGNEE", "POINTS"}}
+	if len(b.Swimlanes) > 0 {
+		table.Headers = append([]string{"SWIMLANE"}, table.Headers...)
+	}
+
+	var keys []string
+	var records []any
+	for c, col := range b.Columns {
+		for _, card := range b.Ordered(c) {
+			row := []string{col.Name, card.Key, card.Summary, card.Assignee, ""}
+			if card.Points > 0 {
+				row[4] = board.FormatPoints(card.Points)
+			}
+			if len(b.Swimlanes) > 0 {
+				row = append([]string{card.Swimlane}, row...)
+			}
+			table.Rows = append(table.Rows, row)
+			keys = append(keys, card.Key)
+			records = append(records, struct {
+				Column string `json:"column"`
+				board.Card
+			}{col.Name, card})
+		}
+	}
+
+	width := printer.Width()
+	if width == 0 {
+		width = boardWidth
+	}
+	return output.Result{
+		Data:    b,
+		Records: records,
+		Table:   table,
+		Keys:    keys,
+		Human: func(w io.Writer) error {
+			drawing, _ := board.Render(b, board.Options{Width: width, Colors: a.colorEnabled(w)})
+			_, err := io.WriteString(w, drawing)
+			return err
+		},
+	}
+}
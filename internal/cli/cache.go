@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"jirar/internal/httpcache"
+	"jirar/internal/output"
+)
+
+// buildCacheCommand creates the cache command.
+func (a *App) buildCacheCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the cache of Jira metadata",
+		Long: `Jira metadata that barely changes is cached on disk under
+$XDG_CACHE_HOME/jirar/<profile>/http, so commands do not refetch it every
+run. Responses of each endpoint class are used for the TTL of the class,
+cache.ttl.<class>, then revalidated: conditionally with If-None-Match when
+Jira sent an ETag, so an unchanged response costs no body. When Jira
+cannot be reached, stale responses are used.
+
+Classes and their default TTLs:
+  fields      24h  /rest/api/3/field
+  statuses    24h  /rest/api/3/status, statuscategory, project/KEY/statuses
+  issuetypes  24h  /rest/api/3/issuetype
+  projects    1h   /rest/api/3/project, project/search, project/KEY
+  createmeta  1h   /rest/api/3/issue/createmeta
+
+--no-cache revalidates every cached response; cache.enabled: false turns
+the cache off.`,
+	}
+
+	cmd.AddCommand(
+		a.buildCacheClearCommand(),
+		a.buildCacheStatsCommand(),
+	)
+
+	return cmd
+}
+
+// buildCacheClearCommand creates the cache clear command.
+func (a *App) buildCacheClearCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:       "clear [class...]",
+		Short:     "Delete cached responses",
+		Long:      `Delete the cached responses of the classes given, or all of them.`,
+		Example:   "  jirar cache clear\n  jirar cache clear fields statuses",
+		ValidArgs: httpcache.Classes,
+		Args:      cobra.OnlyValidArgs,
+		Annotations: map[string]string{
+			annotationLocal: "true",
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			n, err := httpcache.Clear(a.cacheDir(), args...)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Deleted %d cached responses.\n", n)
+			return nil
+		},
+	}
+}
+
+// buildCacheStatsCommand creates the cache stats command.
+func (a *App) buildCacheStatsCommand() *cobra.Command {
+	var opts output.Options
+
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show what the cache holds and how it served",
+		Long: `Show, per endpoint class, its TTL, how many responses are cached and
+fresh, their size, and how often they were served from the cache (hits),
+confirmed unchanged by Jira (revalidated) or fetched in full. Commands
+record these counts when they finish, a running daemon as it refreshes.`,
+		Args: cobra.NoArgs,
+		Annotations: map[string]string{
+			annotationLocal: "true",
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := a.printer(cmd, opts)
+			if err != nil {
+				return err
+			}
+			stats, err := httpcache.Stats(a.cacheDir(), a.cacheTTL())
+			if err != nil {
+				return err
+			}
+			return printer.Render(cmd.OutOrStdout(), cacheStatsResult(stats))
+		},
+	}
+
+	addOutputFlags(cmd, &opts)
+
+	return cmd
+}
+
+// cacheStatsResult describes the cache for the output printer.
+func cacheStatsResult(stats []httpcache.ClassStats) output.Result {
+	table := output.Table{Headers: []string{"Class", "TTL", "Entries", "Fresh", "Size", "Hits", "Revalidated", "Fetched"}}
+	for _, s := range stats {
+		table.Rows = append(table.Rows, []string{
+			s.Class, s.TTL, fmt.Sprint(s.Entries), fmt.Sprint(s.Fresh), fmt.Sprintf("%d KiB", (s.Size+1023)/1024),
+			fmt.Sprint(s.Hits), fmt.Sprint(s.Revalidated), fmt.Sprint(s.Fetched),
+		})
+	}
+	return output.Result{Data: stats, Table: table}
+}
+
+// cacheDir returns the HTTP cache of the profile.
+func (a *App) cacheDir() string {
+	return httpcache.DefaultDir(a.config.ActiveProfile)
+}
+
+// cacheTTL returns the TTLs of the endpoint classes by name.
+func (a *App) cacheTTL() map[string]time.Duration {
+	ttl := a.config.Cache.TTL
+	return map[string]time.Duration{
+		httpcache.ClassFields:     ttl.Fields,
+		httpcache.ClassStatuses:   ttl.Statuses,
+		httpcache.ClassIssueTypes: ttl.IssueTypes,
+		httpcache.ClassProjects:   ttl.Projects,
+		httpcache.ClassCreateMeta: ttl.CreateMeta,
+	}
+}
+
+// cacheTransport returns the transport caching Jira metadata, shared by
+// the clients of a run so its stats are flushed once.
+func (a *App) cacheTransport() *httpcache.Transport {
+	if a.cache == nil {
+		a.cache = &httpcache.Transport{
+			Dir:    a.cacheDir(),
+			TTL:    a.cacheTTL(),
+			Logger: a.logger,
+		}
+	}
+	a.cache.Revalidate = a.noCache
+	return a.cache
+}
+
+// flushCache records how the cache served so far in its stats.
+func (a *App) flushCache() {
+	if a.cache == nil {
+		return
+	}
+	if err := a.cache.Flush(); err != nil {
+		a.logger.WithError(err).Debug("Could not record the cache stats")
+	}
+}
@@ -0,0 +1,353 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"jirar/internal/config"
+	"jirar/internal/output"
+)
+
+// buildConfigCommand creates the config command.
+func (a *App) buildConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage configuration",
+		Long: `Manage Jirar configuration settings.
+Supports interactive setup, validation, and testing.`,
+	}
+
+	// Add subcommands
+	cmd.AddCommand(a.buildConfigInitCommand())
+	cmd.AddCommand(a.buildConfigShowCommand())
+	cmd.AddCommand(a.buildConfigTestCommand())
+	cmd.AddCommand(a.buildConfigGetCommand())
+	cmd.AddCommand(a.buildConfigSetCommand())
+	cmd.AddCommand(a.buildConfigUnsetCommand())
+	cmd.AddCommand(a.buildConfigSetTokenCommand())
+
+	return cmd
+}
+
+// settingView is the output model of a configuration value.
+type settingView struct {
+	Key    string `json:"key"`
+	Value  any    `json:"value"`
+	Origin string `json:"origin,omitempty"`
+}
+
+// buildConfigShowCommand creates the config show subcommand.
+func (a *App) buildConfigShowCommand() *cobra.Command {
+	var (
+		origin bool
+		opts   output.Options
+	)
+
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show current configuration",
+		Long: `Show the effective configuration.
+With --origin each value is listed with its source, highest first: a
+flag, the selected profile, an environment variable, a config file
+(system, user, repo, workdir, then --config) or the built-in default.
+Secrets such as the API token are always redacted.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := a.printer(cmd, opts)
+			if err != nil {
+				return err
+			}
+
+			settings := a.config.Settings()
+			views := make([]settingView, 0, len(settings))
+			records := make([]any, 0, len(settings))
+			table := output.Table{Headers: []string{"Key", "Value"}}
+			if origin {
+				table.Headers = append(table.Headers, "Origin")
+			}
+			for _, s := range settings {
+				view := settingView{Key: s.Key, Value: s.Value}
+				row := []string{s.Key, fmt.Sprint(s.Value)}
+				if origin {
+					view.Origin = a.configOrigin(s.Key).String()
+					row = append(row, view.Origin)
+				}
+				views = append(views, view)
+				records = append(records, view)
+				table.Rows = append(table.Rows, row)
+			}
+
+			result := output.Result{Data: views, Records: records, Table: table}
+			if !origin {
+				result.Human = func(w io.Writer) error {
+					for _, s := range settings {
+						fmt.Fprintf(w, "%s = %v\n", s.Key, s.Value)
+					}
+					return nil
+				}
+			}
+			return printer.Render(cmd.OutOrStdout(), result)
+		},
+	}
+
+	cmd.Flags().BoolVar(&origin, "origin", false, "Show where each value comes from")
+	addOutputFlags(cmd, &opts)
+
+	return cmd
+}
+
+// buildConfigGetCommand creates the config get subcommand.
+func (a *App) buildConfigGetCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get [key]",
+		Short: "Print a single configuration value",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			field, err := resolveConfigKey(args[0])
+			if err != nil {
+				return err
+			}
+
+			s, ok := a.config.Setting(field.Key)
+			if !ok {
+				return fmt.Errorf("%s is not set", field.Key)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), s.Value)
+			return nil
+		},
+	}
+	return cmd
+}
+
+// buildConfigTestCommand creates the config test subcommand.
+func (a *App) buildConfigTestCommand() *cobra.Command {
+	cmd := a.buildDoctorCommand()
+	cmd.Use = "test"
+	cmd.Short = "Test Jira connection (same as 'jirar doctor')"
+	cmd.Aliases = nil
+	return cmd
+}
+
+// buildConfigSetCommand creates the config set subcommand.
+func (a *App) buildConfigSetCommand() *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "set [key] [value]",
+		Short: "Set a specific configuration value",
+		Long: `Set a configuration value. The key and value are checked against the
+configuration schema, and the value is written back to the file it
+currently comes from, keeping comments intact. Values not yet in any file
+go to the user configuration file unless --file is given.`,
+		Example: `  jirar config set ui.compact true
+  jirar config set domain company.atlassian.net
+  jirar config set profiles.acme.project ACME`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			field, err := resolveConfigKey(args[0])
+			if err != nil {
+				return err
+			}
+			if field.Secret {
+				return fmt.Errorf("%s is a secret: set jira.token_ref and run 'jirar config set-token' instead", field.Key)
+			}
+
+			value, err := field.Parse(args[1])
+			if err != nil {
+				return err
+			}
+			if strings.HasSuffix(field.Key, ".domain") {
+				value = config.NormalizeDomain(args[1])
+			}
+
+			if (file == config.ScopeRepo || file == config.ScopeWorkdir) && !config.Shared(field.Key) {
+				return fmt.Errorf("%s cannot be set in the %s file, which only holds %s", field.Key, file, strings.Join(config.SharedSections, ", "))
+			}
+
+			path, key, err := a.configTarget(field.Key, file)
+			if err != nil {
+				return err
+			}
+			if err := a.editFile(path, func(f *config.File) error {
+				return f.Set(key, value)
+			}); err != nil {
+				return err
+			}
+
+			a.logger.WithFields(logrus.Fields{"key": key, "path": path}).Info("Configuration updated")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&file, "file", "", "File or scope (system, user, repo, workdir) to write (default: where the value comes from)")
+
+	return cmd
+}
+
+// buildConfigUnsetCommand creates the config unset subcommand.
+func (a *App) buildConfigUnsetCommand() *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "unset [key]",
+		Short: "Remove a configuration value from its file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			field, err := resolveConfigKey(args[0])
+			if err != nil {
+				return err
+			}
+
+			path, err := scopePath(file)
+			if err != nil {
+				return err
+			}
+			key := field.Key
+			if path == "" {
+				origin := a.config.Origin(field.Key)
+				if origin.Path == "" {
+					return fmt.Errorf("%s is not set in any configuration file (origin: %s)", field.Key, origin)
+				}
+				path, key = origin.Path, origin.Key
+			}
+
+			if err := a.editFile(path, func(f *config.File) error {
+				if !f.Unset(key) {
+					return fmt.Errorf("%s is not set in %s", key, path)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+
+			a.logger.WithFields(logrus.Fields{"key": key, "path": path}).Info("Configuration updated")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&file, "file", "", "File or scope (system, user, repo, workdir) to edit (default: where the value comes from)")
+
+	return cmd
+}
+
+// buildConfigSetTokenCommand creates the config set-token subcommand.
+func (a *App) buildConfigSetTokenCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set-token",
+		Short: "Store the API token in the configured secret backend",
+		Long: `Store the Jira API token in the backend referenced by jira.token_ref
+or jira.credential_helper. The token is read from the terminal without
+echo, or from stdin when piped.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := a.config.Jira.SecretStore()
+			if err != nil {
+				return err
+			}
+			if store == nil {
+				return fmt.Errorf("no secret backend configured: set jira.token_ref or jira.credential_helper")
+			}
+
+			token, err := readToken(cmd)
+			if err != nil {
+				return err
+			}
+
+			if err := store.Set(a.ctx, token); err != nil {
+				return err
+			}
+
+			a.logger.WithField("backend", store.String()).Info("Token stored")
+			return nil
+		},
+	}
+	return cmd
+}
+
+// readToken reads a secret from the terminal without echo, or from stdin.
+func readToken(cmd *cobra.Command) (string, error) {
+	var (
+		b   []byte
+		err error
+	)
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		fmt.Fprint(cmd.ErrOrStderr(), "API token: ")
+		b, err = term.ReadPassword(fd)
+		fmt.Fprintln(cmd.ErrOrStderr())
+	} else {
+		b, err = io.ReadAll(cmd.InOrStdin())
+	}
+	if err != nil {
+		return "", fmt.Errorf("read token: %w", err)
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("empty token")
+	}
+	return token, nil
+}
+
+// configFlags maps configuration keys to the root flags that override them.
+var configFlags = map[string]string{
+	"debug":          "debug",
+	"log_level":      "log-level",
+	"ui.time_format": "time-format",
+}
+
+// configOrigin reports where a value came from, including root flags.
+func (a *App) configOrigin(key string) config.Origin {
+	if name, ok := configFlags[key]; ok && a.root.PersistentFlags().Changed(name) {
+		return config.Origin{Source: config.SourceFlag, Detail: "--" + name}
+	}
+	return a.config.Origin(key)
+}
+
+// configTarget picks the file and key that config set writes to: an
+// explicit file, else the file the value currently comes from, else the
+// user configuration file.
+func (a *App) configTarget(key, file string) (string, string, error) {
+	if file != "" {
+		path, err := scopePath(file)
+		return path, key, err
+	}
+	if origin := a.config.Origin(key); origin.Path != "" {
+		return origin.Path, origin.Key, nil
+	}
+	return a.config.WritePath(), key, nil
+}
+
+// scopePath resolves a layer scope name such as "repo" to its file; other
+// values are taken as paths.
+func scopePath(file string) (string, error) {
+	switch file {
+	case config.ScopeSystem, config.ScopeUser, config.ScopeRepo, config.ScopeWorkdir:
+		path := config.LayerPath(file)
+		if path == "" && file == config.ScopeRepo {
+			return "", fmt.Errorf("%s scope: not inside a git repository", file)
+		}
+		if path == "" {
+			return "", fmt.Errorf("%s scope: no configuration file", file)
+		}
+		return path, nil
+	}
+	return file, nil
+}
+
+// resolveConfigKey validates a key against the schema. Bare Jira keys such
+// as "domain" are accepted as shorthand for "jira.domain".
+func resolveConfigKey(key string) (config.Field, error) {
+	if field, ok := config.LookupField(key); ok {
+		return field, nil
+	}
+	if field, ok := config.LookupField("jira." + key); ok {
+		return field, nil
+	}
+	return config.Field{}, fmt.Errorf("unknown configuration key %q (see 'jirar config show')", key)
+}
@@ -0,0 +1,296 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"jirar/internal/config"
+	"jirar/internal/jira"
+	"jirar/internal/secret"
+)
+
+// Token storage choices offered by config init.
+const (
+	storeKeyring = "keyring"
+	storeAge     = "age"
+	storeInline  = "inline"
+	storeNone    = "none"
+)
+
+// tokenStores lists the storage choices in the order they are offered.
+var tokenStores = []string{storeKeyring, storeAge, storeInline, storeNone}
+
+// initOptions holds the flags of the config init subcommand.
+type initOptions struct {
+	fromEnv bool
+	store   string
+	project string
+	board   string
+}
+
+// buildConfigInitCommand creates the config init subcommand.
+func (a *App) buildConfigInitCommand() *cobra.Command {
+	var opts initOptions
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Interactive setup wizard",
+		Long: `Set up jirar against a Jira instance.
+
+Asks for the domain, email and API token, verifies them against the server,
+lets you pick a default project and board, and writes the configuration
+file with 0600 permissions. With --profile the answers are saved as a
+named profile.
+
+With --from-env no questions are asked: JIRA_DOMAIN, JIRA_EMAIL and
+JIRA_TOKEN are read from the environment, which suits CI.`,
+		Example: `  jirar config init
+  jirar --profile acme config init
+  JIRA_DOMAIN=... JIRA_EMAIL=... JIRA_TOKEN=... jirar config init --from-env --project PROJ`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{annotationManagesProfiles: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.runConfigInit(cmd, opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.fromEnv, "from-env", false, "Read credentials from JIRA_* variables without prompting")
+	cmd.Flags().StringVar(&opts.store, "store", "", "Token storage: keyring, age, inline or none (default: ask, or none with --from-env)")
+	cmd.Flags().StringVar(&opts.project, "project", "", "Default project key")
+	cmd.Flags().StringVar(&opts.board, "board", "", "Default board ID")
+
+	return cmd
+}
+
+// runConfigInit gathers, verifies and saves the Jira settings.
+func (a *App) runConfigInit(cmd *cobra.Command, opts initOptions) error {
+	p := newPrompter(cmd.InOrStdin(), cmd.ErrOrStderr())
+	interactive := !opts.fromEnv
+
+	jc, err := a.gatherCredentials(p, opts.fromEnv)
+	if err != nil {
+		return err
+	}
+
+	client := jira.NewClient(&jc, a.logger)
+	if err := client.ValidateCredentials(a.ctx); err != nil {
+		return fmt.Errorf("could not authenticate against %s: %w", jc.Domain, err)
+	}
+	user, err := client.GetCurrentUser(a.ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "✅ Authenticated as %s <%s>", user.DisplayName, user.Email)
+	if user.TimeZone != "" {
+		fmt.Fprintf(cmd.ErrOrStderr(), " (%s)", user.TimeZone)
+	}
+	fmt.Fprintln(cmd.ErrOrStderr())
+
+	defaults := config.DefaultsConfig{Project: opts.project, Board: opts.board}
+	if interactive {
+		if err := a.pickDefaults(p, client, &defaults); err != nil {
+			return err
+		}
+	}
+
+	store := opts.store
+	if store == "" && interactive {
+		// Blank picks the keyring only when one is reachable, so a headless
+		// box does not fail after the token was typed
+		keyring := secret.KeyringAvailable()
+		if keyring {
+			fmt.Fprintln(cmd.ErrOrStderr(), "Where should the API token be stored? (blank for the keyring)")
+		} else {
+			fmt.Fprintln(cmd.ErrOrStderr(), "Where should the API token be stored? (no system keyring is reachable)")
+		}
+		for store == "" {
+			idx, err := p.choose("Token storage", tokenStores)
+			switch {
+			case err != nil:
+				return err
+			case idx >= 0:
+				store = tokenStores[idx]
+			case keyring:
+				store = storeKeyring
+			default:
+				fmt.Fprintln(cmd.ErrOrStderr(), "Choose where to store the token; without a keyring, age or inline keeps it.")
+			}
+		}
+	}
+	if store == "" {
+		store = storeNone
+	}
+
+	if err := a.storeToken(&jc, store); err != nil {
+		return err
+	}
+
+	path := a.config.WritePath()
+	if err := a.writeInitConfig(path, jc, defaults); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "Configuration written to %s\n", path)
+	return nil
+}
+
+// gatherCredentials reads domain, email and token from prompts or the
+// environment.
+func (a *App) gatherCredentials(p *prompter, fromEnv bool) (config.JiraConfig, error) {
+	jc := config.JiraConfig{AgeIdentity: a.config.Jira.AgeIdentity}
+
+	if fromEnv {
+		jc.Domain = os.Getenv("JIRA_DOMAIN")
+		jc.Email = os.Getenv("JIRA_EMAIL")
+		jc.Token = os.Getenv("JIRA_TOKEN")
+	} else {
+		var err error
+		if jc.Domain, err = p.ask("Jira domain (e.g. company.atlassian.net)", a.config.Jira.Domain); err != nil {
+			return jc, err
+		}
+		if jc.Email, err = p.ask("Email", a.config.Jira.Email); err != nil {
+			return jc, err
+		}
+		if jc.Token, err = p.askSecret("API token (https://id.atlassian.com/manage-profile/security/api-tokens)"); err != nil {
+			return jc, err
+		}
+	}
+
+	jc.Domain = config.NormalizeDomain(jc.Domain)
+	cfg := config.Config{Jira: jc}
+	if err := cfg.Validate(); err != nil {
+		return jc, err
+	}
+	return jc, nil
+}
+
+// pickDefaults lets the user choose a default project and board from the
+// server's lists.
+func (a *App) pickDefaults(p *prompter, client jira.Client, defaults *config.DefaultsConfig) error {
+	if defaults.Project == "" {
+		projects, err := client.ListProjects(a.ctx)
+		if err != nil {
+			a.logger.WithError(err).Warn("Could not list projects")
+		} else if len(projects) > 0 {
+			options := make([]string, len(projects))
+			for i, project := range projects {
+				options[i] = fmt.Sprintf("%s (%s)", project.Key, project.Name)
+			}
+			idx, err := p.choose("Default project", options)
+			if err != nil {
+				return err
+			}
+			if idx >= 0 {
+				defaults.Project = projects[idx].Key
+			}
+		}
+	}
+
+	if defaults.Board == "" {
+		boards, err := client.ListBoards(a.ctx, defaults.Project)
+		if err != nil {
+			a.logger.WithError(err).Warn("Could not list boards")
+		} else if len(boards) > 0 {
+			options := make([]string, len(boards))
+			for i, board := range boards {
+				options[i] = fmt.Sprintf("%d (%s, %s)", board.ID, board.Name, board.Type)
+			}
+			idx, err := p.choose("Default board", options)
+			if err != nil {
+				return err
+			}
+			if idx >= 0 {
+				defaults.Board = strconv.Itoa(boards[idx].ID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// storeToken saves the token in the chosen backend and records the
+// reference in jc. Only the inline choice keeps the token in jc.Token.
+func (a *App) storeToken(jc *config.JiraConfig, store string) error {
+	switch store {
+	case storeInline:
+		return nil
+	case storeNone:
+		jc.Token = ""
+		return nil
+	case storeKeyring:
+		// Profiles sharing an email keep their own keyring entries
+		jc.TokenRef = "keyring:" + secret.DefaultService
+		if a.profile != "" {
+			jc.TokenRef = fmt.Sprintf("keyring:%s/%s", secret.DefaultService, a.profile)
+		}
+	case storeAge:
+		jc.TokenRef = "age:~/.config/jirar/token.age"
+		if a.profile != "" {
+			jc.TokenRef = fmt.Sprintf("age:~/.config/jirar/%s.token.age", a.profile)
+		}
+	default:
+		return fmt.Errorf("unknown token storage %q (use keyring, age, inline or none)", store)
+	}
+
+	backend, err := jc.SecretStore()
+	if err != nil {
+		return err
+	}
+	if err := backend.Set(a.ctx, jc.Token); err != nil {
+		return err
+	}
+
+	a.logger.WithField("backend", backend.String()).Info("Token stored")
+	jc.Token = ""
+	return nil
+}
+
+// writeInitConfig saves the wizard's answers, under the selected profile if
+// --profile was given.
+func (a *App) writeInitConfig(path string, jc config.JiraConfig, defaults config.DefaultsConfig) error {
+	f, err := config.LoadFile(path)
+	if err != nil {
+		return err
+	}
+
+	jiraPrefix, defaultsPrefix := "jira.", "defaults."
+	if a.profile != "" {
+		jiraPrefix = "profiles." + a.profile + "."
+		defaultsPrefix = jiraPrefix
+		if !f.Has("current_profile") {
+			if err := f.Set("current_profile", a.profile); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Credentials left empty are cleared so stale ones do not linger;
+	// defaults that were skipped are kept
+	values := []struct {
+		key, value   string
+		clearIfEmpty bool
+	}{
+		{jiraPrefix + "domain", jc.Domain, true},
+		{jiraPrefix + "email", jc.Email, true},
+		{jiraPrefix + "token", jc.Token, true},
+		{jiraPrefix + "token_ref", jc.TokenRef, true},
+		{defaultsPrefix + "project", defaults.Project, false},
+		{defaultsPrefix + "board", defaults.Board, false},
+	}
+	for _, v := range values {
+		if v.value == "" {
+			if v.clearIfEmpty {
+				f.Unset(v.key)
+			}
+			continue
+		}
+		if err := f.Set(v.key, v.value); err != nil {
+			return err
+		}
+	}
+
+	return f.Save()
+}
@@ -0,0 +1,383 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"jirar/internal/daemon"
+	"jirar/internal/jira"
+	"jirar/internal/jira/watcher"
+	"jirar/internal/output"
+	"jirar/internal/rules"
+	"jirar/internal/statusline"
+)
+
+// buildDaemonCommand creates the daemon command.
+func (a *App) buildDaemonCommand() *cobra.Command {
+	var (
+		refresh string
+		noWatch bool
+		desktop bool
+		opts    output.Options
+	)
+
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Run a background daemon that answers other commands",
+		Long: `Run jirar in the background with a warm Jira client, so other commands
+answer without the start-up cost and, for issues asked for before, without
+waiting for Jira.
+
+The daemon listens on $XDG_RUNTIME_DIR/jirar.sock (daemon.socket) and
+speaks JSON-RPC 2.0; commands of the same profile use it when it runs and
+talk to Jira directly when it does not, or with --no-daemon. Searches and
+issues it served are cached and refetched every daemon.refresh (1m) while
+they are in use, so they are at most a couple of minutes old. Changes made
+through the daemon, and changes its watchers see, refetch them at once.
+
+The daemon also runs the watchers, rules and sinks of jirar watch and
+prints their events; it takes the place of jirar watch. --no-watch leaves
+that to a jirar watch of its own.
+
+jirar daemon install sets the daemon up as a systemd user service.`,
+		Example: `  jirar daemon
+  jirar daemon --no-watch --refresh 30s
+  jirar daemon install && systemctl --user enable --now jirar
+  echo '{"jsonrpc":"2.0","id":1,"method":"Daemon.Status"}' | socat - UNIX-CONNECT:$XDG_RUNTIME_DIR/jirar.sock`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := a.printer(cmd, opts)
+			if err != nil {
+				return err
+			}
+			every, err := parseInterval(refresh, a.config.Daemon.Refresh)
+			if err != nil {
+				return err
+			}
+			client, err := a.directClient()
+			if err != nil {
+				return err
+			}
+
+			var (
+				engine   *rules.Engine
+				filters  []string
+				watchers []*watcher.Watcher
+			)
+			if !noWatch {
+				var sinks []string
+				if desktop {
+					sinks = append(sinks, desktopSink)
+				}
+				engine, filters, err = a.ruleEngine(client, "", nil, sinks)
+				if err != nil {
+					return err
+				}
+				defer engine.Close()
+				interval, err := parseInterval("", a.config.Watch.Interval)
+				if err != nil {
+					return err
+				}
+				watchers, err = a.newWatchers(client, filters, interval, false, cmd.ErrOrStderr())
+				if err != nil {
+					return err
+				}
+			}
+
+			path := a.socketPath()
+			listener, err := daemon.Listen(path)
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(a.ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			server := daemon.NewServer(daemon.Options{
+				Client:   client,
+				Profile:  a.config.ActiveProfile,
+				Domain:   a.config.Jira.Domain,
+				Refresh:  every,
+				Watching: filters,
+				Stop:     stop,
+				Logger:   a.logger,
+			})
+			go a.warmDaemon(ctx, server)
+			counts := make(chan struct{}, 1)
+			go a.countIssues(ctx, client, every, counts)
+
+			errs := make(chan error, len(watchers)+1)
+			go func() { errs <- server.Serve(ctx, listener) }()
+			if engine != nil {
+				go engine.Run(ctx, time.Minute)
+			}
+
+			out := cmd.OutOrStdout()
+			th := a.theme(out)
+			var mu sync.Mutex
+			for i, w := range watchers {
+				filter := filters[i]
+				go func() {
+					errs <- w.Run(ctx, func(e watcher.Event) {
+						server.Invalidate()
+						select {
+						case counts <- struct{}{}:
+						default:
+						}
+						mu.Lock()
+						defer mu.Unlock()
+						if err := printer.Render(out, a.eventResult(e, th)); err != nil {
+							a.logger.WithError(err).Error("Failed to print event")
+						}
+						a.recordActivity(e)
+						engine.Handle(ctx, filter, e)
+					})
+				}()
+			}
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "Serving profile %s on %s. Press Ctrl+C to stop.\n", profileName(a.config.ActiveProfile), path)
+			for range len(watchers) + 1 {
+				if err := <-errs; err != nil && !errors.Is(err, context.Canceled) {
+					stop()
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&refresh, "refresh", "", "How often cached issues are refetched (default: daemon.refresh, 1m)")
+	cmd.Flags().BoolVar(&noWatch, "no-watch", false, "Do not run the watchers of jirar watch")
+	cmd.Flags().BoolVar(&desktop, "desktop", false, "Show desktop notifications of watch events (default: watch.desktop.enabled)")
+	addOutputFlags(cmd, &opts)
+
+	cmd.AddCommand(
+		a.buildDaemonStatusCommand(),
+		a.buildDaemonStopCommand(),
+		a.buildDaemonInstallCommand(),
+	)
+	return cmd
+}
+
+// warmDaemon fetches the issues of a plain jirar list into the daemon's
+// cache, so the first list is as quick as the next.
+func (a *App) warmDaemon(ctx context.Context, server *daemon.Server) {
+	jql, err := listJQL("", a.config.Defaults.Project, "updated")
+	if err != nil {
+		return
+	}
+	columns, err := a.issueColumns("list", "", true)
+	if err != nil {
+		return
+	}
+	if err := server.Warm(ctx, jql, jira.WithLimit(listLimit), jira.WithFields(output.ColumnFields(columns)...)); err != nil {
+		a.logger.WithError(err).Warn("Failed to fetch your issues")
+	}
+}
+
+// countIssues keeps the counts of jirar status-line current: every period
+// and when asked to after a change, until ctx is cancelled.
+func (a *App) countIssues(ctx context.Context, client jira.Client, every time.Duration, changed <-chan struct{}) {
+	path := statusline.DefaultPath(a.config.ActiveProfile)
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		assigned, inProgress, err := statusline.Count(ctx, client)
+		if err == nil {
+			err = statusline.Update(path, func(s *statusline.State) {
+				s.Assigned, s.InProgress, s.Counted = assigned, inProgress, time.Now()
+			})
+		}
+		if err != nil && ctx.Err() == nil {
+			a.logger.WithError(err).Warn("Failed to count your issues")
+		}
+		// The daemon runs for days, so cache stats are flushed as it goes
+		a.flushCache()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-changed:
+		}
+	}
+}
+
+// profileName names a profile in messages; the configuration without a
+// profile is "default".
+func profileName(profile string) string {
+	if profile == "" {
+		return "default"
+	}
+	return profile
+}
+
+// buildDaemonStatusCommand creates the daemon status command.
+func (a *App) buildDaemonStatusCommand() *cobra.Command {
+	var opts output.Options
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show whether the daemon is running",
+		Long: `Show the daemon listening on the socket: its profile, what it watches
+and how well its cache does. Fails when no daemon is running.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := a.printer(cmd, opts)
+			if err != nil {
+				return err
+			}
+			path := a.socketPath()
+			ctx, cancel := context.WithTimeout(a.ctx, 5*time.Second)
+			defer cancel()
+			status, err := daemon.GetStatus(ctx, path)
+			if errors.Is(err, daemon.ErrNotRunning) {
+				return fmt.Errorf("no daemon is running on %s (start one with 'jirar daemon')", path)
+			}
+			if err != nil {
+				return err
+			}
+			return printer.Render(cmd.OutOrStdout(), daemonStatusResult(status, path))
+		},
+	}
+
+	addOutputFlags(cmd, &opts)
+
+	return cmd
+}
+
+// daemonStatusResult describes a daemon's status for the output printer.
+func daemonStatusResult(status *daemon.Status, path string) output.Result {
+	rows := [][]string{
+		{"Profile", profileName(status.Profile)},
+		{"Jira", status.Domain},
+		{"PID", fmt.Sprint(status.PID)},
+		{"Started", status.Started.Format(time.DateTime)},
+		{"Socket", path},
+		{"Refresh", status.Refresh},
+		{"Cached", fmt.Sprintf("%d results, %d hits, %d misses", status.Cached, status.Hits, status.Misses)},
+	}
+	for _, filter := range status.Watching {
+		rows = append(rows, []string{"Watching", filter})
+	}
+
+	return output.Result{
+		Data:  status,
+		Table: output.Table{Headers: []string{"Field", "Value"}, Rows: rows},
+		Human: func(w io.Writer) error {
+			for _, row := range rows {
+				fmt.Fprintf(w, "%-9s %s\n", row[0]+":", row[1])
+			}
+			return nil
+		},
+	}
+}
+
+// buildDaemonStopCommand creates the daemon stop command.
+func (a *App) buildDaemonStopCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the running daemon",
+		Long: `Stop the daemon listening on the socket. A daemon run by systemd is
+better stopped with systemctl --user stop jirar.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := a.socketPath()
+			ctx, cancel := context.WithTimeout(a.ctx, 10*time.Second)
+			defer cancel()
+			err := daemon.Stop(ctx, path)
+			if errors.Is(err, daemon.ErrNotRunning) {
+				return fmt.Errorf("no daemon is running on %s", path)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.ErrOrStderr(), "Stopped the daemon.")
+			return nil
+		},
+	}
+}
+
+// buildDaemonInstallCommand creates the daemon install command.
+func (a *App) buildDaemonInstallCommand() *cobra.Command {
+	var (
+		print bool
+		force bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install the daemon as a systemd user service",
+		Long: `Write a systemd user unit that runs jirar daemon with this executable
+and the --profile and --config given, to ~/.config/systemd/user/jirar.service.
+Enable and start it with:
+
+  systemctl --user daemon-reload
+  systemctl --user enable --now jirar
+
+The service does not see the variables of your shell, so keep the Jira
+token in the configuration or a secret backend rather than in JIRA_TOKEN.`,
+		Example: `  jirar daemon install
+  jirar --profile work daemon install --force
+  jirar daemon install --print`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			executable, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("find jirar executable: %w", err)
+			}
+			if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+				executable = resolved
+			}
+			daemonArgs := []string{"daemon"}
+			if a.profile != "" {
+				daemonArgs = append(daemonArgs, "--profile", a.profile)
+			}
+			if a.configPath != "" {
+				configPath, err := filepath.Abs(a.configPath)
+				if err != nil {
+					return fmt.Errorf("resolve config path: %w", err)
+				}
+				daemonArgs = append(daemonArgs, "--config", configPath)
+			}
+			unit := daemon.SystemdUnit(executable, daemonArgs)
+			if print {
+				_, err := io.WriteString(cmd.OutOrStdout(), unit)
+				return err
+			}
+
+			path, err := daemon.DefaultUnitPath()
+			if err != nil {
+				return err
+			}
+			if _, err := os.Stat(path); err == nil && !force {
+				return fmt.Errorf("%s already exists (use --force to replace it)", path)
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return fmt.Errorf("create unit directory: %w", err)
+			}
+			if err := os.WriteFile(path, []byte(unit), 0o644); err != nil {
+				return fmt.Errorf("write unit: %w", err)
+			}
+
+			name := strings.TrimSuffix(daemon.UnitName, ".service")
+			fmt.Fprintf(cmd.ErrOrStderr(), "Wrote %s. Start the daemon with:\n\n", path)
+			fmt.Fprintf(cmd.OutOrStdout(), "  systemctl --user daemon-reload\n  systemctl --user enable --now %s\n", name)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&print, "print", false, "Print the unit instead of writing it")
+	cmd.Flags().BoolVar(&force, "force", false, "Replace an existing unit")
+
+	return cmd
+}
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"jirar/internal/doctor"
+	"jirar/internal/jira"
+	"jirar/internal/output"
+	"jirar/internal/theme"
+)
+
+// doctorIcons maps check statuses to checklist markers.
+var doctorIcons = map[string]string{
+	doctor.StatusPass: "✅",
+	doctor.StatusWarn: "⚠️ ",
+	doctor.StatusFail: "❌",
+	doctor.StatusSkip: "⏭️ ",
+}
+
+// doctorMarkers replace the icons when ui.icons is off.
+var doctorMarkers = map[string]string{
+	doctor.StatusPass: "[ok]  ",
+	doctor.StatusWarn: "[warn]",
+	doctor.StatusFail: "[fail]",
+	doctor.StatusSkip: "[skip]",
+}
+
+// doctorColors maps check statuses to theme colors.
+var doctorColors = map[string]string{
+	doctor.StatusPass: "green",
+	doctor.StatusWarn: "yellow",
+	doctor.StatusFail: "red",
+	doctor.StatusSkip: "gray",
+}
+
+// buildDoctorCommand creates the doctor command.
+func (a *App) buildDoctorCommand() *cobra.Command {
+	var opts output.Options
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose configuration, connectivity and permissions",
+		Long: `Check that jirar can reach and use Jira: configuration resolution,
+DNS and TLS reachability of the domain, Cloud or Server/Data Center
+detection, credential validity, clock skew against the server, API
+rate-limit headroom and permissions in the default project.
+
+Use -o json to attach the report to a support ticket.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := a.printer(cmd, opts)
+			if err != nil {
+				return err
+			}
+
+			// Failures are reported in the checklist, not the log
+			logger := a.logger
+			if !a.config.IsDebug() {
+				logger = logrus.New()
+				logger.SetOutput(io.Discard)
+			}
+
+			client := jira.NewClient(&a.config.Jira, logger)
+			report := doctor.New(a.config, client, a.root.Version).Run(a.ctx)
+
+			out := cmd.OutOrStdout()
+			if err := printer.Render(out, doctorResult(report, theme.New(a.config.UI, theme.Options{Colors: a.colorEnabled(out)}))); err != nil {
+				return err
+			}
+
+			if n := report.Failed(); n > 0 {
+				return fmt.Errorf("%d check(s) failed", n)
+			}
+			return nil
+		},
+	}
+
+	addOutputFlags(cmd, &opts)
+
+	return cmd
+}
+
+// doctorResult describes the report for the output printer; the table
+// format keeps the checklist.
+func doctorResult(report *doctor.Report, th *theme.Theme) output.Result {
+	table := output.Table{Headers: []string{"Check", "Status", "Message", "Hint"}}
+	records := make([]any, 0, len(report.Checks))
+	for _, c := range report.Checks {
+		table.Rows = append(table.Rows, []string{c.Name, c.Status, c.Message, c.Hint})
+		records = append(records, c)
+	}
+
+	return output.Result{
+		Data:    report,
+		Records: records,
+		Table:   table,
+		Human: func(w io.Writer) error {
+			printDoctorReport(w, report, th)
+			return nil
+		},
+	}
+}
+
+// printDoctorReport renders the checklist with remediation hints.
+func printDoctorReport(w io.Writer, report *doctor.Report, th *theme.Theme) {
+	fmt.Fprintf(w, "jirar %s doctor — %s\n\n", report.Version, report.Domain)
+	for _, c := range report.Checks {
+		marker := doctorMarkers[c.Status]
+		if th.Icons() {
+			marker = doctorIcons[c.Status]
+		}
+		fmt.Fprintf(w, "%s %-12s %s\n", th.Paint(doctorColors[c.Status], marker), c.Name, c.Message)
+		if c.Hint != "" && c.Status != doctor.StatusPass {
+			fmt.Fprintf(w, "   %-12s → %s\n", "", c.Hint)
+		}
+	}
+}
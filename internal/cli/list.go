@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"jirar/internal/jira"
+	"jirar/internal/output"
+)
+
+// listStatuses maps list --status values to JQL clauses.
+var listStatuses = map[string]string{
+	"todo":        `statusCategory = "To Do"`,
+	"in-progress": `statusCategory = "In Progress"`,
+	"done":        `statusCategory = Done`,
+}
+
+// listLimit is the number of issues jirar list shows by default.
+const listLimit = 20
+
+// listSortFields are the fields list --sort accepts.
+var listSortFields = []string{"updated", "created", "priority"}
+
+// buildListCommand creates the list command.
+func (a *App) buildListCommand() *cobra.Command {
+	var (
+		status  string
+		limit   int
+		project string
+		sort    string
+		columns string
+		opts    output.Options
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List Jira tickets assigned to you",
+		Long: `List all Jira tickets that are currently assigned to you.
+Supports filtering by status, project, and sorting options.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := a.printer(cmd, opts)
+			if err != nil {
+				return err
+			}
+			styler := a.styler(cmd.OutOrStdout(), printer)
+			cols, err := a.issueColumns(cmd.Name(), columns, styler.Hyperlinks())
+			if err != nil {
+				return err
+			}
+			if project == "" {
+				project = a.config.Defaults.Project
+			}
+			jql, err := listJQL(status, project, sort)
+			if err != nil {
+				return err
+			}
+
+			return a.searchIssues(cmd, printer, styler, jql, limit, cols)
+		},
+	}
+
+	// Add command flags
+	cmd.Flags().StringVarP(&status, "status", "s", "", "Filter by status (todo, in-progress, done, or a status name)")
+	cmd.Flags().IntVarP(&limit, "limit", "l", listLimit, "Maximum number of tickets to show")
+	cmd.Flags().StringVarP(&project, "project", "p", "", "Filter by project key (default: defaults.project)")
+	cmd.Flags().StringVar(&sort, "sort", "updated", "Sort field (updated, created, priority)")
+	addColumnsFlag(cmd, &columns)
+	addOutputFlags(cmd, &opts)
+
+	return cmd
+}
+
+// listJQL builds the query behind jirar list.
+func listJQL(status, project, sort string) (string, error) {
+	clauses := []string{"assignee = currentUser()"}
+	if project != "" {
+		clauses = append(clauses, fmt.Sprintf("project = %q", project))
+	}
+	if status != "" {
+		clause, ok := listStatuses[strings.ToLower(status)]
+		if !ok {
+			clause = fmt.Sprintf("status = %q", status)
+		}
+		clauses = append(clauses, clause)
+	}
+
+	valid := false
+	for _, field := range listSortFields {
+		if sort == field {
+			valid = true
+		}
+	}
+	if !valid {
+		return "", fmt.Errorf("invalid sort field %q (use %s)", sort, strings.Join(listSortFields, ", "))
+	}
+
+	return fmt.Sprintf("%s ORDER BY %s DESC", strings.Join(clauses, " AND "), sort), nil
+}
+
+// searchIssues runs jql and renders the first page of results, fetching
+// the fields the columns need.
+func (a *App) searchIssues(cmd *cobra.Command, printer *output.Printer, styler output.Styler, jql string, limit int, columns []output.Column) error {
+	client, err := a.jiraClient()
+	if err != nil {
+		return err
+	}
+
+	a.logger.WithField("jql", jql).Debug("Searching issues")
+	res, err := client.SearchIssues(a.ctx, jql, jira.WithLimit(limit), jira.WithFields(output.ColumnFields(columns)...))
+	if err != nil {
+		return err
+	}
+
+	result := output.Issues(a.config.Jira.Domain, res, limit, columns, styler)
+	return printer.Render(cmd.OutOrStdout(), result)
+}
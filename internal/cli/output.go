@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"jirar/internal/output"
+	"jirar/internal/theme"
+)
+
+// addOutputFlags registers -o/--output, --template and --jq on cmd. The legacy
+// --json switch stays as a hidden alias for -o json.
+func addOutputFlags(cmd *cobra.Command, opts *output.Options) {
+	cmd.Flags().StringVarP(&opts.Format, "output", "o", "", "Output format: "+strings.Join(output.Formats, ", ")+" (default: ui.output)")
+	cmd.Flags().StringVar(&opts.Template, "template", "", "Go template applied to the JSON output")
+	cmd.Flags().StringVar(&opts.JQ, "jq", "", "Filter the JSON output with a jq expression")
+	cmd.Flags().Bool("json", false, "Output in JSON format")
+	cmd.Flags().MarkDeprecated("json", "use -o json instead")
+}
+
+// printer returns the printer selected by the output flags of cmd, falling
+// back to the configured ui.output format.
+func (a *App) printer(cmd *cobra.Command, opts output.Options) (*output.Printer, error) {
+	if legacy, _ := cmd.Flags().GetBool("json"); legacy && opts.Format == "" {
+		opts.Format = output.FormatJSON
+	}
+	if opts.Format == "" {
+		opts.Format = a.config.UI.Output
+	}
+	if f, ok := cmd.OutOrStdout().(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		opts.Width, _, _ = term.GetSize(int(f.Fd()))
+	}
+	opts.Compact = a.config.UI.Compact
+	return output.New(opts)
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// colorEnabled reports whether output to w may use ANSI colors: ui.colors
+// is on, neither --no-color nor NO_COLOR is set, and w is a terminal.
+func (a *App) colorEnabled(w io.Writer) bool {
+	if a.noColor || !a.config.UI.Colors || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(w)
+}
+
+// hyperlinksEnabled reports whether issue keys written to w may be OSC 8
+// hyperlinks, following ui.hyperlinks.
+func (a *App) hyperlinksEnabled(w io.Writer) bool {
+	switch a.config.UI.Hyperlinks {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return isTerminal(w) && os.Getenv("TERM") != "dumb" && theme.SupportsHyperlinks(os.Getenv)
+}
+
+// timeLocation returns the timezone for absolute times: ui.timezone, else
+// the Jira account's timezone, else the local one.
+func (a *App) timeLocation() *time.Location {
+	if a.location != nil {
+		return a.location
+	}
+
+	a.location = time.Local
+	name := a.config.UI.TimeZone
+	if name == "" {
+		if client, err := a.jiraClient(); err == nil {
+			if user, err := client.GetCurrentUser(a.ctx); err == nil {
+				name = user.TimeZone
+			}
+		}
+	}
+	if name != "" {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			a.logger.WithError(err).WithField("timezone", name).Warn("Unknown timezone, using local time")
+		} else {
+			a.location = loc
+		}
+	}
+	return a.location
+}
+
+// theme returns the theme for terminal output written to w.
+func (a *App) theme(w io.Writer) *theme.Theme {
+	return theme.New(a.config.UI, a.themeOptions(w, a.config.UI.TimeFormat))
+}
+
+// themeOptions detects the capabilities of w for a theme.
+func (a *App) themeOptions(w io.Writer, timeFormat string) theme.Options {
+	opts := theme.Options{
+		Colors:     a.colorEnabled(w),
+		Hyperlinks: a.hyperlinksEnabled(w),
+		TimeFormat: timeFormat,
+	}
+	if timeFormat != theme.TimeRelative {
+		opts.Location = a.timeLocation()
+	}
+	return opts
+}
+
+// styler returns the theme for issue cells. Only the table format is
+// styled; machine formats get RFC 3339 times unless --time-format is given.
+func (a *App) styler(w io.Writer, printer *output.Printer) *theme.Theme {
+	if printer.Format() == output.FormatTable && !printer.Filtering() {
+		return a.theme(w)
+	}
+
+	format := theme.TimeISO
+	if a.root.PersistentFlags().Changed("time-format") {
+		format = a.config.UI.TimeFormat
+	}
+	return theme.Plain(a.themeOptions(w, format))
+}
+
+// addColumnsFlag registers --columns on a command printing issue tables.
+func addColumnsFlag(cmd *cobra.Command, columns *string) {
+	cmd.Flags().StringVar(columns, "columns", "", "Table columns or a preset name from ui.columns.presets."+cmd.Name())
+}
+
+// issueColumns resolves --columns for a command. A single name matching a
+// preset of the command expands to the preset; an empty value uses the
+// command's "default" preset, else the built-in columns, with a link
+// column when keys are not hyperlinks.
+func (a *App) issueColumns(command, spec string, hyperlinks bool) ([]output.Column, error) {
+	presets := a.config.UI.Columns.Presets[command]
+	if spec == "" {
+		spec = output.DefaultColumns
+		if !hyperlinks {
+			spec += ",link"
+		}
+		if preset, ok := presets["default"]; ok {
+			spec = preset
+		}
+	} else if preset, ok := presets[strings.ToLower(spec)]; ok {
+		spec = preset
+	}
+
+	columns, err := output.ParseColumns(spec, a.config.UI.Columns.Aliases)
+	if err != nil {
+		return nil, fmt.Errorf("--columns: %w", err)
+	}
+	return columns, nil
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"jirar/internal/jira"
+	"jirar/internal/recent"
+	"jirar/internal/theme"
+	"jirar/internal/tui"
+)
+
+// issueKeyPattern matches an issue key such as PROJ-123.
+var issueKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]+-[0-9]+$`)
+
+// assignedJQL selects the issues offered by the picker besides recent ones.
+const assignedJQL = "assignee = currentUser() AND resolution = Unresolved ORDER BY updated DESC"
+
+// assignedLimit caps the assigned issues kept for the picker.
+const assignedLimit = 50
+
+// addPickFlag registers --pick on a command taking an issue key.
+func addPickFlag(cmd *cobra.Command, pick *bool) {
+	cmd.Flags().BoolVar(pick, "pick", false, "Pick the issue with the fuzzy finder even when a key is given")
+}
+
+// issueKey returns the issue key argument of cmd. Without one, or with
+// --pick, the user picks an issue from their recent and assigned issues
+// when running in a terminal.
+func (a *App) issueKey(cmd *cobra.Command, args []string, pick bool) (jira.Issue, error) {
+	if len(args) > 0 && !pick {
+		key, err := a.normalizeKey(args[0])
+		return jira.Issue{Key: key}, err
+	}
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+		return jira.Issue{}, fmt.Errorf("issue key required (run in a terminal to pick one)")
+	}
+	return a.pickIssue(cmd.Name())
+}
+
+// normalizeKey upper-cases an issue key and prefixes a bare number with
+// defaults.project.
+func (a *App) normalizeKey(arg string) (string, error) {
+	key := strings.ToUpper(strings.TrimSpace(arg))
+	if filterIDPattern.MatchString(key) && a.config.Defaults.Project != "" {
+		key = strings.ToUpper(a.config.Defaults.Project) + "-" + key
+	}
+	if !issueKeyPattern.MatchString(key) {
+		return "", fmt.Errorf("invalid issue key %q (expected e.g. PROJ-123)", arg)
+	}
+	return key, nil
+}
+
+// pickIssue runs the fuzzy finder over the cached recent and assigned
+// issues while the assigned issues are refreshed in the background.
+// prompt names the command the issue is picked for.
+func (a *App) pickIssue(prompt string) (jira.Issue, error) {
+	client, err := a.quietClient()
+	if err != nil {
+		return jira.Issue{}, err
+	}
+	cache := a.recentCache()
+
+	opts := a.themeOptions(os.Stderr, a.config.UI.TimeFormat)
+	opts.Hyperlinks = false
+
+	// The refresh may still be running when the picker closes, so its
+	// client logs nothing and it only updates the cache while the picker
+	// is open.
+	var mu sync.Mutex
+	open := true
+	refresh := func(ctx context.Context) ([]jira.Issue, error) {
+		res, err := client.SearchIssues(ctx, assignedJQL, jira.WithLimit(assignedLimit))
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if open {
+			cache.SetAssigned(res.Issues, time.Now())
+		}
+		return cache.Issues(), nil
+	}
+
+	// Log lines would corrupt the screen
+	out := a.logger.Out
+	a.logger.SetOutput(io.Discard)
+	issue, err := tui.Pick(a.ctx, tui.PickOptions{
+		Issues:  cache.Issues(),
+		Refresh: refresh,
+		Theme:   theme.New(a.config.UI, opts),
+		Prompt:  prompt,
+	})
+	a.logger.SetOutput(out)
+
+	mu.Lock()
+	open = false
+	mu.Unlock()
+	if err := cache.Save(); err != nil {
+		a.logger.WithError(err).Debug("Could not save recent issues")
+	}
+	return issue, err
+}
+
+// recentCache loads the recent issues of the active profile. A broken
+// cache is logged and replaced.
+func (a *App) recentCache() *recent.Cache {
+	cache, err := recent.Load(recent.DefaultPath(a.config.ActiveProfile))
+	if err != nil {
+		a.logger.WithError(err).Debug("Ignoring recent issues cache")
+	}
+	return cache
+}
+
+// quietClient returns a Jira client like jiraClient's that does not log,
+// for requests whose failure the user need not see.
+func (a *App) quietClient() (jira.Client, error) {
+	if a.quiet != nil {
+		return a.quiet, nil
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	client, err := a.newClient(logger)
+	if err != nil {
+		return nil, err
+	}
+	a.quiet = client
+	return client, nil
+}
+
+// visit records an issue in the recent issues. Without a summary it is
+// looked up, unless the cache already knows it.
+func (a *App) visit(issue jira.Issue) {
+	cache := a.recentCache()
+	if issue.Fields.Summary == "" && !cachedIssue(cache, issue.Key) {
+		if client, err := a.quietClient(); err == nil {
+			ctx, cancel := context.WithTimeout(a.ctx, 3*time.Second)
+			defer cancel()
+			if full, err := client.GetIssue(ctx, issue.Key); err == nil {
+				issue = *full
+			}
+		}
+	}
+	cache.Visit(issue, time.Now())
+	if err := cache.Save(); err != nil {
+		a.logger.WithError(err).Debug("Could not save recent issues")
+	}
+}
+
+// cachedIssue reports whether the cache holds a summary for key.
+func cachedIssue(cache *recent.Cache, key string) bool {
+	for _, issue := range cache.Issues() {
+		if issue.Key == key && issue.Fields.Summary != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"jirar/internal/config"
+	"jirar/internal/output"
+)
+
+// profileNamePattern restricts profile names to safe config keys.
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// profileFields lists the profile keys settable from profile add flags.
+var profileFields = []string{"domain", "email", "token_ref", "credential_helper", "project", "board"}
+
+// buildProfileCommand creates the profile command.
+func (a *App) buildProfileCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage Jira profiles",
+		Long: `Manage named Jira instances.
+The current profile is used unless --profile selects another one.`,
+	}
+
+	cmd.AddCommand(a.buildProfileListCommand())
+	cmd.AddCommand(a.buildProfileUseCommand())
+	cmd.AddCommand(a.buildProfileAddCommand())
+	cmd.AddCommand(a.buildProfileRemoveCommand())
+
+	return cmd
+}
+
+// profileView is the output model of a profile; the token is never included.
+type profileView struct {
+	Name             string `json:"name"`
+	Current          bool   `json:"current"`
+	Domain           string `json:"domain,omitempty"`
+	Email            string `json:"email,omitempty"`
+	TokenRef         string `json:"token_ref,omitempty"`
+	CredentialHelper string `json:"credential_helper,omitempty"`
+	Project          string `json:"project,omitempty"`
+	Board            string `json:"board,omitempty"`
+}
+
+// buildProfileListCommand creates the profile list subcommand.
+func (a *App) buildProfileListCommand() *cobra.Command {
+	var opts output.Options
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List configured profiles",
+		Args:  cobra.NoArgs,
+		Annotations: map[string]string{
+			annotationLocal:           "true",
+			annotationManagesProfiles: "true",
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := a.printer(cmd, opts)
+			if err != nil {
+				return err
+			}
+
+			views := []profileView{}
+			table := output.Table{Headers: []string{"Name", "Current", "Domain", "Email", "Project", "Board"}}
+			for _, name := range a.config.ProfileNames() {
+				p := a.config.Profiles[name]
+				view := profileView{
+					Name:             name,
+					Current:          name == a.config.ActiveProfile,
+					Domain:           p.Domain,
+					Email:            p.Email,
+					TokenRef:         p.TokenRef,
+					CredentialHelper: p.CredentialHelper,
+					Project:          p.Project,
+					Board:            p.Board,
+				}
+				views = append(views, view)
+
+				marker := ""
+				if view.Current {
+					marker = "*"
+				}
+				table.Rows = append(table.Rows, []string{name, marker, p.Domain, p.Email, p.Project, p.Board})
+			}
+
+			records := make([]any, len(views))
+			for i, v := range views {
+				records[i] = v
+			}
+			return printer.Render(cmd.OutOrStdout(), output.Result{Data: views, Records: records, Table: table})
+		},
+	}
+
+	addOutputFlags(cmd, &opts)
+
+	return cmd
+}
+
+// buildProfileUseCommand creates the profile use subcommand.
+func (a *App) buildProfileUseCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "use [name]",
+		Short: "Set the current profile",
+		Args:  cobra.ExactArgs(1),
+		Annotations: map[string]string{
+			annotationLocal:           "true",
+			annotationManagesProfiles: "true",
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if _, ok := a.config.Profiles[name]; !ok {
+				return fmt.Errorf("unknown profile %q", name)
+			}
+
+			if err := a.editConfigFile(func(f *config.File) error {
+				return f.Set("current_profile", name)
+			}); err != nil {
+				return err
+			}
+
+			a.logger.WithField("profile", name).Info("Switched profile")
+			return nil
+		},
+	}
+	return cmd
+}
+
+// buildProfileAddCommand creates the profile add subcommand.
+func (a *App) buildProfileAddCommand() *cobra.Command {
+	var (
+		profile config.Profile
+		use     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "add [name]",
+		Short: "Add or update a profile",
+		Long: `Add a named Jira instance, or update the given fields of an existing one.
+Store its token afterwards with: jirar --profile NAME config set-token`,
+		Example: `  jirar profile add acme --domain https://jira.acme.com --email me@acme.com \
+    --token-ref keyring:jirar/acme --project ACME --board 12`,
+		Args: cobra.ExactArgs(1),
+		Annotations: map[string]string{
+			annotationLocal:           "true",
+			annotationManagesProfiles: "true",
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := strings.ToLower(args[0])
+			if !profileNamePattern.MatchString(name) {
+				return fmt.Errorf("invalid profile name %q: use letters, digits, '-' and '_'", args[0])
+			}
+
+			values := map[string]string{
+				"domain":            profile.Domain,
+				"email":             profile.Email,
+				"token_ref":         profile.TokenRef,
+				"credential_helper": profile.CredentialHelper,
+				"project":           profile.Project,
+				"board":             profile.Board,
+			}
+
+			if err := a.editConfigFile(func(f *config.File) error {
+				key := "profiles." + name
+				if !f.Has(key) {
+					if err := f.Set(key, map[string]string{}); err != nil {
+						return err
+					}
+				}
+				for _, field := range profileFields {
+					if !cmd.Flags().Changed(strings.ReplaceAll(field, "_", "-")) {
+						continue
+					}
+					if err := f.Set(key+"."+field, values[field]); err != nil {
+						return err
+					}
+				}
+				if use {
+					return f.Set("current_profile", name)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+
+			a.logger.WithField("profile", name).Info("Profile saved")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&profile.Domain, "domain", "", "Jira base URL")
+	cmd.Flags().StringVar(&profile.Email, "email", "", "Account email")
+	cmd.Flags().StringVar(&profile.TokenRef, "token-ref", "", "Secret reference for the API token")
+	cmd.Flags().StringVar(&profile.CredentialHelper, "credential-helper", "", "git-credential style helper command")
+	cmd.Flags().StringVar(&profile.Project, "project", "", "Default project key")
+	cmd.Flags().StringVar(&profile.Board, "board", "", "Default board ID")
+	cmd.Flags().BoolVar(&use, "use", false, "Make this the current profile")
+
+	return cmd
+}
+
+// buildProfileRemoveCommand creates the profile remove subcommand.
+func (a *App) buildProfileRemoveCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "remove [name]",
+		Aliases: []string{"rm"},
+		Short:   "Remove a profile",
+		Args:    cobra.ExactArgs(1),
+		Annotations: map[string]string{
+			annotationLocal:           "true",
+			annotationManagesProfiles: "true",
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			if err := a.editConfigFile(func(f *config.File) error {
+				if !f.Unset("profiles." + name) {
+					return fmt.Errorf("unknown profile %q", name)
+				}
+				var current string
+				if _, err := f.Get("current_profile", &current); err != nil {
+					return err
+				}
+				if current == name {
+					f.Unset("current_profile")
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+
+			a.logger.WithField("profile", name).Info("Profile removed")
+			return nil
+		},
+	}
+	return cmd
+}
+
+// editConfigFile applies edit to the user configuration file and saves it.
+func (a *App) editConfigFile(edit func(*config.File) error) error {
+	return a.editFile(a.config.WritePath(), edit)
+}
+
+// editFile applies edit to the configuration file at path and saves it.
+func (a *App) editFile(path string, edit func(*config.File) error) error {
+	f, err := config.LoadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := edit(f); err != nil {
+		return err
+	}
+	if err := f.Save(); err != nil {
+		return err
+	}
+
+	a.logger.WithField("path", f.Path).Debug("Configuration file updated")
+	return nil
+}
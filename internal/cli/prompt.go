@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// prompter asks line-based questions on a terminal or piped stdin.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// newPrompter creates a prompter reading from in and writing questions to out.
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{in: bufio.NewReader(in), out: out}
+}
+
+// ask prompts for a value, returning def when the answer is empty.
+func (p *prompter) ask(label, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", label, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", label)
+	}
+
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("read %s: %w", strings.ToLower(label), err)
+	}
+
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// askSecret prompts for a value without echo when stdin is a terminal.
+func (p *prompter) askSecret(label string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return p.ask(label, "")
+	}
+
+	fmt.Fprintf(p.out, "%s: ", label)
+	b, err := term.ReadPassword(fd)
+	fmt.Fprintln(p.out)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", strings.ToLower(label), err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// choose lists options and returns the index picked by number or by exact
+// (case-insensitive) match, or -1 when the answer is empty.
+func (p *prompter) choose(label string, options []string) (int, error) {
+	for i, option := range options {
+		fmt.Fprintf(p.out, "  %2d) %s\n", i+1, option)
+	}
+
+	for {
+		answer, err := p.ask(label+" (number, blank to skip)", "")
+		if err != nil || answer == "" {
+			return -1, err
+		}
+
+		if n, convErr := strconv.Atoi(answer); convErr == nil && n >= 1 && n <= len(options) {
+			return n - 1, nil
+		}
+		for i, option := range options {
+			if strings.EqualFold(option, answer) || strings.HasPrefix(strings.ToLower(option), strings.ToLower(answer)+" ") {
+				return i, nil
+			}
+		}
+		fmt.Fprintf(p.out, "Invalid choice %q\n", answer)
+	}
+}
+
+// confirm asks a yes/no question.
+func (p *prompter) confirm(label string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+
+	answer, err := p.ask(label+" ("+hint+")", "")
+	if err != nil || answer == "" {
+		return def, err
+	}
+	return strings.HasPrefix(strings.ToLower(answer), "y"), nil
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"jirar/internal/output"
+)
+
+// buildSearchCommand creates the search command.
+func (a *App) buildSearchCommand() *cobra.Command {
+	var (
+		limit   int
+		columns string
+		opts    output.Options
+	)
+
+	cmd := &cobra.Command{
+		Use:   "search [jql]",
+		Short: "Search Jira tickets using JQL",
+		Long: `Search for Jira tickets using Jira Query Language (JQL).
+If no JQL is provided, will prompt for a query.`,
+		Example: `  jirar search "project = PROJ AND status = 'In Progress'"
+  jirar search "assignee = currentUser()" -o keys
+  jirar search "updated >= -1d" --template '{{range .issues}}{{.key}} {{.fields.summary}}{{"\n"}}{{end}}'`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := a.printer(cmd, opts)
+			if err != nil {
+				return err
+			}
+			styler := a.styler(cmd.OutOrStdout(), printer)
+			cols, err := a.issueColumns(cmd.Name(), columns, styler.Hyperlinks())
+			if err != nil {
+				return err
+			}
+
+			var jql string
+			if len(args) == 1 {
+				jql = args[0]
+			} else {
+				jql, err = newPrompter(cmd.InOrStdin(), cmd.ErrOrStderr()).ask("JQL", "")
+				if err != nil {
+					return err
+				}
+			}
+			if jql == "" {
+				return fmt.Errorf("empty JQL query")
+			}
+
+			return a.searchIssues(cmd, printer, styler, jql, limit, cols)
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "limit", "l", 50, "Maximum number of results")
+	addColumnsFlag(cmd, &columns)
+	addOutputFlags(cmd, &opts)
+
+	return cmd
+}
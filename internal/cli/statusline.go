@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"jirar/internal/statusline"
+)
+
+// statusLineSnippets configure status bars and prompts to show
+// jirar-status-line, by tool.
+var statusLineSnippets = map[string]string{
+	"starship": `# ~/.config/starship.toml
+[custom.jira]
+command = "jirar-status-line"
+when = true
+format = "[$output]($style) "
+style = "bold blue"
+`,
+	"tmux": `# ~/.tmux.conf
+set -g status-interval 15
+set -g status-right "#(jirar-status-line) %H:%M"
+`,
+	"i3blocks": `# ~/.config/i3blocks/config
+[jira]
+command=jirar-status-line --format '{{.Assigned}} 💬{{.Unread}}{{if .Stale}} ?{{end}}'
+interval=30
+`,
+	"waybar": `// ~/.config/waybar/config, with "custom/jira" in a modules list
+"custom/jira": {
+    "exec": "jirar-status-line --format waybar",
+    "return-type": "json",
+    "interval": 30,
+    "on-click": "jirar status-line --mark-read"
+}
+`,
+}
+
+// buildStatusLineCommand creates the status-line command.
+func (a *App) buildStatusLineCommand() *cobra.Command {
+	var (
+		format   string
+		markRead bool
+		snippet  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "status-line",
+		Short: "Print counts of your issues for prompts and status bars",
+		Long: `Print a line for shell prompts and status bars: how many issues are
+assigned to you, how many are in progress and how many have comments you
+have not read. It only reads files jirar keeps locally and never waits for
+Jira.
+
+The counts are kept current by jirar daemon. Comments are recorded by the
+daemon, jirar watch and jirar webhook serve, and are read once you open
+the issue with jirar, click Mark read on its notification, or run
+--mark-read.
+
+--format is a Go template (default: ui.status_line) over .Assigned,
+.InProgress, .Unread, .Mentions, .Keys (the issues with unread comments),
+.Counted, .Age and .Stale, which is set when the counts are older than
+three daemon refresh periods. join joins a list, e.g. {{join .Keys " "}}.
+--format waybar prints the JSON of a waybar custom module.
+
+Prompts run it often; the jirar-status-line executable prints the same
+line in a few milliseconds, where jirar needs several times as long to
+start. --snippet prints a configuration for starship, tmux, i3blocks or
+waybar.`,
+		Example: `  jirar status-line
+  jirar status-line --format '{{.Assigned}} 🔥{{.InProgress}} 💬{{.Unread}}'
+  jirar status-line --format '{{if .Mentions}}@{{.Mentions}}{{end}}'
+  jirar status-line --snippet tmux >> ~/.tmux.conf`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{annotationLocal: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if snippet != "" {
+				text, ok := statusLineSnippets[strings.ToLower(snippet)]
+				if !ok {
+					return fmt.Errorf("unknown snippet %q (use %s)", snippet, strings.Join(slices.Sorted(maps.Keys(statusLineSnippets)), ", "))
+				}
+				_, err := fmt.Fprint(cmd.OutOrStdout(), text)
+				return err
+			}
+
+			if format == "" {
+				format = a.config.UI.StatusLine
+			}
+			return statusline.Print(cmd.OutOrStdout(), statusline.Options{
+				Profile:    a.config.ActiveProfile,
+				Format:     format,
+				StaleAfter: 3 * a.config.Daemon.Refresh,
+				MarkRead:   markRead,
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "", "Go template of the line, or waybar (default: ui.status_line)")
+	cmd.Flags().BoolVar(&markRead, "mark-read", false, "Mark all comments read first")
+	cmd.Flags().StringVar(&snippet, "snippet", "", "Print the configuration of starship, tmux, i3blocks or waybar")
+
+	return cmd
+}
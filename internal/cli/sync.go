@@ -0,0 +1,318 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"jirar/internal/jira"
+	"jirar/internal/output"
+	"jirar/internal/store"
+)
+
+// buildSyncCommand creates the sync command.
+func (a *App) buildSyncCommand() *cobra.Command {
+	var (
+		full    bool
+		scopes  []string
+		status  bool
+		push    bool
+		force   bool
+		outbox  bool
+		discard []uint
+		opts    output.Options
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Sync issues into the local store",
+		Long: `Sync the issues of the store.scopes JQL queries into a local store under
+$XDG_CACHE_HOME/jirar/<profile>, with their comments and the users,
+projects, statuses and fields they use.
+
+The first sync of a scope fetches all its issues; later syncs only fetch
+those updated since the last one. Every store.reconcile (24h), or with
+--full, a sync also lists each scope in full to drop the issues deleted or
+moved out of it, and refetches projects, statuses and fields. Issues of
+scopes no longer configured are dropped.
+
+Read commands given --cached or --offline answer from the store: list,
+search, open and ui evaluate JQL locally over the synced issues. --cached
+asks Jira for what the store lacks, such as boards or JQL it cannot
+evaluate; --offline never talks to Jira. Both print how old the store is
+and suggest a sync once it is older than store.stale_after (1h).
+
+Offline, comments, transitions, assignments and jirar api changes to
+issues, such as edits and worklogs, are queued in an outbox in the store
+and show in it at once. --push sends them to Jira in order before syncing.
+A change to an issue updated in Jira after it was synced is a conflict:
+it is held back, with later changes to the issue, and stays queued until
+pushed with --force or dropped with --discard. Changes Jira refuses are
+dropped. Either way the push reports why.`,
+		Example: `  jirar sync
+  jirar sync --full
+  jirar sync --scope 'project = PROJ AND resolution = Unresolved'
+  jirar sync --status
+  jirar list --offline
+  jirar sync --push
+  jirar sync --outbox
+  jirar sync --discard 3`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := a.printer(cmd, opts)
+			if err != nil {
+				return err
+			}
+			// Only syncing and discarding write the store
+			st, err := a.openStore(status || outbox)
+			if err != nil {
+				return err
+			}
+			switch {
+			case status:
+				info, err := st.Info()
+				if err != nil {
+					return err
+				}
+				return printer.Render(cmd.OutOrStdout(), storeInfoResult(info, time.Now()))
+			case outbox:
+				ops, err := st.Outbox()
+				if err != nil {
+					return err
+				}
+				return printer.Render(cmd.OutOrStdout(), outboxResult(ops))
+			}
+
+			// Metadata refetched in full must not come from the cache
+			a.noCache = a.noCache || full
+			// The daemon caches searches, which would hide recent updates
+			client, err := a.directClient()
+			if err != nil {
+				return err
+			}
+			if len(discard) > 0 {
+				return a.discardChanges(cmd, client, st, discard)
+			}
+			syncOpts := store.SyncOptions{
+				Domain:    a.config.Jira.Domain,
+				Scopes:    a.config.Store.Scopes,
+				Reconcile: a.config.Store.Reconcile,
+				Full:      full,
+				Prune:     len(scopes) == 0,
+				Push:      push || force,
+				Force:     force,
+				Logger:    a.logger,
+			}
+			if len(scopes) > 0 {
+				syncOpts.Scopes = scopes
+			}
+			if len(syncOpts.Scopes) == 0 {
+				return fmt.Errorf("no scopes to sync (set store.scopes or give --scope)")
+			}
+			result, err := store.Sync(a.ctx, client, st, syncOpts)
+			if err != nil {
+				return err
+			}
+			return printer.Render(cmd.OutOrStdout(), syncResult(result))
+		},
+	}
+
+	cmd.Flags().BoolVar(&full, "full", false, "Reconcile every scope and refetch metadata now")
+	cmd.Flags().StringArrayVar(&scopes, "scope", nil, "JQL query to sync instead of store.scopes (repeatable)")
+	cmd.Flags().BoolVar(&status, "status", false, "Show what the store holds instead of syncing")
+	cmd.Flags().BoolVar(&push, "push", false, "Push the changes queued offline first")
+	cmd.Flags().BoolVar(&force, "force", false, "Push conflicting changes too (implies --push)")
+	cmd.Flags().BoolVar(&outbox, "outbox", false, "List the changes queued offline instead of syncing")
+	cmd.Flags().UintSliceVar(&discard, "discard", nil, "Drop queued changes by ID instead of syncing")
+	cmd.MarkFlagsMutuallyExclusive("status", "outbox", "discard", "push")
+	addOutputFlags(cmd, &opts)
+
+	return cmd
+}
+
+// syncResult describes a sync for the output printer.
+func syncResult(result *store.SyncResult) output.Result {
+	table := output.Table{Headers: []string{"Scope", "Fetched", "Removed", "Sync"}}
+	for _, s := range result.Scopes {
+		kind := "delta"
+		switch {
+		case s.Initial:
+			kind = "initial"
+		case s.Reconciled:
+			kind = "reconciled"
+		}
+		table.Rows = append(table.Rows, []string{s.JQL, fmt.Sprint(s.Fetched), fmt.Sprint(s.Removed), kind})
+	}
+
+	return output.Result{
+		Data:  result,
+		Table: table,
+		Human: func(w io.Writer) error {
+			if p := result.Push; p != nil {
+				for _, op := range p.Pushed {
+					fmt.Fprintf(w, "Pushed #%d %s\n", op.ID, describeOp(op))
+				}
+				for _, r := range p.Rejected {
+					fmt.Fprintf(w, "Rejected #%d %s: %s", r.Op.ID, describeOp(r.Op), r.Reason)
+					if r.Kept {
+						fmt.Fprintf(w, " (kept; push with --force or drop with --discard %d)", r.Op.ID)
+					}
+					fmt.Fprintln(w)
+				}
+			}
+			for _, row := range table.Rows {
+				fmt.Fprintf(w, "%s: %s fetched, %s removed (%s)\n", row[0], row[1], row[2], row[3])
+			}
+			if result.Pruned > 0 {
+				fmt.Fprintf(w, "Dropped %d issues of scopes no longer configured.\n", result.Pruned)
+			}
+			if result.Queued > 0 {
+				fmt.Fprintf(w, "%d changes are queued; push them with 'jirar sync --push'.\n", result.Queued)
+			}
+			return nil
+		},
+	}
+}
+
+// storeInfoResult describes the local store for the output printer.
+func storeInfoResult(info *store.Info, now time.Time) output.Result {
+	synced := "never"
+	if t := info.Synced(); !t.IsZero() {
+		synced = output.TimeAgo(t, now)
+	}
+	rows := [][]string{
+		{"Path", info.Path},
+		{"Jira", info.Domain},
+		{"Size", fmt.Sprintf("%d KiB", info.Size/1024)},
+		{"Synced", synced},
+		{"Issues", fmt.Sprint(info.Issues)},
+		{"Comments", fmt.Sprint(info.Comments)},
+		{"Users", fmt.Sprint(info.Users)},
+		{"Projects", fmt.Sprint(info.Projects)},
+		{"Statuses", fmt.Sprint(info.Statuses)},
+		{"Fields", fmt.Sprint(info.Fields)},
+		{"Queued", fmt.Sprint(info.Queued)},
+	}
+	for _, s := range info.Scopes {
+		rows = append(rows, []string{"Scope", s.JQL})
+	}
+
+	return output.Result{
+		Data:  info,
+		Table: output.Table{Headers: []string{"Field", "Value"}, Rows: rows},
+		Human: func(w io.Writer) error {
+			for _, row := range rows {
+				fmt.Fprintf(w, "%-9s %s\n", row[0]+":", row[1])
+			}
+			return nil
+		},
+	}
+}
+
+// outboxResult lists queued changes for the output printer.
+func outboxResult(ops []store.Op) output.Result {
+	table := output.Table{Headers: []string{"ID", "Key", "Change", "Queued", "Conflict"}}
+	for _, op := range ops {
+		table.Rows = append(table.Rows, []string{fmt.Sprint(op.ID), op.Key, op.Action, op.Queued.Format(time.DateTime), op.Conflict})
+	}
+	return output.Result{Data: ops, Table: table}
+}
+
+// describeOp names a queued change and its issue.
+func describeOp(op store.Op) string {
+	if op.Key == "" {
+		return op.Action
+	}
+	return op.Action + " on " + op.Key
+}
+
+// discardChanges drops queued changes and refetches their issues, undoing
+// them in the store.
+func (a *App) discardChanges(cmd *cobra.Command, client jira.Client, st *store.Store, ids []uint) error {
+	keys := []string{}
+	for _, id := range ids {
+		ops, err := st.Unqueue(uint64(id))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Dropped #%d %s.\n", id, describeOp(ops[0]))
+		if ops[0].Key != "" && !slices.Contains(keys, ops[0].Key) {
+			keys = append(keys, ops[0].Key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := store.Refresh(a.ctx, client, st, keys...); err != nil {
+		a.logger.WithError(err).Warn("Could not refetch the issues; they show the dropped changes until synced")
+	}
+	return nil
+}
+
+// openStore opens the local store of the profile, once. A store opened
+// read-only, which other jirar can read meanwhile, is reopened for writing
+// when changes are queued.
+func (a *App) openStore(readOnly bool) (*store.Store, error) {
+	if a.store != nil {
+		return a.store, nil
+	}
+	open := store.Open
+	if readOnly {
+		open = store.OpenReadOnly
+	}
+	st, err := open(store.DefaultPath(a.config.ActiveProfile))
+	if err != nil {
+		return nil, err
+	}
+	a.store = st
+	return st, nil
+}
+
+// storeClient returns a client answering from the local store, asking Jira
+// for what it lacks unless offline, and tells how old the store is once.
+func (a *App) storeClient(logger *logrus.Logger) (jira.Client, error) {
+	st, err := a.openStore(true)
+	if err != nil {
+		return nil, err
+	}
+	info, err := st.Info()
+	if err != nil {
+		return nil, err
+	}
+	synced := info.Synced()
+	if synced.IsZero() {
+		return nil, fmt.Errorf("the local store is empty (run 'jirar sync')")
+	}
+	if a.config.Jira.Domain != "" && info.Domain != a.config.Jira.Domain {
+		return nil, fmt.Errorf("the local store holds %s, not %s (run 'jirar sync')", info.Domain, a.config.Jira.Domain)
+	}
+
+	var online jira.Client
+	if !a.offline {
+		direct, err := a.newDirectClient(logger)
+		if err != nil {
+			return nil, err
+		}
+		online = a.viaDaemon(direct)
+	}
+	if a.storeNoticed {
+		return store.NewClient(st, online, logger), nil
+	}
+	a.storeNoticed = true
+
+	age := time.Since(synced)
+	notice := fmt.Sprintf("Local store synced %s", output.TimeAgo(synced, time.Now()))
+	if info.Queued > 0 {
+		notice += fmt.Sprintf(", %d changes queued", info.Queued)
+	}
+	if a.config.Store.StaleAfter > 0 && age > a.config.Store.StaleAfter {
+		notice += "; run 'jirar sync' to refresh it"
+	}
+	fmt.Fprintln(a.root.ErrOrStderr(), notice+".")
+
+	return store.NewClient(st, online, logger), nil
+}
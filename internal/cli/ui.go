@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/spf13/cobra"
+
+	"jirar/internal/jira"
+	"jirar/internal/theme"
+	"jirar/internal/tui"
+)
+
+// filterIDPattern matches the ID of a saved Jira filter.
+var filterIDPattern = regexp.MustCompile(`^[0-9]+$`)
+
+// buildUICommand creates the ui command.
+func (a *App) buildUICommand() *cobra.Command {
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "ui [view|filter-id|jql]",
+		Short: "Browse issues in an interactive terminal UI",
+		Long: `Browse issues in a full-screen terminal UI: the issue list on the left,
+filtered fuzzily with /, and the selected issue with its rendered
+description and comments on the right.
+
+The argument is a view from the views config section, the ID of a saved
+Jira filter or a JQL query. Without one the "default" view is used, or
+your assigned issues.
+
+Keys: o open in browser, t transition, a assign, c comment, y copy key,
+r refresh, tab/enter focus the details, ? more help, q quit.`,
+		Example: `  jirar ui
+  jirar ui mine
+  jirar ui 10042
+  jirar ui "project = PROJ AND sprint in openSprints()"`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+				return fmt.Errorf("jirar ui needs an interactive terminal")
+			}
+
+			var arg string
+			if len(args) == 1 {
+				arg = args[0]
+			}
+			title, jql, err := a.resolveView(arg)
+			if err != nil {
+				return err
+			}
+
+			client, err := a.jiraClient()
+			if err != nil {
+				return err
+			}
+
+			opts := a.themeOptions(os.Stdout, a.config.UI.TimeFormat)
+			opts.Hyperlinks = false
+			style := "notty"
+			if opts.Colors {
+				style = "dark"
+				if env := os.Getenv("GLAMOUR_STYLE"); env != "" {
+					style = env
+				}
+			}
+
+			// Log lines would corrupt the screen
+			out := a.logger.Out
+			a.logger.SetOutput(io.Discard)
+			defer a.logger.SetOutput(out)
+
+			return tui.Run(a.ctx, tui.Options{
+				Client: client,
+				Domain: a.config.Jira.Domain,
+				Title:  title,
+				JQL:    jql,
+				Limit:  limit,
+				Theme:  theme.New(a.config.UI, opts),
+				Style:  style,
+				Visited: func(issue jira.Issue) {
+					a.visit(issue)
+				},
+			})
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "limit", "l", 100, "Maximum number of issues to load")
+
+	return cmd
+}
+
+// resolveView returns the title and JQL for a view name, a saved filter ID
+// or a JQL query. An empty argument selects the "default" view, falling
+// back to the issues assigned to the user.
+func (a *App) resolveView(arg string) (string, string, error) {
+	if arg == "" {
+		if jql, ok := a.config.Views["default"]; ok {
+			return "default", jql, nil
+		}
+		jql, err := listJQL("", a.config.Defaults.Project, "updated")
+		return "My issues", jql, err
+	}
+	if jql, ok := a.config.Views[arg]; ok {
+		return arg, jql, nil
+	}
+	if filterIDPattern.MatchString(arg) {
+		return "Filter " + arg, "filter = " + arg, nil
+	}
+	return arg, arg, nil
+}
@@ -0,0 +1,466 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"jirar/internal/browser"
+	"jirar/internal/jira"
+	"jirar/internal/jira/watcher"
+	"jirar/internal/notify"
+	"jirar/internal/output"
+	"jirar/internal/rules"
+	"jirar/internal/statusline"
+	"jirar/internal/theme"
+)
+
+// buildWatchCommand creates the watch command.
+func (a *App) buildWatchCommand() *cobra.Command {
+	var (
+		interval string
+		filter   string
+		once     bool
+		reset    bool
+		desktop  bool
+		sinks    []string
+		only     []string
+		opts     output.Options
+	)
+
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Watch issues for changes",
+		Long: `Poll Jira for changes to the issues you are assigned to or watch and
+print them as they happen: new assignments, status and priority changes,
+comments and mentions of you. With --desktop they also appear as desktop
+notifications, with buttons to open the issue or mark it read.
+
+Events can also be sent to sinks configured under watch.sinks: Slack,
+Microsoft Teams, Discord, ntfy or any JSON webhook. Rules under
+watch.rules pick the filter, event types and sinks, and can match events
+with an expression such as issue.fields.priority.name in ["Highest"].
+Rules may batch their events into periodic digests, and watch.quiet holds
+notifications back at night and on weekends. Messages a sink fails to
+deliver are kept on disk and retried.
+
+Each poll asks only for issues updated since the previous one and compares
+them with what was seen before. The checkpoint is kept on disk per profile
+and filter, so a restart neither replays nor misses changes. The first run
+records a baseline and reports nothing. Failed polls are retried with
+increasing delays.`,
+		Example: `  jirar watch
+  jirar watch --interval 60 --desktop
+  jirar watch --filter "project = PROJ AND priority in (Highest, High)" --sink team-slack
+  jirar watch --rule urgent
+  jirar watch -o ndjson | jq -r .key`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := a.printer(cmd, opts)
+			if err != nil {
+				return err
+			}
+			every, err := parseInterval(interval, a.config.Watch.Interval)
+			if err != nil {
+				return err
+			}
+			if desktop {
+				sinks = append(sinks, desktopSink)
+			}
+			client, err := a.directClient()
+			if err != nil {
+				return err
+			}
+			engine, filters, err := a.ruleEngine(client, filter, only, sinks)
+			if err != nil {
+				return err
+			}
+			defer engine.Close()
+
+			watchers, err := a.newWatchers(client, filters, every, reset, cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			th := a.theme(out)
+			var mu sync.Mutex
+
+			// Watchers of different filters run concurrently
+			handler := func(filter string) func(watcher.Event) {
+				return func(e watcher.Event) {
+					mu.Lock()
+					defer mu.Unlock()
+					if err := printer.Render(out, a.eventResult(e, th)); err != nil {
+						a.logger.WithError(err).Error("Failed to print event")
+					}
+					a.recordActivity(e)
+					engine.Handle(a.ctx, filter, e)
+				}
+			}
+
+			if once {
+				for i, w := range watchers {
+					if err := w.Poll(a.ctx, handler(filters[i])); err != nil {
+						return err
+					}
+				}
+				engine.Flush(a.ctx)
+				return nil
+			}
+
+			ctx, stop := signal.NotifyContext(a.ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go engine.Run(ctx, every)
+
+			errs := make(chan error, len(watchers))
+			for i, w := range watchers {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Watching %q every %s.\n", filters[i], every)
+				go func() { errs <- w.Run(ctx, handler(filters[i])) }()
+			}
+			fmt.Fprintln(cmd.ErrOrStderr(), "Press Ctrl+C to stop.")
+			for range watchers {
+				if err := <-errs; !errors.Is(err, context.Canceled) {
+					stop()
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&interval, "interval", "i", "", "Polling interval in seconds or as a duration such as 2m (default: watch.interval)")
+	cmd.Flags().StringVar(&filter, "filter", "", "JQL of the issues to watch, ignoring watch.rules (default: watch.filter, or your assigned and watched issues)")
+	cmd.Flags().BoolVar(&once, "once", false, "Poll once and exit")
+	cmd.Flags().BoolVar(&reset, "reset", false, "Forget the checkpoint and record a new baseline")
+	cmd.Flags().BoolVar(&desktop, "desktop", false, "Show desktop notifications (default: watch.desktop.enabled)")
+	cmd.Flags().StringSliceVar(&sinks, "sink", nil, "Also send every event to these sinks from watch.sinks")
+	cmd.Flags().StringSliceVar(&only, "rule", nil, "Only run these rules from watch.rules")
+	addOutputFlags(cmd, &opts)
+
+	cmd.AddCommand(a.buildWatchTestCommand())
+	return cmd
+}
+
+// buildWatchTestCommand creates the watch test command.
+func (a *App) buildWatchTestCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "test [sink...]",
+		Short: "Send a test event to notification sinks",
+		Long: `Send a made-up status change to the named sinks, or to all of watch.sinks,
+and report whether each accepted it. Messages waiting in the outboxes are
+retried first.`,
+		Example: `  jirar watch test
+  jirar watch test team-slack desktop`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = slices.Sorted(maps.Keys(a.config.Watch.Sinks))
+			}
+			if len(args) == 0 {
+				return fmt.Errorf("no sinks configured under watch.sinks")
+			}
+			var client jira.Client
+			if slices.Contains(args, desktopSink) {
+				c, err := a.jiraClient()
+				if err != nil {
+					return err
+				}
+				client = c
+			}
+			notifiers, err := a.notifiers(client, args)
+			if err != nil {
+				return err
+			}
+
+			e := watcher.Event{
+				Type: watcher.EventStatus,
+				Key:  "TEST-1",
+				Time: time.Now(),
+				From: "To Do",
+				To:   "In Progress",
+				Issue: jira.Issue{Key: "TEST-1", Fields: jira.Fields{
+					Summary:   "Test notification from jirar",
+					Status:    jira.Status{Name: "In Progress"},
+					Priority:  jira.Priority{Name: "Medium"},
+					IssueType: jira.IssueType{Name: "Task"},
+				}},
+			}
+			failed := 0
+			for _, name := range args {
+				err := notifiers[name].Notify(a.ctx, e)
+				notifiers[name].Close()
+				if err != nil {
+					failed++
+					fmt.Fprintf(cmd.ErrOrStderr(), "%s: %v\n", name, err)
+					continue
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s: delivered\n", name)
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d sinks failed", failed, len(args))
+			}
+			return nil
+		},
+	}
+}
+
+// newWatchers creates a watcher of each filter, resuming from its saved
+// state; reset forgets the state and records a new baseline.
+func (a *App) newWatchers(client jira.Client, filters []string, every time.Duration, reset bool, stderr io.Writer) ([]*watcher.Watcher, error) {
+	var watchers []*watcher.Watcher
+	for _, filter := range filters {
+		path := watcher.DefaultStatePath(a.config.ActiveProfile, filter)
+		if reset {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("reset watch state: %w", err)
+			}
+		}
+		state, err := watcher.LoadState(path)
+		if err != nil {
+			return nil, fmt.Errorf("%w (run with --reset to start over)", err)
+		}
+		if state.Checkpoint.IsZero() {
+			fmt.Fprintf(stderr, "Recording a baseline of %q; changes from now on are reported.\n", filter)
+		}
+
+		watchers = append(watchers, watcher.New(client, watcher.Options{
+			JQL:      filter,
+			Interval: every,
+			State:    state,
+			Logger:   a.logger,
+		}))
+	}
+	return watchers, nil
+}
+
+// recordActivity notes the comments of an event for jirar status-line.
+func (a *App) recordActivity(e watcher.Event) {
+	if e.Type != watcher.EventComment && e.Type != watcher.EventMention {
+		return
+	}
+	path := statusline.DefaultPath(a.config.ActiveProfile)
+	if err := statusline.Update(path, func(s *statusline.State) { s.Record(e) }); err != nil {
+		a.logger.WithError(err).Warn("Failed to record unread comments")
+	}
+}
+
+// desktopSink is the sink name of desktop notifications.
+const desktopSink = "desktop"
+
+// watchRules returns the selected rules and the filters they watch. A
+// --filter flag, or a configuration without rules, watches a single filter
+// and only sends events to the sinks of the flags.
+func (a *App) watchRules(filter string, only, always []string) ([]rules.Rule, []string, error) {
+	def := a.config.Watch.Filter
+	if def == "" {
+		def = watcher.DefaultJQL
+	}
+	if a.config.Watch.Desktop.Enabled {
+		always = append(always, desktopSink)
+	}
+	// The sinks of the flags take every event, whatever its source
+	var list []rules.Rule
+	if len(always) > 0 {
+		list = append(list, rules.Rule{Name: "--sink", Sinks: always})
+	}
+	if filter != "" || (len(a.config.Watch.Rules) == 0 && len(only) == 0) {
+		if filter == "" {
+			filter = def
+		}
+		return list, []string{filter}, nil
+	}
+
+	names := only
+	if len(names) == 0 {
+		names = slices.Sorted(maps.Keys(a.config.Watch.Rules))
+	}
+	var filters []string
+	for _, name := range names {
+		rule, ok := a.config.Watch.Rules[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown rule %q (configure it under watch.rules)", name)
+		}
+		r := rules.Rule{
+			Name:        name,
+			Filter:      rule.Filter,
+			When:        rule.When,
+			Sinks:       rule.Sinks,
+			Digest:      rule.Digest,
+			IgnoreQuiet: rule.IgnoreQuiet,
+		}
+		if r.Filter == "" {
+			r.Filter = def
+		}
+		for _, t := range rule.Events {
+			r.Events = append(r.Events, watcher.EventType(t))
+		}
+		list = append(list, r)
+		if !slices.Contains(filters, r.Filter) {
+			filters = append(filters, r.Filter)
+		}
+	}
+	return list, filters, nil
+}
+
+// ruleEngine creates the sinks and rule engine of the selected rules and
+// returns the filters to watch.
+func (a *App) ruleEngine(client jira.Client, filter string, only, always []string) (*rules.Engine, []string, error) {
+	list, filters, err := a.watchRules(filter, only, always)
+	if err != nil {
+		return nil, nil, err
+	}
+	var names []string
+	for _, r := range list {
+		names = append(names, r.Sinks...)
+	}
+	notifiers, err := a.notifiers(client, names)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := rules.Options{
+		Rules:     list,
+		Notifiers: notifiers,
+		StatePath: rules.DefaultStatePath(a.config.ActiveProfile),
+		Logger:    a.logger,
+	}
+	if q := a.config.Watch.Quiet; q.Hours != "" || q.Weekends {
+		// Only quiet hours need the user's timezone
+		opts.Quiet, err = rules.ParseQuiet(q.Hours, q.Weekends, a.timeLocation())
+	}
+	var engine *rules.Engine
+	if err == nil {
+		engine, err = rules.New(opts)
+	}
+	if err != nil {
+		for _, n := range notifiers {
+			n.Close()
+		}
+		return nil, nil, err
+	}
+	return engine, filters, nil
+}
+
+// notifiers creates the named sinks, each once.
+func (a *App) notifiers(client jira.Client, names []string) (map[string]notify.Notifier, error) {
+	notifiers := map[string]notify.Notifier{}
+	for _, name := range names {
+		if notifiers[name] != nil {
+			continue
+		}
+		n, err := a.notifier(client, name)
+		if err != nil {
+			for _, n := range notifiers {
+				n.Close()
+			}
+			return nil, err
+		}
+		notifiers[name] = n
+	}
+	return notifiers, nil
+}
+
+// notifier creates a sink from watch.sinks; "desktop" is the desktop
+// unless a sink of that name is configured.
+func (a *App) notifier(client jira.Client, name string) (notify.Notifier, error) {
+	sink, ok := a.config.Watch.Sinks[name]
+	if !ok {
+		if name == desktopSink {
+			return a.desktopNotifier(client)
+		}
+		return nil, fmt.Errorf("unknown sink %q (configure it under watch.sinks)", name)
+	}
+	return notify.NewSink(notify.SinkOptions{
+		Name:    name,
+		Type:    sink.Type,
+		URL:     sink.URL,
+		Secret:  sink.Secret,
+		Token:   sink.Token,
+		Title:   sink.Title,
+		Text:    sink.Text,
+		Headers: sink.Headers,
+		Domain:  a.config.Jira.Domain,
+		Outbox:  notify.DefaultOutboxDir(a.config.ActiveProfile, name),
+		Logger:  a.logger,
+	})
+}
+
+// desktopNotifier connects to the desktop notification service. Its Open
+// button opens the issue in the browser; Mark read dismisses it.
+func (a *App) desktopNotifier(client jira.Client) (*notify.Desktop, error) {
+	opts := notify.DesktopOptions{
+		OnAction: func(action notify.Action) {
+			switch action.Name {
+			case notify.ActionOpen:
+				if err := browser.Open(output.BrowseURL(a.config.Jira.Domain, action.Key)); err != nil {
+					a.logger.WithError(err).Warn("Failed to open browser")
+				}
+			case notify.ActionRead:
+				path := statusline.DefaultPath(a.config.ActiveProfile)
+				if err := statusline.Update(path, func(s *statusline.State) { s.MarkRead(action.Key) }); err != nil {
+					a.logger.WithError(err).Warn("Failed to mark comments read")
+				}
+			}
+		},
+	}
+	if a.config.Watch.Desktop.Icons {
+		opts.Icon = notify.NewIcons(client, a.config.Jira.Domain, notify.DefaultIconDir()).Path
+	}
+
+	d, err := notify.NewDesktop(opts)
+	if err != nil {
+		return nil, fmt.Errorf("desktop notifications: %w", err)
+	}
+	return d, nil
+}
+
+// parseInterval parses --interval as seconds or a Go duration, falling
+// back to def.
+func parseInterval(raw string, def time.Duration) (time.Duration, error) {
+	if raw == "" {
+		if def <= 0 {
+			return watcher.DefaultInterval, nil
+		}
+		return def, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if seconds, convErr := strconv.Atoi(raw); convErr == nil {
+		d, err = time.Duration(seconds)*time.Second, nil
+	}
+	if err != nil || d < time.Second {
+		return 0, fmt.Errorf("invalid interval %q (use seconds or a duration such as 2m)", raw)
+	}
+	return d, nil
+}
+
+// eventResult builds the output of a watch event. The table format prints
+// a line per event.
+func (a *App) eventResult(e watcher.Event, th *theme.Theme) output.Result {
+	text := e.Text()[len(e.Key):]
+	clock := e.Time.In(a.timeLocation()).Format("15:04")
+	return output.Result{
+		Data:    e,
+		Records: []any{e},
+		Table: output.Table{
+			Headers: []string{"Time", "Type", "Key", "Change"},
+			Rows:    [][]string{{e.Time.Format(time.RFC3339), string(e.Type), e.Key, text[1:]}},
+		},
+		Keys: []string{e.Key},
+		Human: func(w io.Writer) error {
+			key := th.Link(output.BrowseURL(a.config.Jira.Domain, e.Key), th.Paint("bold", e.Key))
+			_, err := fmt.Fprintf(w, "%s %-8s %s%s\n", th.Paint("8", clock), e.Type, key, text)
+			return err
+		},
+	}
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"jirar/internal/jira/watcher"
+	"jirar/internal/output"
+	"jirar/internal/webhook"
+)
+
+// buildWebhookCommand creates the webhook command.
+func (a *App) buildWebhookCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "webhook",
+		Short: "Receive Jira webhooks",
+	}
+	cmd.AddCommand(a.buildWebhookServeCommand())
+	return cmd
+}
+
+// buildWebhookServeCommand creates the webhook serve command.
+func (a *App) buildWebhookServeCommand() *cobra.Command {
+	var (
+		addr     string
+		secret   string
+		insecure bool
+		desktop  bool
+		sinks    []string
+		only     []string
+		opts     output.Options
+	)
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Receive Jira webhooks and send them to notification sinks",
+		Long: `Listen for Jira webhooks and Automation "Send web request" calls and turn
+them into watch events, which are printed and sent through watch.rules to
+the sinks like those of jirar watch, without polling.
+
+Jira webhooks report jira:issue_updated changes of status, assignee and
+priority, comment_created, and jira:issue_created for issues assigned to
+you. Automation requests name the event type in an "event" field of the
+body or in the query, e.g. /automation?event=status, and send the issue
+as "Issue data (Jira format)" or in an "issue" field.
+
+Requests must carry the secret (--secret or webhook.secret): as the
+signature Jira webhooks add when given a secret, or as a token in the
+X-Automation-Webhook-Token header, a bearer token or the secret query
+parameter. Rule filters do not apply to pushed events; the JQL of the Jira
+webhook selects the issues.`,
+		Example: `  jirar webhook serve --addr :8089 --desktop
+  jirar webhook serve --rule urgent
+  curl -H "X-Automation-Webhook-Token: $SECRET" -d @payload.json localhost:8089/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := a.printer(cmd, opts)
+			if err != nil {
+				return err
+			}
+			if addr == "" {
+				addr = a.config.Webhook.Addr
+			}
+			if secret == "" {
+				secret = a.config.Webhook.Secret
+			}
+			if secret == "" && !insecure {
+				return fmt.Errorf("a secret is required to verify webhooks (set webhook.secret or pass --insecure)")
+			}
+			if desktop {
+				sinks = append(sinks, desktopSink)
+			}
+
+			client, err := a.directClient()
+			if err != nil {
+				return err
+			}
+			user, err := client.GetCurrentUser(a.ctx)
+			if err != nil {
+				return fmt.Errorf("look up current user: %w", err)
+			}
+			engine, _, err := a.ruleEngine(client, "", only, sinks)
+			if err != nil {
+				return err
+			}
+			defer engine.Close()
+
+			ctx, stop := signal.NotifyContext(a.ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			// Events are delivered apart from the requests, so Jira gets
+			// its answer without waiting for slow sinks
+			out := cmd.OutOrStdout()
+			th := a.theme(out)
+			events := make(chan watcher.Event, 256)
+			go func() {
+				for e := range events {
+					if err := printer.Render(out, a.eventResult(e, th)); err != nil {
+						a.logger.WithError(err).Error("Failed to print event")
+					}
+					a.recordActivity(e)
+					engine.Handle(ctx, "", e)
+				}
+			}()
+			handler := webhook.New(webhook.Options{
+				Secret:    secret,
+				AccountID: user.AccountID,
+				Logger:    a.logger,
+				Handle:    func(e watcher.Event) { events <- e },
+			})
+
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				return fmt.Errorf("listen on %s: %w", addr, err)
+			}
+			server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+			go engine.Run(ctx, time.Minute)
+			go func() {
+				<-ctx.Done()
+				shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				server.Shutdown(shutdown)
+			}()
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "Listening for webhooks on %s. Press Ctrl+C to stop.\n", listener.Addr())
+			if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("serve webhooks: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", "", "Address to listen on (default: webhook.addr, :8089)")
+	cmd.Flags().StringVar(&secret, "secret", "", "Secret requests must carry (default: webhook.secret)")
+	cmd.Flags().BoolVar(&insecure, "insecure", false, "Accept requests without a secret")
+	cmd.Flags().BoolVar(&desktop, "desktop", false, "Show desktop notifications (default: watch.desktop.enabled)")
+	cmd.Flags().StringSliceVar(&sinks, "sink", nil, "Also send every event to these sinks from watch.sinks")
+	cmd.Flags().StringSliceVar(&only, "rule", nil, "Only run these rules from watch.rules")
+	addOutputFlags(cmd, &opts)
+
+	return cmd
+}
@@ -0,0 +1,58 @@
+// Package clipboard copies text to the system clipboard.
+package clipboard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// tools are the clipboard commands tried in order, with their arguments.
+var tools = [][]string{
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+}
+
+// Copy puts text on the clipboard using the platform's clipboard command.
+// When none is available, e.g. over SSH, it writes an OSC 52 sequence to
+// term so the terminal sets its clipboard instead.
+func Copy(term io.Writer, text string) error {
+	if args := command(); args != nil {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err == nil {
+			return nil
+		}
+	}
+
+	if term == nil {
+		return fmt.Errorf("no clipboard command found")
+	}
+	_, err := fmt.Fprintf(term, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
+
+// command returns the clipboard command to use, or nil.
+func command() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"pbcopy"}
+	case "windows":
+		return []string{"clip"}
+	}
+
+	for _, args := range tools {
+		if args[0] == "wl-copy" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			continue
+		}
+		if _, err := exec.LookPath(args[0]); err == nil {
+			return args
+		}
+	}
+	return nil
+}
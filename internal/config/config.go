@@ -2,24 +2,222 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+
+	"jirar/internal/secret"
 )
 
+// ErrUnknownProfile is returned when a selected profile is not configured.
+var ErrUnknownProfile = errors.New("unknown profile")
+
 // Config represents the application configuration.
 type Config struct {
-	Jira     JiraConfig `mapstructure:"jira"`
-	UI       UIConfig   `mapstructure:"ui"`
-	Debug    bool       `mapstructure:"debug"`
-	LogLevel string     `mapstructure:"log_level"`
+	Jira     JiraConfig     `mapstructure:"jira"`
+	Defaults DefaultsConfig `mapstructure:"defaults"`
+	Git      GitConfig      `mapstructure:"git"`
+	UI       UIConfig       `mapstructure:"ui"`
+	Watch    WatchConfig    `mapstructure:"watch"`
+	Webhook  WebhookConfig  `mapstructure:"webhook"`
+	Daemon   DaemonConfig   `mapstructure:"daemon"`
+	Store    StoreConfig    `mapstructure:"store"`
+	Cache    CacheConfig    `mapstructure:"cache"`
+	Debug    bool           `mapstructure:"debug"`
+	LogLevel string         `mapstructure:"log_level"`
+
+	// Views are saved JQL queries by name, e.g. mine: assignee =
+	// currentUser() AND resolution = Unresolved.
+	Views map[string]string `mapstructure:"views"`
+
+	// Profiles holds named Jira instances selectable with --profile.
+	Profiles map[string]Profile `mapstructure:"profiles"`
+	// CurrentProfile is the profile used when --profile is not given.
+	CurrentProfile string `mapstructure:"current_profile"`
+
+	// ActiveProfile is the profile applied by Activate, if any.
+	ActiveProfile string `mapstructure:"-"`
+
+	// layers are the configuration files read, lowest precedence first.
+	layers []Layer
+}
+
+// envBindings maps configuration keys to their unprefixed environment
+// variables. Every key can also be set as JIRAR_<KEY> with dots as underscores.
+var envBindings = map[string]string{
+	"jira.domain":            "JIRA_DOMAIN",
+	"jira.email":             "JIRA_EMAIL",
+	"jira.token":             "JIRA_TOKEN",
+	"jira.token_ref":         "JIRA_TOKEN_REF",
+	"jira.credential_helper": "JIRA_CREDENTIAL_HELPER",
 }
 
 // JiraConfig holds Jira-specific configuration.
 type JiraConfig struct {
 	Domain string `mapstructure:"domain"`
 	Email  string `mapstructure:"email"`
-	Token  string `mapstructure:"token"`
+	Token  string `mapstructure:"token" secret:"true"`
+
+	// TokenRef references the token in a secret backend instead of storing
+	// it inline, e.g. "keyring:jirar" or "age:~/.config/jirar/token.age".
+	TokenRef string `mapstructure:"token_ref"`
+	// CredentialHelper is a git-credential style command that prints the token.
+	CredentialHelper string `mapstructure:"credential_helper"`
+	// AgeIdentity is the identity file used to decrypt age token files.
+	AgeIdentity string `mapstructure:"age_identity"`
+}
+
+// DefaultsConfig holds defaults applied to commands when flags are omitted.
+type DefaultsConfig struct {
+	Project string `mapstructure:"project"`
+	Board   string `mapstructure:"board"`
+}
+
+// GitConfig holds repository conventions that tie branches and commits to
+// issues, typically set in a repository's .jirar.yaml.
+type GitConfig struct {
+	// BranchTemplate is a Go template for branch names, e.g.
+	// "{{.Key}}-{{.Summary}}"; the summary is slugified.
+	BranchTemplate string `mapstructure:"branch_template"`
+	// CommitPattern is a regular expression commit subjects must match,
+	// e.g. "^[A-Z]+-[0-9]+ ".
+	CommitPattern string `mapstructure:"commit_pattern"`
+	// RequireIssueKey rejects commits whose subject has no issue key.
+	RequireIssueKey bool `mapstructure:"require_issue_key"`
+}
+
+// WatchConfig holds the defaults of jirar watch.
+type WatchConfig struct {
+	// Filter is the JQL of the watched issues; empty watches the issues
+	// assigned to or watched by the user.
+	Filter string `mapstructure:"filter"`
+	// Interval is the time between polls, e.g. 30s.
+	Interval time.Duration `mapstructure:"interval"`
+	// Desktop configures desktop notifications of watch events.
+	Desktop DesktopConfig `mapstructure:"desktop"`
+	// Sinks are named channels events are delivered to, e.g. a Slack
+	// incoming webhook.
+	Sinks map[string]SinkConfig `mapstructure:"sinks"`
+	// Rules route the events of a filter to sinks by name. Without rules,
+	// jirar watch only prints events.
+	Rules map[string]RuleConfig `mapstructure:"rules"`
+	// Quiet holds notifications back at night and on weekends.
+	Quiet QuietConfig `mapstructure:"quiet"`
+}
+
+// QuietConfig sets the quiet hours of notifications, in the timezone of
+// ui.timezone or the Jira account. Events are held and sent as a digest
+// when the quiet hours end.
+type QuietConfig struct {
+	// Hours is a daily range such as 22:00-08:00.
+	Hours string `mapstructure:"hours"`
+	// Weekends keeps Saturdays and Sundays quiet.
+	Weekends bool `mapstructure:"weekends"`
+}
+
+// SinkConfig configures a notification sink.
+type SinkConfig struct {
+	// Type is slack, teams, discord, ntfy or webhook.
+	Type string `mapstructure:"type"`
+	// URL is the incoming webhook, ntfy topic or endpoint to post to.
+	URL string `mapstructure:"url" secret:"true"`
+	// Secret signs webhook payloads with HMAC-SHA256.
+	Secret string `mapstructure:"secret" secret:"true"`
+	// Token is sent as a bearer token, e.g. an ntfy access token.
+	Token string `mapstructure:"token" secret:"true"`
+	// Title and Text are Go templates over the event; empty uses the
+	// built-in ones.
+	Title string `mapstructure:"title"`
+	Text  string `mapstructure:"text"`
+	// Headers are added to every request.
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+// RuleConfig sends the events of a filter to sinks.
+type RuleConfig struct {
+	// Filter is the JQL of the watched issues; empty uses watch.filter.
+	Filter string `mapstructure:"filter"`
+	// Events are the event types to send; empty sends all.
+	Events []string `mapstructure:"events"`
+	// When is an expression over event and issue that events must match,
+	// e.g. issue.fields.priority.name in ["Highest", "High"].
+	When string `mapstructure:"when"`
+	// Sinks name the sinks to send to; "desktop" is the desktop.
+	Sinks []string `mapstructure:"sinks"`
+	// Digest batches events into one message per period, e.g. 1h.
+	Digest time.Duration `mapstructure:"digest"`
+	// IgnoreQuiet sends events during quiet hours.
+	IgnoreQuiet bool `mapstructure:"ignore_quiet"`
+}
+
+// WebhookConfig holds the defaults of jirar webhook serve.
+type WebhookConfig struct {
+	// Addr is the address to listen on, e.g. :8089.
+	Addr string `mapstructure:"addr"`
+	// Secret verifies requests: the secret of a Jira webhook or a token
+	// sent by Automation.
+	Secret string `mapstructure:"secret" secret:"true"`
+}
+
+// DaemonConfig holds the settings of jirar daemon.
+type DaemonConfig struct {
+	// Socket is the path of the daemon's Unix socket; empty is
+	// $XDG_RUNTIME_DIR/jirar.sock.
+	Socket string `mapstructure:"socket"`
+	// Refresh is how often the daemon refetches the issues it serves.
+	Refresh time.Duration `mapstructure:"refresh"`
+}
+
+// StoreConfig holds the settings of the local store kept by jirar sync.
+type StoreConfig struct {
+	// Scopes are the JQL queries whose issues are synced.
+	Scopes []string `mapstructure:"scopes"`
+	// Reconcile is how often a sync lists scopes in full to drop deleted
+	// issues, and refetches projects, statuses and fields.
+	Reconcile time.Duration `mapstructure:"reconcile"`
+	// StaleAfter is the age after which --cached and --offline suggest
+	// running jirar sync.
+	StaleAfter time.Duration `mapstructure:"stale_after"`
+}
+
+// CacheConfig holds the settings of the HTTP cache of Jira metadata.
+type CacheConfig struct {
+	// Enabled caches metadata responses on disk.
+	Enabled bool `mapstructure:"enabled"`
+	// TTL is how long the responses of each endpoint class are used
+	// before asking Jira again; 0 does not cache the class.
+	TTL CacheTTLConfig `mapstructure:"ttl"`
+}
+
+// CacheTTLConfig holds the TTLs of the endpoint classes cached.
+type CacheTTLConfig struct {
+	Fields     time.Duration `mapstructure:"fields"`
+	Statuses   time.Duration `mapstructure:"statuses"`
+	IssueTypes time.Duration `mapstructure:"issuetypes"`
+	Projects   time.Duration `mapstructure:"projects"`
+	CreateMeta time.Duration `mapstructure:"createmeta"`
+}
+
+// DesktopConfig configures desktop notifications over D-Bus.
+type DesktopConfig struct {
+	// Enabled shows watch events as desktop notifications, like --desktop.
+	Enabled bool `mapstructure:"enabled"`
+	// Icons shows the issue type icon, downloaded from Jira once.
+	Icons bool `mapstructure:"icons"`
+}
+
+// Profile describes a named Jira instance and its command defaults.
+type Profile struct {
+	JiraConfig     `mapstructure:",squash"`
+	DefaultsConfig `mapstructure:",squash"`
 }
 
 // UIConfig holds UI-specific configuration.
@@ -27,9 +225,52 @@ type UIConfig struct {
 	Colors  bool `mapstructure:"colors"`
 	Icons   bool `mapstructure:"icons"`
 	Compact bool `mapstructure:"compact"`
+	// Output is the default output format, e.g. "table" or "json".
+	Output string `mapstructure:"output"`
+	// Hyperlinks controls OSC 8 links on issue keys: auto, always or never.
+	Hyperlinks string `mapstructure:"hyperlinks"`
+	// TimeFormat is relative, absolute, iso or a Go time layout.
+	TimeFormat string `mapstructure:"time_format"`
+	// TimeZone is the IANA zone for absolute times; empty uses the Jira
+	// account's timezone.
+	TimeZone string `mapstructure:"timezone"`
+	// Columns configures issue table columns.
+	Columns ColumnsConfig `mapstructure:"columns"`
+	// Theme overrides the default status and priority styles.
+	Theme ThemeConfig `mapstructure:"theme"`
+	// StatusLine is the template of jirar status-line, e.g.
+	// "{{.Assigned}} 💬{{.Unread}}".
+	StatusLine string `mapstructure:"status_line"`
+}
+
+// ThemeConfig overrides icons and colors. Statuses are styled by their
+// category (new, indeterminate, done) unless a style for the status name
+// exists; names match case-insensitively.
+type ThemeConfig struct {
+	Categories map[string]StyleConfig `mapstructure:"categories"`
+	Statuses   map[string]StyleConfig `mapstructure:"statuses"`
+	Priorities map[string]StyleConfig `mapstructure:"priorities"`
+}
+
+// StyleConfig is the icon and color of a status or priority, e.g.
+// color: "bold red" or a 256-color number.
+type StyleConfig struct {
+	Icon  string `mapstructure:"icon"`
+	Color string `mapstructure:"color"`
+}
+
+// ColumnsConfig holds column aliases and named column lists for --columns.
+type ColumnsConfig struct {
+	// Aliases name Jira fields, e.g. sp: customfield_10016.
+	Aliases map[string]string `mapstructure:"aliases"`
+	// Presets holds named column lists per command, e.g.
+	// presets.list.triage: key,priority,assignee,summary. A preset named
+	// "default" replaces the built-in columns of that command.
+	Presets map[string]map[string]string `mapstructure:"presets"`
 }
 
-// New creates a new configuration instance with defaults.
+// New creates a new configuration instance with defaults. Configuration
+// files are merged in layers; see Load.
 func New() (*Config, error) {
 	cfg := &Config{}
 
@@ -39,64 +280,141 @@ func New() (*Config, error) {
 	// Configure Viper
 	viper.AutomaticEnv()
 	viper.SetEnvPrefix("JIRAR")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
-	// Environment variable mappings
-	viper.BindEnv("jira.domain", "JIRA_DOMAIN")
-	viper.BindEnv("jira.email", "JIRA_EMAIL")
-	viper.BindEnv("jira.token", "JIRA_TOKEN")
-
-	// Load configuration file
-	if err := loadConfigFile(); err != nil {
-		// Config file is optional, but log warning if not found
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			fmt.Printf("Warning: %v\n", err)
+	// Environment variable mappings. Binding every key lets JIRAR_<KEY>
+	// reach keys that have no default.
+	for _, field := range Fields() {
+		if !strings.Contains(field.Key, "*") && field.Type.Kind() != reflect.Map {
+			viper.BindEnv(field.Key)
 		}
 	}
-
-	// Unmarshal into struct
-	if err := viper.Unmarshal(cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	for key, env := range envBindings {
+		viper.BindEnv(key, env)
 	}
 
-	// Validate configuration
-	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	if err := cfg.Load(os.Getenv("JIRA_CONFIG_PATH")); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
 }
 
-// setDefaults sets default values for configuration.
-func setDefaults() {
-	viper.SetDefault("debug", false)
-	viper.SetDefault("log_level", "info")
-	viper.SetDefault("ui.colors", true)
-	viper.SetDefault("ui.icons", true)
-	viper.SetDefault("ui.compact", false)
+// Activate applies the named profile on top of the base configuration and
+// resolves the Jira token. An empty name falls back to JIRAR_PROFILE and then
+// to current_profile; with none set the top-level jira section is used.
+func (c *Config) Activate(ctx context.Context, name string) error {
+	if err := c.SelectProfile(name); err != nil {
+		return err
+	}
+
+	// A missing secret is not fatal so that commands storing the token can
+	// still run; Validate reports it once a command needs Jira.
+	if err := c.Jira.ResolveToken(ctx); err != nil && !errors.Is(err, secret.ErrNotFound) {
+		return fmt.Errorf("failed to resolve jira token: %w", err)
+	}
+	return nil
 }
 
-// loadConfigFile loads configuration from various locations.
-func loadConfigFile() error {
-	// Try to find config file in standard locations
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
+// SelectProfile applies the named profile like Activate without resolving
+// the token, for commands that only read local files.
+func (c *Config) SelectProfile(name string) error {
+	if name == "" {
+		name = viper.GetString("profile")
+	}
+	if name == "" {
+		name = c.CurrentProfile
+	}
 
-	// Search paths in order of preference
-	configPaths := []string{
-		".",
-		"$HOME/.jirar",
-		"$HOME/.config/jirar",
-		"/etc/jirar",
+	if name != "" {
+		profile, ok := c.Profiles[name]
+		if !ok {
+			return fmt.Errorf("%w %q", ErrUnknownProfile, name)
+		}
+		profile = c.unshadowed(name, profile)
+		token := c.Jira.Token
+		c.Jira.merge(profile.JiraConfig)
+		// JIRA_TOKEN is given for this run, so it still takes precedence
+		// over the profile's reference on another instance
+		if c.Jira.Token == "" && tokenFromEnv() {
+			c.Jira.Token = token
+		}
+		c.Defaults.merge(profile.DefaultsConfig)
+		c.ActiveProfile = name
 	}
 
-	for _, path := range configPaths {
-		viper.AddConfigPath(path)
+	c.Jira.Domain = NormalizeDomain(c.Jira.Domain)
+	return nil
+}
+
+// unshadowed clears the profile fields that a configuration layer above the
+// profile's own layer sets, so a repository .jirar.yaml can pin the default
+// project even when a user profile sets one.
+func (c *Config) unshadowed(name string, p Profile) Profile {
+	files := c.loadFiles()
+	rank := highestLayer(files, "profiles."+name)
+	fields := map[string]*string{
+		"domain":            &p.Domain,
+		"email":             &p.Email,
+		"token":             &p.Token,
+		"token_ref":         &p.TokenRef,
+		"credential_helper": &p.CredentialHelper,
+		"project":           &p.Project,
+		"board":             &p.Board,
 	}
+	for field, value := range fields {
+		section := "jira."
+		if field == "project" || field == "board" {
+			section = "defaults."
+		}
+		if highestLayer(files, section+field) > rank {
+			*value = ""
+		}
+	}
+	return p
+}
 
-	return viper.ReadInConfig()
+// ProfileNames returns the configured profile names in sorted order.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
-// Validate validates the configuration.
+// setDefaults sets default values for configuration.
+func setDefaults() {
+	viper.SetDefault("debug", false)
+	viper.SetDefault("log_level", "info")
+	viper.SetDefault("ui.colors", true)
+	viper.SetDefault("ui.icons", true)
+	viper.SetDefault("ui.compact", false)
+	viper.SetDefault("ui.output", "table")
+	viper.SetDefault("ui.hyperlinks", "auto")
+	viper.SetDefault("ui.time_format", "relative")
+	viper.SetDefault("jira.age_identity", "~/.config/jirar/age.key")
+	viper.SetDefault("git.branch_template", "{{.Key}}-{{.Summary}}")
+	viper.SetDefault("git.require_issue_key", false)
+	viper.SetDefault("watch.interval", "30s")
+	viper.SetDefault("watch.desktop.enabled", false)
+	viper.SetDefault("watch.desktop.icons", true)
+	viper.SetDefault("watch.quiet.weekends", false)
+	viper.SetDefault("webhook.addr", ":8089")
+	viper.SetDefault("daemon.refresh", "1m")
+	viper.SetDefault("store.scopes", []string{"assignee = currentUser() OR reporter = currentUser() OR watcher = currentUser()"})
+	viper.SetDefault("store.reconcile", "24h")
+	viper.SetDefault("store.stale_after", "1h")
+	viper.SetDefault("cache.enabled", true)
+	viper.SetDefault("cache.ttl.fields", "24h")
+	viper.SetDefault("cache.ttl.statuses", "24h")
+	viper.SetDefault("cache.ttl.issuetypes", "24h")
+	viper.SetDefault("cache.ttl.projects", "1h")
+	viper.SetDefault("cache.ttl.createmeta", "1h")
+}
+
+// Validate validates the configuration. Commands call it before talking to Jira.
 func (c *Config) Validate() error {
 	if c.Jira.Domain == "" {
 		return fmt.Errorf("jira domain is required")
@@ -110,6 +428,102 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// tokenFromEnv reports whether the Jira token is set in the environment.
+func tokenFromEnv() bool {
+	for _, env := range EnvVars("jira.token") {
+		if os.Getenv(env) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// merge overlays the non-empty fields of other onto j.
+func (j *JiraConfig) merge(other JiraConfig) {
+	// Credentials of files never carry over to a different instance;
+	// SelectProfile keeps a token from the environment
+	if other.Domain != "" && other.Domain != j.Domain {
+		j.Token, j.TokenRef, j.CredentialHelper = "", "", ""
+	}
+
+	overlay(&j.Domain, other.Domain)
+	overlay(&j.Email, other.Email)
+	overlay(&j.Token, other.Token)
+	overlay(&j.TokenRef, other.TokenRef)
+	overlay(&j.CredentialHelper, other.CredentialHelper)
+	overlay(&j.AgeIdentity, other.AgeIdentity)
+}
+
+// merge overlays the non-empty fields of other onto d.
+func (d *DefaultsConfig) merge(other DefaultsConfig) {
+	overlay(&d.Project, other.Project)
+	overlay(&d.Board, other.Board)
+}
+
+// overlay replaces dst with src when src is set.
+func overlay(dst *string, src string) {
+	if src != "" {
+		*dst = src
+	}
+}
+
+// SecretStore returns the backend holding the Jira token, or nil when the
+// token is only configured inline.
+func (j *JiraConfig) SecretStore() (secret.Store, error) {
+	ref := j.TokenRef
+	if ref == "" && j.CredentialHelper != "" {
+		ref = "helper:" + j.CredentialHelper
+	}
+	if ref == "" {
+		return nil, nil
+	}
+
+	return secret.Open(ref, secret.Options{
+		Account:     j.Email,
+		Host:        j.Host(),
+		AgeIdentity: j.AgeIdentity,
+	})
+}
+
+// ResolveToken fills Token from the configured secret backend. An inline
+// token, e.g. from JIRA_TOKEN, takes precedence over the backend.
+func (j *JiraConfig) ResolveToken(ctx context.Context) error {
+	if j.Token != "" {
+		return nil
+	}
+
+	store, err := j.SecretStore()
+	if err != nil || store == nil {
+		return err
+	}
+
+	token, err := store.Get(ctx)
+	if err != nil {
+		return err
+	}
+	j.Token = token
+	return nil
+}
+
+// NormalizeDomain turns user input such as "company.atlassian.net" into a
+// base URL with a scheme and no trailing slash.
+func NormalizeDomain(domain string) string {
+	domain = strings.TrimRight(strings.TrimSpace(domain), "/")
+	if domain != "" && !strings.Contains(domain, "://") {
+		domain = "https://" + domain
+	}
+	return domain
+}
+
+// Host returns the host part of the configured Jira domain.
+func (j *JiraConfig) Host() string {
+	u, err := url.Parse(j.Domain)
+	if err != nil || u.Host == "" {
+		return j.Domain
+	}
+	return u.Host
+}
+
 // IsDebug returns true if debug mode is enabled.
 func (c *Config) IsDebug() bool {
 	return c.Debug
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// File is a YAML configuration file edited in place. Edits go through the
+// YAML node tree so comments and key order survive a round trip.
+type File struct {
+	Path string
+	doc  *yaml.Node
+}
+
+// DefaultPath returns the user configuration file written by jirar.
+func DefaultPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(".jirar", "config.yaml")
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "jirar", "config.yaml")
+}
+
+// LoadFile reads the YAML file at path. A missing file yields an empty
+// document that is created on Save.
+func LoadFile(path string) (*File, error) {
+	f := &File{Path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file %s: top level must be a mapping", path)
+	}
+
+	f.doc = &doc
+	return f, nil
+}
+
+// Has reports whether the dotted key is present in the file.
+func (f *File) Has(key string) bool {
+	_, ok := f.lookup(key)
+	return ok
+}
+
+// Get decodes the value at the dotted key into out.
+func (f *File) Get(key string, out any) (bool, error) {
+	node, ok := f.lookup(key)
+	if !ok {
+		return false, nil
+	}
+	if err := node.Decode(out); err != nil {
+		return true, fmt.Errorf("decode %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// Set stores value at the dotted key, creating intermediate mappings.
+// Comments attached to an existing value are kept.
+func (f *File) Set(key string, value any) error {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return fmt.Errorf("encode %s: %w", key, err)
+	}
+	blockStyle(&node)
+
+	parts := strings.Split(key, ".")
+	m := f.doc.Content[0]
+	for i, part := range parts {
+		idx := mappingIndex(m, part)
+		last := i == len(parts)-1
+
+		if idx < 0 {
+			next := &node
+			if !last {
+				next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			}
+			m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part}, next)
+			m = next
+			continue
+		}
+
+		current := m.Content[idx+1]
+		if last {
+			node.HeadComment = current.HeadComment
+			node.LineComment = current.LineComment
+			node.FootComment = current.FootComment
+			m.Content[idx+1] = &node
+			return nil
+		}
+		if current.Kind != yaml.MappingNode {
+			return fmt.Errorf("cannot set %s: %s is not a mapping", key, strings.Join(parts[:i+1], "."))
+		}
+		m = current
+	}
+	return nil
+}
+
+// Unset removes the dotted key and reports whether it was present.
+func (f *File) Unset(key string) bool {
+	parts := strings.Split(key, ".")
+	parent := f.doc.Content[0]
+	if len(parts) > 1 {
+		node, ok := f.lookup(strings.Join(parts[:len(parts)-1], "."))
+		if !ok || node.Kind != yaml.MappingNode {
+			return false
+		}
+		parent = node
+	}
+
+	idx := mappingIndex(parent, parts[len(parts)-1])
+	if idx < 0 {
+		return false
+	}
+	parent.Content = append(parent.Content[:idx], parent.Content[idx+2:]...)
+	return true
+}
+
+// Restrict removes the top-level keys not in allowed and returns those
+// removed, in file order.
+func (f *File) Restrict(allowed []string) []string {
+	m := f.doc.Content[0]
+	var removed []string
+	kept := m.Content[:0]
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if slices.Contains(allowed, m.Content[i].Value) {
+			kept = append(kept, m.Content[i], m.Content[i+1])
+		} else {
+			removed = append(removed, m.Content[i].Value)
+		}
+	}
+	m.Content = kept
+	return removed
+}
+
+// Bytes encodes the file as YAML.
+func (f *File) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(f.doc); err != nil {
+		return nil, fmt.Errorf("encode config file: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encode config file: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Save writes the file readable only by the current user.
+func (f *File) Save() error {
+	data, err := f.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o700); err != nil {
+		return fmt.Errorf("create config directory: %w", err)
+	}
+	// A new 0600 file replaces the old one, which may be readable by others
+	// and is about to hold the token; a symlinked config keeps its link
+	path := f.Path
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*.yaml")
+	if err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+	return nil
+}
+
+// lookup finds the value node for a dotted key.
+func (f *File) lookup(key string) (*yaml.Node, bool) {
+	node := f.doc.Content[0]
+	for _, part := range strings.Split(key, ".") {
+		if node.Kind != yaml.MappingNode {
+			return nil, false
+		}
+		idx := mappingIndex(node, part)
+		if idx < 0 {
+			return nil, false
+		}
+		node = node.Content[idx+1]
+	}
+	return node, true
+}
+
+// mappingIndex returns the index of key within a mapping node's content.
+func mappingIndex(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// blockStyle switches collections to block style so later additions do not
+// end up in an inline {} or [] literal.
+func blockStyle(n *yaml.Node) {
+	if n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode {
+		n.Style &^= yaml.FlowStyle
+	}
+	for _, child := range n.Content {
+		blockStyle(child)
+	}
+}
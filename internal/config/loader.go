@@ -0,0 +1,198 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Layer scopes, lowest precedence first.
+const (
+	ScopeSystem   = "system"
+	ScopeUser     = "user"
+	ScopeRepo     = "repo"
+	ScopeWorkdir  = "workdir"
+	ScopeExplicit = "explicit"
+)
+
+// RepoFileName is the repository-local configuration file at the git root.
+const RepoFileName = ".jirar.yaml"
+
+// SharedSections are the top-level keys the repo and workdir layers may
+// set. Those files come with whatever is checked out, so they cannot name
+// the Jira host, the token or commands run for it, profiles, or where
+// events are sent.
+var SharedSections = []string{"defaults", "git", "views", "ui"}
+
+// Layer is a configuration file merged into the effective configuration.
+type Layer struct {
+	Scope string
+	Path  string
+	// Ignored lists the top-level keys of a shared layer outside
+	// SharedSections, which are not read
+	Ignored []string
+}
+
+// Shared reports whether key may be set in the repo and workdir layers.
+func Shared(key string) bool {
+	section, _, _ := strings.Cut(key, ".")
+	return slices.Contains(SharedSections, section)
+}
+
+// shared reports whether the layer is limited to SharedSections.
+func (l Layer) shared() bool {
+	return l.Scope == ScopeRepo || l.Scope == ScopeWorkdir
+}
+
+// Load merges the configuration layers into c: the system file, the user
+// file, the repository .jirar.yaml, config.yaml in the working directory
+// and finally the explicit file, if any.
+// Later layers override earlier ones key by key; the repo and workdir
+// layers only set SharedSections.
+func (c *Config) Load(explicit string) error {
+	layers, err := discoverLayers(explicit)
+	if err != nil {
+		return err
+	}
+
+	for i, layer := range layers {
+		if layer.shared() {
+			f, err := LoadFile(layer.Path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s config %s: %w", layer.Scope, layer.Path, err)
+			}
+			layers[i].Ignored = f.Restrict(SharedSections)
+			data, err := f.Bytes()
+			if err != nil {
+				return err
+			}
+			viper.SetConfigType("yaml")
+			if err := viper.MergeConfig(bytes.NewReader(data)); err != nil {
+				return fmt.Errorf("failed to read %s config %s: %w", layer.Scope, layer.Path, err)
+			}
+			continue
+		}
+		viper.SetConfigFile(layer.Path)
+		viper.SetConfigType("yaml")
+		if err := viper.MergeInConfig(); err != nil {
+			return fmt.Errorf("failed to read %s config %s: %w", layer.Scope, layer.Path, err)
+		}
+	}
+
+	// Unmarshal into struct
+	fresh := Config{layers: layers}
+	if err := viper.Unmarshal(&fresh); err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	*c = fresh
+
+	return nil
+}
+
+// Layers returns the configuration files read, lowest precedence first.
+func (c *Config) Layers() []Layer {
+	return c.layers
+}
+
+// WritePath returns the file that commands writing configuration use: the
+// explicit file if given, else the user file in use, else DefaultPath.
+func (c *Config) WritePath() string {
+	path := DefaultPath()
+	for _, layer := range c.layers {
+		switch layer.Scope {
+		case ScopeExplicit:
+			return layer.Path
+		case ScopeUser:
+			path = layer.Path
+		}
+	}
+	return path
+}
+
+// LayerPath returns the file for the given scope, whether or not it exists.
+// The repo scope has no path outside a git repository.
+func LayerPath(scope string) string {
+	switch scope {
+	case ScopeSystem:
+		return filepath.Join("/etc", "jirar", "config.yaml")
+	case ScopeUser:
+		return DefaultPath()
+	case ScopeRepo:
+		if root := RepoRoot(); root != "" {
+			return filepath.Join(root, RepoFileName)
+		}
+	case ScopeWorkdir:
+		// Read before layers existed, when it took precedence over the
+		// user file
+		if path, err := filepath.Abs("config.yaml"); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// RepoRoot returns the root of the git repository containing the working
+// directory, or "" outside a repository.
+func RepoRoot() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// discoverLayers lists the configuration files that exist, lowest
+// precedence first.
+func discoverLayers(explicit string) ([]Layer, error) {
+	var layers []Layer
+
+	candidates := []Layer{{Scope: ScopeSystem, Path: LayerPath(ScopeSystem)}}
+
+	// The legacy ~/.jirar location is read before the XDG one
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, Layer{Scope: ScopeUser, Path: filepath.Join(home, ".jirar", "config.yaml")})
+	}
+	candidates = append(candidates,
+		Layer{Scope: ScopeUser, Path: LayerPath(ScopeUser)},
+		Layer{Scope: ScopeRepo, Path: LayerPath(ScopeRepo)},
+		Layer{Scope: ScopeWorkdir, Path: LayerPath(ScopeWorkdir)},
+	)
+
+	for _, layer := range candidates {
+		// In ~/.config/jirar, ./config.yaml is the user file; read it once
+		if layer.Path == "" || slices.ContainsFunc(layers, func(l Layer) bool { return l.Path == layer.Path }) {
+			continue
+		}
+		if _, err := os.Stat(layer.Path); err == nil {
+			layers = append(layers, layer)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to access %s config %s: %w", layer.Scope, layer.Path, err)
+		}
+	}
+
+	if explicit != "" {
+		if _, err := os.Stat(explicit); err != nil {
+			return nil, fmt.Errorf("config file %s: %w", explicit, err)
+		}
+		layers = append(layers, Layer{Scope: ScopeExplicit, Path: explicit})
+	}
+
+	return layers, nil
+}
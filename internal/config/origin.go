@@ -0,0 +1,109 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// Origin sources, from highest to lowest precedence.
+const (
+	SourceFlag    = "flag"
+	SourceProfile = "profile"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+	SourceDefault = "default"
+)
+
+// Origin describes where an effective configuration value came from.
+type Origin struct {
+	Source string
+	// Detail names the flag, environment variable, profile or layer scope
+	Detail string
+	// Path is the file holding the value, if any
+	Path string
+	// Key is the key within Path, which differs from the requested key
+	// when the value comes from a profile
+	Key string
+}
+
+// String renders the origin for display.
+func (o Origin) String() string {
+	switch o.Source {
+	case SourceProfile:
+		return "profile " + o.Detail + " (" + o.Path + ")"
+	case SourceFile:
+		return o.Detail + " file " + o.Path
+	case SourceDefault:
+		return SourceDefault
+	default:
+		return o.Source + " " + o.Detail
+	}
+}
+
+// Origin reports where the effective value of key came from. Command-line
+// flags are not known to the config package; callers check them first.
+func (c *Config) Origin(key string) Origin {
+	files := c.loadFiles()
+
+	if c.ActiveProfile != "" {
+		for _, section := range []string{"jira.", "defaults."} {
+			field, ok := strings.CutPrefix(key, section)
+			if !ok {
+				continue
+			}
+			profileKey := "profiles." + c.ActiveProfile + "." + field
+			if i := highestLayer(files, profileKey); i >= 0 && i >= highestLayer(files, key) {
+				return Origin{Source: SourceProfile, Detail: c.ActiveProfile, Path: files[i].Path, Key: profileKey}
+			}
+		}
+	}
+
+	for _, env := range EnvVars(key) {
+		if _, ok := os.LookupEnv(env); ok {
+			return Origin{Source: SourceEnv, Detail: env}
+		}
+	}
+
+	if i := highestLayer(files, key); i >= 0 {
+		return Origin{Source: SourceFile, Detail: c.layers[i].Scope, Path: files[i].Path, Key: key}
+	}
+
+	return Origin{Source: SourceDefault}
+}
+
+// EnvVars lists the environment variables that can set key.
+func EnvVars(key string) []string {
+	vars := []string{"JIRAR_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))}
+	if env, ok := envBindings[key]; ok {
+		vars = append([]string{env}, vars...)
+	}
+	return vars
+}
+
+// loadFiles parses every layer. Unreadable files are kept as empty
+// documents so indexes line up with c.layers.
+func (c *Config) loadFiles() []*File {
+	files := make([]*File, len(c.layers))
+	for i, layer := range c.layers {
+		f, err := LoadFile(layer.Path)
+		if err != nil {
+			f, _ = LoadFile("")
+			f.Path = layer.Path
+		}
+		if layer.shared() {
+			f.Restrict(SharedSections)
+		}
+		files[i] = f
+	}
+	return files
+}
+
+// highestLayer returns the index of the last file containing key, or -1.
+func highestLayer(files []*File, key string) int {
+	for i := len(files) - 1; i >= 0; i-- {
+		if files[i].Has(key) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"jirar/internal/secret"
+)
+
+// Setting is a single configuration key with its effective value.
+type Setting struct {
+	Key    string
+	Value  any
+	Secret bool
+}
+
+// Setting returns the effective value of a single key.
+func (c *Config) Setting(key string) (Setting, bool) {
+	for _, s := range c.Settings() {
+		if s.Key == key {
+			return s, true
+		}
+	}
+	return Setting{}, false
+}
+
+// Settings flattens the configuration into dotted keys in declaration order.
+// Values of fields tagged `secret:"true"` are always redacted.
+func (c *Config) Settings() []Setting {
+	var settings []Setting
+	flatten("", reflect.ValueOf(c).Elem(), &settings)
+	return settings
+}
+
+// flatten walks a struct value following its mapstructure tags.
+func flatten(prefix string, v reflect.Value, out *[]Setting) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		value := v.Field(i)
+		if opts == "squash" {
+			flatten(prefix, value, out)
+			continue
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		switch {
+		case value.Kind() == reflect.Struct:
+			flatten(key, value, out)
+			continue
+		case value.Kind() == reflect.Map && value.Type().Elem().Kind() == reflect.Struct:
+			keys := value.MapKeys()
+			sort.Slice(keys, func(a, b int) bool { return keys[a].String() < keys[b].String() })
+			for _, k := range keys {
+				elem := reflect.New(value.Type().Elem()).Elem()
+				elem.Set(value.MapIndex(k))
+				flatten(key+"."+k.String(), elem, out)
+			}
+			continue
+		case value.Kind() == reflect.Map:
+			flattenMap(key, value, out)
+			continue
+		}
+
+		setting := Setting{Key: key, Value: value.Interface(), Secret: field.Tag.Get("secret") == "true"}
+		if setting.Secret {
+			setting.Value = secret.Redact(value.String())
+		}
+		*out = append(*out, setting)
+	}
+}
+
+// flattenMap lists the entries of a map, and of nested maps, as dotted keys.
+func flattenMap(prefix string, v reflect.Value, out *[]Setting) {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(a, b int) bool { return keys[a].String() < keys[b].String() })
+	for _, k := range keys {
+		key := prefix + "." + k.String()
+		elem := reflect.ValueOf(v.MapIndex(k).Interface())
+		if elem.Kind() == reflect.Map {
+			flattenMap(key, elem, out)
+			continue
+		}
+		*out = append(*out, Setting{Key: key, Value: elem.Interface()})
+	}
+}
+
+// Field describes a settable configuration key. Keys inside maps such as
+// profiles use "*" for the map key.
+type Field struct {
+	Key    string
+	Type   reflect.Type
+	Secret bool
+}
+
+// Fields lists every configuration key in declaration order.
+func Fields() []Field {
+	var fields []Field
+	walkFields("", reflect.TypeOf(Config{}), &fields)
+	return fields
+}
+
+// LookupField finds the schema field for a dotted key.
+func LookupField(key string) (Field, bool) {
+	parts := strings.Split(key, ".")
+	for _, f := range Fields() {
+		pattern := strings.Split(f.Key, ".")
+		if len(pattern) != len(parts) {
+			continue
+		}
+
+		match := true
+		for i := range pattern {
+			if pattern[i] != "*" && pattern[i] != parts[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			f.Key = key
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// Parse converts a command-line string into a value of the field's type.
+func (f Field) Parse(raw string) (any, error) {
+	switch f.Type.Kind() {
+	case reflect.String:
+		return raw, nil
+	case reflect.Bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects a boolean, got %q", f.Key, raw)
+		}
+		return v, nil
+	case reflect.Int, reflect.Int64:
+		if f.Type == reflect.TypeOf(time.Duration(0)) {
+			v, err := time.ParseDuration(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%s expects a duration such as 30s, got %q", f.Key, raw)
+			}
+			return v.String(), nil
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects an integer, got %q", f.Key, raw)
+		}
+		return v, nil
+	case reflect.Slice:
+		if f.Type.Elem().Kind() != reflect.String {
+			break
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	case reflect.Map:
+		if f.Type.Key().Kind() != reflect.String || f.Type.Elem().Kind() != reflect.String {
+			break
+		}
+		items := map[string]string{}
+		for _, item := range strings.Split(raw, ",") {
+			k, v, ok := strings.Cut(item, "=")
+			if !ok {
+				return nil, fmt.Errorf("%s expects key=value pairs, got %q", f.Key, item)
+			}
+			items[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("%s cannot be set from the command line", f.Key)
+}
+
+// walkFields collects schema fields from a struct type.
+func walkFields(prefix string, t reflect.Type, out *[]Field) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if opts == "squash" {
+			walkFields(prefix, field.Type, out)
+			continue
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			walkFields(key, field.Type, out)
+		case field.Type.Kind() == reflect.Map && field.Type.Elem().Kind() == reflect.Struct:
+			walkFields(key+".*", field.Type.Elem(), out)
+		case field.Type.Kind() == reflect.Map:
+			walkMap(key, field.Type, out)
+		default:
+			*out = append(*out, Field{Key: key, Type: field.Type, Secret: field.Tag.Get("secret") == "true"})
+		}
+	}
+}
+
+// walkMap collects a map field and its entries, so both
+// "ui.columns.aliases" and "ui.columns.aliases.sp" are settable.
+func walkMap(key string, t reflect.Type, out *[]Field) {
+	*out = append(*out, Field{Key: key, Type: t})
+	if t.Elem().Kind() == reflect.Map {
+		walkMap(key+".*", t.Elem(), out)
+		return
+	}
+	*out = append(*out, Field{Key: key + ".*", Type: t.Elem()})
+}
@@ -0,0 +1,212 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"jirar/internal/jira"
+)
+
+// connectTimeout bounds the status check of Connect, so a hung daemon
+// costs little more than going to Jira directly.
+const connectTimeout = 250 * time.Millisecond
+
+// Client is a jira.Client served by a daemon. Once the daemon cannot be
+// reached, its calls go to Jira directly.
+type Client struct {
+	path   string
+	direct jira.Client
+	logger *logrus.Logger
+	// down is set when the daemon went away
+	down atomic.Bool
+}
+
+var _ jira.Client = (*Client)(nil)
+
+// Connect returns a client served by the daemon on path if it runs for the
+// same profile and Jira site, and direct otherwise.
+func Connect(ctx context.Context, path, profile, domain string, direct jira.Client, logger *logrus.Logger) jira.Client {
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	// Another user owning the directory could serve any answers
+	if err := checkSocketDir(filepath.Dir(path)); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.WithError(err).Warn("Not using the daemon")
+		}
+		return direct
+	}
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+	status, err := GetStatus(ctx, path)
+	if err != nil {
+		logger.WithError(err).Debug("Not using the daemon")
+		return direct
+	}
+	if status.Profile != profile || status.Domain != domain {
+		logger.WithFields(logrus.Fields{"profile": status.Profile, "domain": status.Domain}).Debug("Not using the daemon of another profile")
+		return direct
+	}
+	logger.WithField("pid", status.PID).Debug("Using the daemon")
+	return &Client{path: path, direct: direct, logger: logger}
+}
+
+// do calls method on the daemon, or runs direct against Jira when the
+// daemon cannot be reached. Requests the daemon may have received are
+// not repeated, so a comment is never added twice.
+func do[R any](ctx context.Context, c *Client, method string, params any, direct func(jira.Client) (R, error)) (R, error) {
+	if !c.down.Load() {
+		var out R
+		err := call(ctx, c.path, method, params, &out)
+		var dial *dialError
+		if !errors.As(err, &dial) {
+			return out, err
+		}
+		c.logger.WithError(err).Debug("Daemon is gone, calling Jira directly")
+		c.down.Store(true)
+	}
+	return direct(c.direct)
+}
+
+// done adapts a call without a result to do.
+func done(err error) (struct{}, error) { return struct{}{}, err }
+
+// SearchIssues implements jira.Client.
+func (c *Client) SearchIssues(ctx context.Context, jql string, opts ...jira.SearchOption) (*jira.SearchResult, error) {
+	params := searchParams{JQL: jql, Options: searchOptions(opts)}
+	return do(ctx, c, "Jira.SearchIssues", params, func(d jira.Client) (*jira.SearchResult, error) {
+		return d.SearchIssues(ctx, jql, opts...)
+	})
+}
+
+// GetIssue implements jira.Client.
+func (c *Client) GetIssue(ctx context.Context, key string) (*jira.Issue, error) {
+	return do(ctx, c, "Jira.GetIssue", keyParams{Key: key}, func(d jira.Client) (*jira.Issue, error) {
+		return d.GetIssue(ctx, key)
+	})
+}
+
+// GetCurrentUser implements jira.Client.
+func (c *Client) GetCurrentUser(ctx context.Context) (*jira.CurrentUser, error) {
+	return do(ctx, c, "Jira.GetCurrentUser", nil, func(d jira.Client) (*jira.CurrentUser, error) {
+		return d.GetCurrentUser(ctx)
+	})
+}
+
+// ValidateCredentials implements jira.Client.
+func (c *Client) ValidateCredentials(ctx context.Context) error {
+	_, err := do(ctx, c, "Jira.ValidateCredentials", nil, func(d jira.Client) (struct{}, error) {
+		return done(d.ValidateCredentials(ctx))
+	})
+	return err
+}
+
+// ListProjects implements jira.Client.
+func (c *Client) ListProjects(ctx context.Context) ([]jira.Project, error) {
+	return do(ctx, c, "Jira.ListProjects", nil, func(d jira.Client) ([]jira.Project, error) {
+		return d.ListProjects(ctx)
+	})
+}
+
+// ListBoards implements jira.Client.
+func (c *Client) ListBoards(ctx context.Context, projectKey string) ([]jira.Board, error) {
+	return do(ctx, c, "Jira.ListBoards", keyParams{Key: projectKey}, func(d jira.Client) ([]jira.Board, error) {
+		return d.ListBoards(ctx, projectKey)
+	})
+}
+
+// GetBoard implements jira.Client.
+func (c *Client) GetBoard(ctx context.Context, id int) (*jira.Board, error) {
+	return do(ctx, c, "Jira.GetBoard", boardParams{ID: id}, func(d jira.Client) (*jira.Board, error) {
+		return d.GetBoard(ctx, id)
+	})
+}
+
+// GetBoardConfiguration implements jira.Client.
+func (c *Client) GetBoardConfiguration(ctx context.Context, id int) (*jira.BoardConfiguration, error) {
+	return do(ctx, c, "Jira.GetBoardConfiguration", boardParams{ID: id}, func(d jira.Client) (*jira.BoardConfiguration, error) {
+		return d.GetBoardConfiguration(ctx, id)
+	})
+}
+
+// GetBoardIssues implements jira.Client.
+func (c *Client) GetBoardIssues(ctx context.Context, id int, jql string, opts ...jira.SearchOption) (*jira.SearchResult, error) {
+	params := boardParams{ID: id, JQL: jql, Options: searchOptions(opts)}
+	return do(ctx, c, "Jira.GetBoardIssues", params, func(d jira.Client) (*jira.SearchResult, error) {
+		return d.GetBoardIssues(ctx, id, jql, opts...)
+	})
+}
+
+// GetServerInfo implements jira.Client.
+func (c *Client) GetServerInfo(ctx context.Context) (*jira.ServerInfo, error) {
+	return do(ctx, c, "Jira.GetServerInfo", nil, func(d jira.Client) (*jira.ServerInfo, error) {
+		return d.GetServerInfo(ctx)
+	})
+}
+
+// GetMyPermissions implements jira.Client.
+func (c *Client) GetMyPermissions(ctx context.Context, projectKey string, permissions ...string) (map[string]jira.Permission, error) {
+	params := permissionParams{Project: projectKey, Permissions: permissions}
+	return do(ctx, c, "Jira.GetMyPermissions", params, func(d jira.Client) (map[string]jira.Permission, error) {
+		return d.GetMyPermissions(ctx, projectKey, permissions...)
+	})
+}
+
+// GetComments implements jira.Client.
+func (c *Client) GetComments(ctx context.Context, key string) ([]jira.Comment, error) {
+	return do(ctx, c, "Jira.GetComments", keyParams{Key: key}, func(d jira.Client) ([]jira.Comment, error) {
+		return d.GetComments(ctx, key)
+	})
+}
+
+// AddComment implements jira.Client.
+func (c *Client) AddComment(ctx context.Context, key string, body *jira.ADF) (*jira.Comment, error) {
+	return do(ctx, c, "Jira.AddComment", commentParams{Key: key, Body: body}, func(d jira.Client) (*jira.Comment, error) {
+		return d.AddComment(ctx, key, body)
+	})
+}
+
+// GetTransitions implements jira.Client.
+func (c *Client) GetTransitions(ctx context.Context, key string) ([]jira.Transition, error) {
+	return do(ctx, c, "Jira.GetTransitions", keyParams{Key: key}, func(d jira.Client) ([]jira.Transition, error) {
+		return d.GetTransitions(ctx, key)
+	})
+}
+
+// TransitionIssue implements jira.Client.
+func (c *Client) TransitionIssue(ctx context.Context, key, transitionID string) error {
+	params := transitionParams{Key: key, Transition: transitionID}
+	_, err := do(ctx, c, "Jira.TransitionIssue", params, func(d jira.Client) (struct{}, error) {
+		return done(d.TransitionIssue(ctx, key, transitionID))
+	})
+	return err
+}
+
+// AssignIssue implements jira.Client.
+func (c *Client) AssignIssue(ctx context.Context, key, accountID string) error {
+	params := assignParams{Key: key, AccountID: accountID}
+	_, err := do(ctx, c, "Jira.AssignIssue", params, func(d jira.Client) (struct{}, error) {
+		return done(d.AssignIssue(ctx, key, accountID))
+	})
+	return err
+}
+
+// FindAssignableUsers implements jira.Client.
+func (c *Client) FindAssignableUsers(ctx context.Context, key, query string) ([]jira.User, error) {
+	return do(ctx, c, "Jira.FindAssignableUsers", userParams{Key: key, Query: query}, func(d jira.Client) ([]jira.User, error) {
+		return d.FindAssignableUsers(ctx, key, query)
+	})
+}
+
+// Do implements jira.Client.
+func (c *Client) Do(ctx context.Context, req *jira.RawRequest) (*jira.RawResponse, error) {
+	return do(ctx, c, "Jira.Do", req, func(d jira.Client) (*jira.RawResponse, error) {
+		return d.Do(ctx, req)
+	})
+}
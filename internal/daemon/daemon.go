@@ -0,0 +1,110 @@
+// Package daemon keeps a Jira client warm in a background process and
+// serves it over a Unix socket with JSON-RPC 2.0. The daemon caches the
+// issues it is asked for and refreshes them while they are in use, so
+// commands that go through it answer without waiting for Jira.
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Methods of the daemon besides those of jira.Client, which are named
+// Jira.<method>, e.g. Jira.SearchIssues.
+const (
+	// MethodStatus returns the Status of the daemon
+	MethodStatus = "Daemon.Status"
+	// MethodStop shuts the daemon down after answering
+	MethodStop = "Daemon.Stop"
+)
+
+// Status describes a running daemon.
+type Status struct {
+	// Profile is the configuration profile the daemon serves; empty is
+	// the default one
+	Profile string `json:"profile"`
+	// Domain is the Jira site of the profile
+	Domain  string    `json:"domain"`
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+	// Refresh is how often cached issues are refetched
+	Refresh string `json:"refresh"`
+	// Watching are the filters of the daemon's watchers
+	Watching []string `json:"watching"`
+	// Cached counts the cached results; Hits and Misses the requests
+	// answered from the cache and from Jira
+	Cached int   `json:"cached"`
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// DefaultSocketPath returns $XDG_RUNTIME_DIR/jirar.sock, or a socket in a
+// private directory under the temporary directory without it.
+func DefaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "jirar.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("jirar-%d", os.Getuid()), "jirar.sock")
+}
+
+// Listen opens the socket at path for a new daemon, replacing the socket
+// file of one that is gone. Only the user can connect to it, and the
+// socket directory must be the user's alone.
+func Listen(path string) (net.Listener, error) {
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a daemon is already running on %s", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create socket directory: %w", err)
+	}
+	if err := checkSocketDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("remove stale socket: %w", err)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("restrict socket: %w", err)
+	}
+	return l, nil
+}
+
+// GetStatus asks the daemon on path for its status.
+func GetStatus(ctx context.Context, path string) (*Status, error) {
+	var status Status
+	if err := call(ctx, path, MethodStatus, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Stop shuts down the daemon on path and waits for it to close its
+// socket.
+func Stop(ctx context.Context, path string) error {
+	if err := call(ctx, path, MethodStop, nil, nil); err != nil {
+		return err
+	}
+	for {
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			return nil
+		}
+		conn.Close()
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("daemon did not stop: %w", ctx.Err())
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package daemon
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"jirar/internal/jira"
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	codeParse          = -32700
+	codeInvalidRequest = -32600
+	codeNoMethod       = -32601
+	codeInvalidParams  = -32602
+	// codeJira is a Jira request that failed
+	codeJira = -32000
+)
+
+// request is a JSON-RPC 2.0 request. Requests and responses are JSON
+// values one after the other on a connection, such as one per line.
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC 2.0 response.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is an error answered by the daemon, such as a failed Jira
+// request.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// ErrNotRunning is returned when no daemon listens on the socket.
+var ErrNotRunning = errors.New("daemon is not running")
+
+// dialError is a daemon that could not be reached, so the request was
+// never sent. It is ErrNotRunning.
+type dialError struct {
+	err error
+}
+
+func (e *dialError) Error() string        { return fmt.Sprintf("connect to daemon: %v", e.err) }
+func (e *dialError) Unwrap() error        { return e.err }
+func (e *dialError) Is(target error) bool { return target == ErrNotRunning }
+
+// Parameters of the Jira methods.
+type (
+	searchParams struct {
+		JQL     string             `json:"jql"`
+		Options jira.SearchOptions `json:"options"`
+	}
+	keyParams struct {
+		Key string `json:"key"`
+	}
+	boardParams struct {
+		ID      int                `json:"id"`
+		JQL     string             `json:"jql,omitempty"`
+		Options jira.SearchOptions `json:"options"`
+	}
+	permissionParams struct {
+		Project     string   `json:"project,omitempty"`
+		Permissions []string `json:"permissions,omitempty"`
+	}
+	commentParams struct {
+		Key  string    `json:"key"`
+		Body *jira.ADF `json:"body"`
+	}
+	transitionParams struct {
+		Key        string `json:"key"`
+		Transition string `json:"transition"`
+	}
+	assignParams struct {
+		Key       string `json:"key"`
+		AccountID string `json:"accountId"`
+	}
+	userParams struct {
+		Key   string `json:"key"`
+		Query string `json:"query"`
+	}
+)
+
+// searchOptions collects search options, so they can be sent.
+func searchOptions(opts []jira.SearchOption) jira.SearchOptions {
+	var options jira.SearchOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// options turns sent search options back into the options of a search;
+// unset values keep the client's defaults.
+func options(o jira.SearchOptions) []jira.SearchOption {
+	var opts []jira.SearchOption
+	if o.Limit > 0 {
+		opts = append(opts, jira.WithLimit(o.Limit))
+	}
+	if o.StartAt > 0 {
+		opts = append(opts, jira.WithStartAt(o.StartAt))
+	}
+	if len(o.Fields) > 0 {
+		opts = append(opts, jira.WithFields(o.Fields...))
+	}
+	if len(o.Expand) > 0 {
+		opts = append(opts, jira.WithExpand(o.Expand...))
+	}
+	return opts
+}
+
+// call sends a request to the daemon on path over a connection of its
+// own and decodes the result into out, unless out is nil.
+func call(ctx context.Context, path, method string, params, out any) error {
+	req := request{JSONRPC: "2.0", ID: json.RawMessage("1"), Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("encode %s request: %w", method, err)
+		}
+		req.Params = data
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return &dialError{err}
+	}
+	defer conn.Close()
+	// Blocked reads and writes end with the context
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return fmt.Errorf("send %s request: %w", method, cmp.Or(ctx.Err(), err))
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("read %s response: %w", method, cmp.Or(ctx.Err(), err))
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if out == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, out); err != nil {
+		return fmt.Errorf("decode %s response: %w", method, err)
+	}
+	return nil
+}
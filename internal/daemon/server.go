@@ -0,0 +1,440 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"jirar/internal/jira"
+)
+
+// Cache limits of the server.
+const (
+	// hotFor is how long a cached result is refreshed after it was last
+	// asked for; it is dropped after that
+	hotFor = 15 * time.Minute
+	// staticFor is how long results that hardly change, such as the
+	// current user or the projects, are served
+	staticFor = time.Hour
+	// requestTimeout bounds a Jira request made for a client
+	requestTimeout = 2 * time.Minute
+)
+
+// issueMethods are the cached methods whose results follow the issues:
+// they are refreshed while in use and invalidated by changes.
+var issueMethods = map[string]bool{
+	"Jira.SearchIssues":   true,
+	"Jira.GetIssue":       true,
+	"Jira.GetComments":    true,
+	"Jira.GetBoardIssues": true,
+}
+
+// staticMethods are the cached methods whose results hardly change.
+var staticMethods = map[string]bool{
+	"Jira.GetCurrentUser":        true,
+	"Jira.GetServerInfo":         true,
+	"Jira.ListProjects":          true,
+	"Jira.ListBoards":            true,
+	"Jira.GetBoard":              true,
+	"Jira.GetBoardConfiguration": true,
+}
+
+// Options configure a Server.
+type Options struct {
+	// Client makes the requests to Jira
+	Client jira.Client
+	// Profile and Domain identify what the daemon serves, so clients of
+	// other profiles do not use it
+	Profile string
+	Domain  string
+	// Refresh is how often cached issues are refetched; they are served
+	// for up to twice as long
+	Refresh time.Duration
+	// Watching are the filters the daemon watches, for its status
+	Watching []string
+	// Stop is called when a client asks the daemon to stop
+	Stop   func()
+	Logger *logrus.Logger
+}
+
+// method answers a request with its JSON result.
+type method func(ctx context.Context, params json.RawMessage) (any, error)
+
+// entry is a cached result.
+type entry struct {
+	method  string
+	params  json.RawMessage
+	result  json.RawMessage
+	fetched time.Time
+	used    time.Time
+}
+
+// Server answers JSON-RPC requests with the results of a Jira client,
+// caching those of reads.
+type Server struct {
+	opts    Options
+	logger  *logrus.Logger
+	methods map[string]method
+	started time.Time
+	// kick asks for a refresh of the invalidated results
+	kick chan struct{}
+
+	hits, misses atomic.Int64
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	// generation counts invalidations, so results fetched before one are
+	// not stored as fresh
+	generation int
+}
+
+// NewServer creates a server of opts.Client.
+func NewServer(opts Options) *Server {
+	if opts.Logger == nil {
+		opts.Logger = logrus.StandardLogger()
+	}
+	if opts.Refresh <= 0 {
+		opts.Refresh = time.Minute
+	}
+	s := &Server{
+		opts:    opts,
+		logger:  opts.Logger,
+		started: time.Now(),
+		kick:    make(chan struct{}, 1),
+		entries: map[string]*entry{},
+	}
+	s.methods = s.jiraMethods()
+	s.methods[MethodStatus] = handle(func(context.Context, struct{}) (*Status, error) { return s.status(), nil })
+	s.methods[MethodStop] = handle(func(context.Context, struct{}) (any, error) { return nil, nil })
+	return s
+}
+
+// handle adapts a function of decoded parameters to a method.
+func handle[P, R any](f func(context.Context, P) (R, error)) method {
+	return func(ctx context.Context, raw json.RawMessage) (any, error) {
+		var params P
+		if len(raw) > 0 && string(raw) != "null" {
+			if err := json.Unmarshal(raw, &params); err != nil {
+				return nil, &Error{Code: codeInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+			}
+		}
+		return f(ctx, params)
+	}
+}
+
+// jiraMethods returns the methods of the Jira client.
+func (s *Server) jiraMethods() map[string]method {
+	c := s.opts.Client
+	return map[string]method{
+		"Jira.SearchIssues": handle(func(ctx context.Context, p searchParams) (*jira.SearchResult, error) {
+			return c.SearchIssues(ctx, p.JQL, options(p.Options)...)
+		}),
+		"Jira.GetIssue": handle(func(ctx context.Context, p keyParams) (*jira.Issue, error) {
+			return c.GetIssue(ctx, p.Key)
+		}),
+		"Jira.GetCurrentUser": handle(func(ctx context.Context, _ struct{}) (*jira.CurrentUser, error) {
+			return c.GetCurrentUser(ctx)
+		}),
+		"Jira.ValidateCredentials": handle(func(ctx context.Context, _ struct{}) (any, error) {
+			return nil, c.ValidateCredentials(ctx)
+		}),
+		"Jira.ListProjects": handle(func(ctx context.Context, _ struct{}) ([]jira.Project, error) {
+			return c.ListProjects(ctx)
+		}),
+		"Jira.ListBoards": handle(func(ctx context.Context, p keyParams) ([]jira.Board, error) {
+			return c.ListBoards(ctx, p.Key)
+		}),
+		"Jira.GetBoard": handle(func(ctx context.Context, p boardParams) (*jira.Board, error) {
+			return c.GetBoard(ctx, p.ID)
+		}),
+		"Jira.GetBoardConfiguration": handle(func(ctx context.Context, p boardParams) (*jira.BoardConfiguration, error) {
+			return c.GetBoardConfiguration(ctx, p.ID)
+		}),
+		"Jira.GetBoardIssues": handle(func(ctx context.Context, p boardParams) (*jira.SearchResult, error) {
+			return c.GetBoardIssues(ctx, p.ID, p.JQL, options(p.Options)...)
+		}),
+		"Jira.GetServerInfo": handle(func(ctx context.Context, _ struct{}) (*jira.ServerInfo, error) {
+			return c.GetServerInfo(ctx)
+		}),
+		"Jira.GetMyPermissions": handle(func(ctx context.Context, p permissionParams) (map[string]jira.Permission, error) {
+			return c.GetMyPermissions(ctx, p.Project, p.Permissions...)
+		}),
+		"Jira.GetComments": handle(func(ctx context.Context, p keyParams) ([]jira.Comment, error) {
+			return c.GetComments(ctx, p.Key)
+		}),
+		"Jira.AddComment": handle(func(ctx context.Context, p commentParams) (*jira.Comment, error) {
+			defer s.Invalidate()
+			return c.AddComment(ctx, p.Key, p.Body)
+		}),
+		"Jira.GetTransitions": handle(func(ctx context.Context, p keyParams) ([]jira.Transition, error) {
+			return c.GetTransitions(ctx, p.Key)
+		}),
+		"Jira.TransitionIssue": handle(func(ctx context.Context, p transitionParams) (any, error) {
+			defer s.Invalidate()
+			return nil, c.TransitionIssue(ctx, p.Key, p.Transition)
+		}),
+		"Jira.AssignIssue": handle(func(ctx context.Context, p assignParams) (any, error) {
+			defer s.Invalidate()
+			return nil, c.AssignIssue(ctx, p.Key, p.AccountID)
+		}),
+		"Jira.FindAssignableUsers": handle(func(ctx context.Context, p userParams) ([]jira.User, error) {
+			return c.FindAssignableUsers(ctx, p.Key, p.Query)
+		}),
+		"Jira.Do": handle(func(ctx context.Context, p jira.RawRequest) (*jira.RawResponse, error) {
+			if p.Method != "" && p.Method != http.MethodGet && p.Method != http.MethodHead {
+				defer s.Invalidate()
+			}
+			return c.Do(ctx, &p)
+		}),
+	}
+}
+
+// Serve answers the connections of l until ctx is cancelled, refreshing
+// the cached results in the background.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	go s.refreshLoop(ctx)
+	stop := context.AfterFunc(ctx, func() { l.Close() })
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("accept connection: %w", err)
+		}
+		wg.Go(func() { s.serveConn(ctx, conn) })
+	}
+}
+
+// serveConn answers the requests of a connection in turn.
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	for {
+		var req request
+		if err := dec.Decode(&req); err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				enc.Encode(response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: codeParse, Message: fmt.Sprintf("parse error: %v", err)}})
+			}
+			return
+		}
+		resp := s.answer(ctx, req)
+		if req.ID == nil {
+			// Notifications get no response
+			continue
+		}
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+		if req.Method == MethodStop && resp.Error == nil && s.opts.Stop != nil {
+			s.opts.Stop()
+		}
+	}
+}
+
+// answer runs a request.
+func (s *Server) answer(ctx context.Context, req request) response {
+	resp := response{JSONRPC: "2.0", ID: req.ID}
+	result, err := s.call(ctx, req)
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: codeJira, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+		return resp
+	}
+	resp.Result = result
+	return resp
+}
+
+// call runs a request, from the cache where possible.
+func (s *Server) call(ctx context.Context, req request) (json.RawMessage, error) {
+	if req.JSONRPC != "2.0" {
+		return nil, &Error{Code: codeInvalidRequest, Message: `invalid request: jsonrpc must be "2.0"`}
+	}
+	m, ok := s.methods[req.Method]
+	if !ok {
+		return nil, &Error{Code: codeNoMethod, Message: fmt.Sprintf("method not found: %s", req.Method)}
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	s.logger.WithField("method", req.Method).Debug("Daemon request")
+
+	if !issueMethods[req.Method] && !staticMethods[req.Method] {
+		return run(ctx, m, req.Params)
+	}
+
+	key := req.Method + " " + string(req.Params)
+	now := time.Now()
+	s.mu.Lock()
+	if e := s.entries[key]; e != nil && now.Sub(e.fetched) < s.ttl(e.method) {
+		e.used = now
+		s.mu.Unlock()
+		s.hits.Add(1)
+		return e.result, nil
+	}
+	generation := s.generation
+	s.mu.Unlock()
+
+	s.misses.Add(1)
+	result, err := run(ctx, m, req.Params)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := &entry{method: req.Method, params: req.Params, result: result, fetched: now, used: now}
+	if s.generation != generation && issueMethods[req.Method] {
+		// Changed while fetching; the next request fetches again
+		e.fetched = time.Time{}
+	}
+	s.entries[key] = e
+	return result, nil
+}
+
+// run runs a method and encodes its result.
+func run(ctx context.Context, m method, params json.RawMessage) (json.RawMessage, error) {
+	result, err := m(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("encode result: %w", err)
+	}
+	return data, nil
+}
+
+// ttl returns how long a result of method is served.
+func (s *Server) ttl(method string) time.Duration {
+	if staticMethods[method] {
+		return staticFor
+	}
+	return 2 * s.opts.Refresh
+}
+
+// Warm fetches a search into the cache, as if a client had asked for it,
+// so the first request for it is answered at once.
+func (s *Server) Warm(ctx context.Context, jql string, opts ...jira.SearchOption) error {
+	params, err := json.Marshal(searchParams{JQL: jql, Options: searchOptions(opts)})
+	if err != nil {
+		return fmt.Errorf("encode search: %w", err)
+	}
+	resp := s.answer(ctx, request{JSONRPC: "2.0", Method: "Jira.SearchIssues", Params: params})
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
+
+// Invalidate marks the cached issues as stale after a change and has them
+// refetched.
+func (s *Server) Invalidate() {
+	s.mu.Lock()
+	s.generation++
+	for _, e := range s.entries {
+		if issueMethods[e.method] {
+			e.fetched = time.Time{}
+		}
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+// refreshLoop refreshes the cache every refresh period and after
+// invalidations until ctx is cancelled.
+func (s *Server) refreshLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.Refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.kick:
+		}
+		s.refresh(ctx)
+	}
+}
+
+// refresh refetches the cached issues that are in use and drops the
+// results nobody asked for in a while.
+func (s *Server) refresh(ctx context.Context) {
+	now := time.Now()
+	var stale []*entry
+	s.mu.Lock()
+	for key, e := range s.entries {
+		switch {
+		case now.Sub(e.used) > max(hotFor, s.ttl(e.method)):
+			delete(s.entries, key)
+		case issueMethods[e.method]:
+			// Results fetched for a client just now are fresh enough
+			if now.Sub(e.fetched) >= s.opts.Refresh/2 {
+				stale = append(stale, e)
+			}
+		case now.Sub(e.fetched) >= s.ttl(e.method):
+			delete(s.entries, key)
+		}
+	}
+	generation := s.generation
+	s.mu.Unlock()
+
+	for _, e := range stale {
+		fetch, cancel := context.WithTimeout(ctx, requestTimeout)
+		result, err := run(fetch, s.methods[e.method], e.params)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			s.logger.WithError(err).WithField("method", e.method).Warn("Failed to refresh cached result")
+			continue
+		}
+		s.mu.Lock()
+		if s.generation == generation {
+			e.result, e.fetched = result, time.Now()
+		}
+		s.mu.Unlock()
+	}
+}
+
+// status returns the status of the server.
+func (s *Server) status() *Status {
+	s.mu.Lock()
+	cached := len(s.entries)
+	s.mu.Unlock()
+	return &Status{
+		Profile:  s.opts.Profile,
+		Domain:   s.opts.Domain,
+		PID:      os.Getpid(),
+		Started:  s.started,
+		Refresh:  s.opts.Refresh.String(),
+		Watching: s.opts.Watching,
+		Cached:   cached,
+		Hits:     s.hits.Load(),
+		Misses:   s.misses.Load(),
+	}
+}
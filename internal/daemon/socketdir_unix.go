@@ -0,0 +1,23 @@
+//go:build unix
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkSocketDir refuses a socket directory other users could have put a
+// socket in: it must be a real directory owned by the user with mode 0700.
+func checkSocketDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("socket directory: %w", err)
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !info.IsDir() || !ok || int(st.Uid) != os.Getuid() || info.Mode().Perm() != 0o700 {
+		return fmt.Errorf("socket directory %s must be a directory owned by you with mode 0700", dir)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// UnitName is the name of the systemd user unit of the daemon.
+const UnitName = "jirar.service"
+
+// DefaultUnitPath returns where systemd looks for the user unit:
+// ~/.config/systemd/user/jirar.service.
+func DefaultUnitPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("find config directory: %w", err)
+	}
+	return filepath.Join(dir, "systemd", "user", UnitName), nil
+}
+
+// SystemdUnit returns a systemd user unit that runs the daemon as
+// executable with args, restarting it when it fails.
+func SystemdUnit(executable string, args []string) string {
+	command := []string{quoteArg(executable)}
+	for _, arg := range args {
+		command = append(command, quoteArg(arg))
+	}
+	return fmt.Sprintf(`[Unit]
+Description=jirar daemon for Jira
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+ExecStart=%s
+Restart=on-failure
+RestartSec=10
+
+[Install]
+WantedBy=default.target
+`, strings.Join(command, " "))
+}
+
+// quoteArg quotes an argument of ExecStart when it needs it. Percent
+// signs and dollars, which systemd expands, are escaped.
+func quoteArg(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+	if arg == "" || strings.ContainsAny(arg, " \t\"'\\;") {
+		return strconv.Quote(arg)
+	}
+	return arg
+}
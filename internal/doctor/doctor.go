@@ -0,0 +1,405 @@
+// Package doctor diagnoses configuration, connectivity and permission problems.
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"jirar/internal/config"
+	"jirar/internal/jira"
+)
+
+// Check statuses, from best to worst.
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// Thresholds used by the checks.
+const (
+	clockSkewWarn  = 30 * time.Second
+	clockSkewFail  = 5 * time.Minute
+	certExpiryWarn = 14 * 24 * time.Hour
+	rateLimitWarn  = 0.1
+)
+
+// projectPermissions are checked for the default project. Browsing is
+// required; the rest only limit what jirar can do.
+var projectPermissions = []string{
+	"BROWSE_PROJECTS",
+	"CREATE_ISSUES",
+	"EDIT_ISSUES",
+	"ASSIGN_ISSUES",
+	"TRANSITION_ISSUES",
+	"ADD_COMMENTS",
+	"WORK_ON_ISSUES",
+}
+
+// checkNames lists every check in the order it runs.
+var checkNames = []string{"config", "dns", "tls", "server", "credentials", "clock", "rate limit", "permissions"}
+
+// Check is the outcome of a single diagnostic.
+type Check struct {
+	Name    string         `json:"name"`
+	Status  string         `json:"status"`
+	Message string         `json:"message"`
+	Hint    string         `json:"hint,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Report collects the checks of a doctor run.
+type Report struct {
+	Version string    `json:"version"`
+	Time    time.Time `json:"time"`
+	Profile string    `json:"profile,omitempty"`
+	Domain  string    `json:"domain,omitempty"`
+	Checks  []Check   `json:"checks"`
+}
+
+// Failed returns the number of failed checks.
+func (r *Report) Failed() int {
+	n := 0
+	for _, c := range r.Checks {
+		if c.Status == StatusFail {
+			n++
+		}
+	}
+	return n
+}
+
+// Doctor runs the diagnostics against a configuration.
+type Doctor struct {
+	config  *config.Config
+	client  jira.Client
+	dialer  *net.Dialer
+	version string
+}
+
+// New creates a Doctor for cfg using client for API checks.
+func New(cfg *config.Config, client jira.Client, version string) *Doctor {
+	return &Doctor{
+		config:  cfg,
+		client:  client,
+		dialer:  &net.Dialer{Timeout: 10 * time.Second},
+		version: version,
+	}
+}
+
+// Run executes the checks in order. Checks that depend on an earlier
+// failure are skipped.
+func (d *Doctor) Run(ctx context.Context) *Report {
+	report := &Report{
+		Version: d.version,
+		Time:    time.Now(),
+		Profile: d.config.ActiveProfile,
+		Domain:  d.config.Jira.Domain,
+	}
+	add := func(c Check) bool {
+		report.Checks = append(report.Checks, c)
+		return c.Status != StatusFail && c.Status != StatusSkip
+	}
+
+	u, ok := d.checkConfig(add)
+	ok = ok && add(d.checkDNS(ctx, u))
+	ok = ok && add(d.checkTLS(ctx, u))
+
+	// Server info needs no credentials and tells which API the later
+	// checks use: Server and Data Center only serve version 2
+	var (
+		resp *jira.RawResponse
+		api  = "3"
+	)
+	if ok {
+		var info *jira.ServerInfo
+		info, resp = d.checkServer(ctx, add)
+		if info != nil && !info.IsCloud() {
+			api = "2"
+		}
+	}
+
+	var user *jira.CurrentUser
+	if ok {
+		var c Check
+		user, c = d.checkCredentials(ctx, api)
+		ok = add(c)
+	}
+
+	if ok {
+		add(checkClock(resp))
+		add(checkRateLimit(resp))
+		add(d.checkPermissions(ctx, api, user))
+	}
+
+	for _, name := range checkNames {
+		if !hasCheck(report, name) {
+			add(Check{Name: name, Status: StatusSkip, Message: "skipped after an earlier failure"})
+		}
+	}
+	return report
+}
+
+// checkConfig validates the resolved configuration.
+func (d *Doctor) checkConfig(add func(Check) bool) (*url.URL, bool) {
+	layers := make([]string, 0, len(d.config.Layers()))
+	for _, l := range d.config.Layers() {
+		layers = append(layers, l.Scope+": "+l.Path)
+	}
+	details := map[string]any{"files": layers, "token": d.config.Origin("jira.token").String()}
+	if d.config.Jira.TokenRef != "" {
+		details["token"] = d.config.Jira.TokenRef
+	} else if d.config.Jira.CredentialHelper != "" {
+		details["token"] = "helper:" + d.config.Jira.CredentialHelper
+	}
+
+	if err := d.config.Validate(); err != nil {
+		hint := "Run 'jirar config init' or set JIRA_DOMAIN, JIRA_EMAIL and JIRA_TOKEN"
+		if d.config.Jira.Token == "" && (d.config.Jira.TokenRef != "" || d.config.Jira.CredentialHelper != "") {
+			hint = "The token backend returned nothing; store it with 'jirar config set-token'"
+		}
+		add(Check{Name: "config", Status: StatusFail, Message: err.Error(), Hint: hint, Details: details})
+		return nil, false
+	}
+
+	u, err := url.Parse(d.config.Jira.Domain)
+	if err != nil || u.Host == "" {
+		add(Check{Name: "config", Status: StatusFail, Message: fmt.Sprintf("invalid domain %q", d.config.Jira.Domain),
+			Hint: "Set jira.domain to a URL such as https://company.atlassian.net", Details: details})
+		return nil, false
+	}
+
+	msg := fmt.Sprintf("%d config file(s) merged", len(layers))
+	if d.config.ActiveProfile != "" {
+		msg += ", profile " + d.config.ActiveProfile
+	}
+	return u, add(Check{Name: "config", Status: StatusPass, Message: msg, Details: details})
+}
+
+// checkDNS resolves the Jira host.
+func (d *Doctor) checkDNS(ctx context.Context, u *url.URL) Check {
+	addrs, err := net.DefaultResolver.LookupHost(ctx, u.Hostname())
+	if err != nil {
+		return Check{Name: "dns", Status: StatusFail, Message: err.Error(),
+			Hint: "Check the domain spelling, your network and VPN connection"}
+	}
+	return Check{Name: "dns", Status: StatusPass,
+		Message: fmt.Sprintf("%s resolves to %s", u.Hostname(), strings.Join(addrs, ", ")),
+		Details: map[string]any{"addresses": addrs}}
+}
+
+// checkTLS performs a TLS handshake and inspects the certificate.
+func (d *Doctor) checkTLS(ctx context.Context, u *url.URL) Check {
+	if u.Scheme != "https" {
+		return Check{Name: "tls", Status: StatusWarn, Message: "domain does not use https; the token is sent in clear text",
+			Hint: "Use an https:// domain"}
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+
+	dialer := &tls.Dialer{NetDialer: d.dialer, Config: &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return Check{Name: "tls", Status: StatusFail, Message: err.Error(),
+			Hint: "A proxy or firewall may be intercepting the connection; check HTTPS_PROXY and corporate CA settings"}
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	cert := state.PeerCertificates[0]
+	details := map[string]any{
+		"version":  tls.VersionName(state.Version),
+		"issuer":   cert.Issuer.CommonName,
+		"notAfter": cert.NotAfter,
+	}
+
+	if left := time.Until(cert.NotAfter); left < certExpiryWarn {
+		return Check{Name: "tls", Status: StatusWarn, Message: fmt.Sprintf("certificate expires in %s", left.Round(time.Hour)),
+			Hint: "Tell your Jira administrator", Details: details}
+	}
+	return Check{Name: "tls", Status: StatusPass,
+		Message: fmt.Sprintf("%s, certificate valid until %s", tls.VersionName(state.Version), cert.NotAfter.Format("2006-01-02")),
+		Details: details}
+}
+
+// checkCredentials authenticates as the configured user through version
+// api of the REST API.
+func (d *Doctor) checkCredentials(ctx context.Context, api string) (*jira.CurrentUser, Check) {
+	var user *jira.CurrentUser
+	status, err := d.getJSON(ctx, "/rest/api/"+api+"/myself", nil, &user)
+	if err != nil {
+		hint := "Create a new API token at https://id.atlassian.com/manage-profile/security/api-tokens"
+		if api == "2" {
+			hint = "Create a personal access token under Profile > Personal Access Tokens in Jira"
+		}
+		if status == http.StatusUnauthorized {
+			hint = "The email or token was rejected. " + hint
+		}
+		return nil, Check{Name: "credentials", Status: StatusFail, Message: err.Error(), Hint: hint}
+	}
+	if !user.Active {
+		return user, Check{Name: "credentials", Status: StatusWarn, Message: fmt.Sprintf("%s is deactivated", user.DisplayName)}
+	}
+	return user, Check{Name: "credentials", Status: StatusPass,
+		Message: fmt.Sprintf("authenticated as %s <%s>", user.DisplayName, user.Email),
+		Details: map[string]any{"accountId": user.AccountID, "timeZone": user.TimeZone}}
+}
+
+// checkServer detects Cloud or Server/Data Center through version 2 of
+// the REST API, which both serve. It returns the server info, nil if it
+// could not be read, and the raw response so its headers can be inspected
+// by later checks.
+func (d *Doctor) checkServer(ctx context.Context, add func(Check) bool) (*jira.ServerInfo, *jira.RawResponse) {
+	start := time.Now()
+	resp, err := d.client.Do(ctx, &jira.RawRequest{Path: "/rest/api/2/serverInfo"})
+	latency := time.Since(start)
+	if err != nil {
+		add(Check{Name: "server", Status: StatusWarn, Message: err.Error()})
+		return nil, nil
+	}
+
+	var info jira.ServerInfo
+	if resp.StatusCode != http.StatusOK || json.Unmarshal(resp.Body, &info) != nil {
+		add(Check{Name: "server", Status: StatusWarn, Message: "could not read server info: " + resp.Status})
+		return nil, resp
+	}
+
+	kind := "Jira " + info.DeploymentType
+	if info.DeploymentType == "" {
+		kind = "Jira Server"
+	}
+	add(Check{Name: "server", Status: StatusPass,
+		Message: fmt.Sprintf("%s %s (%s round trip)", kind, info.Version, latency.Round(time.Millisecond)),
+		Details: map[string]any{"deploymentType": info.DeploymentType, "version": info.Version, "baseUrl": info.BaseURL}})
+	return &info, resp
+}
+
+// checkClock compares the local clock with the server's Date header.
+func checkClock(resp *jira.RawResponse) Check {
+	if resp == nil || resp.Header.Get("Date") == "" {
+		return Check{Name: "clock", Status: StatusSkip, Message: "server sent no Date header"}
+	}
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return Check{Name: "clock", Status: StatusSkip, Message: "unparseable Date header"}
+	}
+
+	skew := time.Since(serverTime).Round(time.Second)
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+	details := map[string]any{"skewSeconds": skew.Seconds()}
+
+	switch {
+	case abs > clockSkewFail:
+		return Check{Name: "clock", Status: StatusFail, Message: fmt.Sprintf("local clock is off by %s", skew),
+			Hint: "Enable NTP time sync (e.g. timedatectl set-ntp true)", Details: details}
+	case abs > clockSkewWarn:
+		return Check{Name: "clock", Status: StatusWarn, Message: fmt.Sprintf("local clock is off by %s", skew),
+			Hint: "Relative times and watch checkpoints may be inaccurate; enable NTP", Details: details}
+	}
+	return Check{Name: "clock", Status: StatusPass, Message: fmt.Sprintf("in sync with server (%s)", skew), Details: details}
+}
+
+// checkRateLimit reports the remaining API budget from response headers.
+func checkRateLimit(resp *jira.RawResponse) Check {
+	if resp == nil {
+		return Check{Name: "rate limit", Status: StatusSkip, Message: "no response to inspect"}
+	}
+
+	limit, errL := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	remaining, errR := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if errL != nil || errR != nil || limit <= 0 {
+		if resp.Header.Get("X-RateLimit-NearLimit") == "true" {
+			return Check{Name: "rate limit", Status: StatusWarn, Message: "server reports the rate limit is nearly reached",
+				Hint: "Reduce watch frequency or enable the daemon cache"}
+		}
+		return Check{Name: "rate limit", Status: StatusPass, Message: "no rate limit reported"}
+	}
+
+	details := map[string]any{"limit": limit, "remaining": remaining}
+	msg := fmt.Sprintf("%d of %d requests left", remaining, limit)
+	if float64(remaining) < float64(limit)*rateLimitWarn {
+		return Check{Name: "rate limit", Status: StatusWarn, Message: msg,
+			Hint: "Increase watch intervals or use cached reads", Details: details}
+	}
+	return Check{Name: "rate limit", Status: StatusPass, Message: msg, Details: details}
+}
+
+// checkPermissions verifies what the user may do in the default project
+// through version api of the REST API.
+func (d *Doctor) checkPermissions(ctx context.Context, api string, user *jira.CurrentUser) Check {
+	project := d.config.Defaults.Project
+	if project == "" {
+		return Check{Name: "permissions", Status: StatusSkip, Message: "no default project configured",
+			Hint: "Set one with 'jirar config set defaults.project KEY'"}
+	}
+
+	var result struct {
+		Permissions map[string]jira.Permission `json:"permissions"`
+	}
+	query := url.Values{"projectKey": {project}, "permissions": {strings.Join(projectPermissions, ",")}}
+	_, err := d.getJSON(ctx, "/rest/api/"+api+"/mypermissions", query, &result)
+	perms := result.Permissions
+	if err != nil {
+		return Check{Name: "permissions", Status: StatusFail, Message: err.Error(),
+			Hint: fmt.Sprintf("Check that project %s exists", project)}
+	}
+
+	var missing []string
+	for _, key := range projectPermissions {
+		if !perms[key].HavePermission {
+			missing = append(missing, key)
+		}
+	}
+	details := map[string]any{"project": project, "missing": missing}
+
+	switch {
+	case !perms["BROWSE_PROJECTS"].HavePermission:
+		return Check{Name: "permissions", Status: StatusFail, Message: fmt.Sprintf("%s cannot browse %s", user.DisplayName, project),
+			Hint: "Ask a Jira administrator for access, or fix defaults.project", Details: details}
+	case len(missing) > 0:
+		return Check{Name: "permissions", Status: StatusWarn, Message: fmt.Sprintf("missing in %s: %s", project, strings.Join(missing, ", ")),
+			Hint: "Some commands will fail for this project", Details: details}
+	}
+	return Check{Name: "permissions", Status: StatusPass, Message: fmt.Sprintf("all checked permissions granted in %s", project), Details: details}
+}
+
+// getJSON decodes the response to a GET of path into out. It returns the
+// status code, zero if Jira could not be reached.
+func (d *Doctor) getJSON(ctx context.Context, path string, query url.Values, out any) (int, error) {
+	resp, err := d.client.Do(ctx, &jira.RawRequest{Path: path, Query: query})
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("GET %s failed with status %d", path, resp.StatusCode)
+	}
+	if err := json.Unmarshal(resp.Body, out); err != nil {
+		return resp.StatusCode, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// hasCheck reports whether a check with name was recorded.
+func hasCheck(r *Report, name string) bool {
+	for _, c := range r.Checks {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+// Package filelock serializes jirar processes updating the same file,
+// such as the daemon, watchers and one-off commands.
+package filelock
+
+import "os"
+
+// Lock takes an exclusive lock on path, creating it, waiting for other
+// holders, and returns what releases it.
+func Lock(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
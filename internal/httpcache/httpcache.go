@@ -0,0 +1,470 @@
+// Package httpcache is an RFC 7234-style caching http.RoundTripper for
+// Jira metadata that barely changes, such as fields and statuses. GET
+// responses of these endpoint classes are kept on disk for the TTL of
+// their class; stale ones are revalidated with If-None-Match or
+// If-Modified-Since when Jira sent an ETag or Last-Modified, and served
+// when Jira cannot be reached.
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"jirar/internal/filelock"
+)
+
+// statsFile holds, in the cache directory, how each class served.
+const statsFile = "stats.json"
+
+// Header tells how a response was served: hit, revalidated, stale or miss.
+const Header = "X-Jirar-Cache"
+
+// Names of the endpoint classes.
+const (
+	ClassFields     = "fields"
+	ClassStatuses   = "statuses"
+	ClassIssueTypes = "issuetypes"
+	ClassProjects   = "projects"
+	ClassCreateMeta = "createmeta"
+)
+
+// patterns match the paths of the endpoint classes.
+var patterns = []struct {
+	class   string
+	pattern *regexp.Regexp
+}{
+	{ClassFields, regexp.MustCompile(`^/rest/api/[23]/field$`)},
+	{ClassStatuses, regexp.MustCompile(`^/rest/api/[23]/(status|statuscategory)(/[^/]+)?$|^/rest/api/[23]/project/[^/]+/statuses$`)},
+	{ClassIssueTypes, regexp.MustCompile(`^/rest/api/[23]/issuetype(/.*)?$`)},
+	{ClassProjects, regexp.MustCompile(`^/rest/api/[23]/project(/search|/[^/]+)?$`)},
+	{ClassCreateMeta, regexp.MustCompile(`^/rest/api/[23]/issue/createmeta(/.*)?$`)},
+}
+
+// Classes are the names of the endpoint classes.
+var Classes = func() []string {
+	names := make([]string, len(patterns))
+	for i, p := range patterns {
+		names[i] = p.class
+	}
+	return names
+}()
+
+// Class returns the endpoint class of a request path; empty if it is not
+// cached.
+func Class(path string) string {
+	for _, p := range patterns {
+		if p.pattern.MatchString(path) {
+			return p.class
+		}
+	}
+	return ""
+}
+
+// Transport caches the responses of a base transport.
+type Transport struct {
+	// Base makes the requests; nil is http.DefaultTransport
+	Base http.RoundTripper
+	// Dir holds a file per cached response
+	Dir string
+	// TTL is how long responses of each class are fresh; classes without
+	// one are not cached
+	TTL map[string]time.Duration
+	// Revalidate asks Jira about every cached response, conditionally
+	// when possible, as if requests had Cache-Control: no-cache
+	Revalidate bool
+	Logger     *logrus.Logger
+
+	mu sync.Mutex
+	// served counts how responses were served since the last Flush
+	served map[string]counts
+}
+
+// counts tell how the responses of a class were served.
+type counts struct {
+	Hits        int `json:"hits"`
+	Revalidated int `json:"revalidated"`
+	Fetched     int `json:"fetched"`
+}
+
+// entry is a cached response.
+type entry struct {
+	URL    string      `json:"url"`
+	Class  string      `json:"class"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+	// Stored is when the response was fetched or last revalidated
+	Stored time.Time `json:"stored"`
+}
+
+// DefaultDir returns the cache of a profile under the user cache
+// directory.
+func DefaultDir(profile string) string {
+	if profile == "" {
+		profile = "default"
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = filepath.Join(os.TempDir(), "jirar-cache")
+	}
+	return filepath.Join(dir, "jirar", profile, "http")
+}
+
+// RoundTrip implements http.RoundTripper. Jira marks its REST responses
+// no-store, so the TTL of the class decides freshness; requests with
+// Cache-Control: no-store bypass the cache and no-cache revalidate.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	class := Class(req.URL.Path)
+	ttl := t.TTL[class]
+	control := req.Header.Get("Cache-Control")
+	if class == "" || ttl <= 0 || req.Method != http.MethodGet || strings.Contains(control, "no-store") {
+		return base.RoundTrip(req)
+	}
+
+	path := filepath.Join(t.Dir, key(req)+".json")
+	cached := t.load(path)
+	now := time.Now()
+	revalidate := t.Revalidate || strings.Contains(control, "no-cache")
+	if cached != nil && !revalidate && now.Sub(cached.Stored) < ttl {
+		t.count(class, func(c *counts) { c.Hits++ })
+		return cached.response(req, "hit", now), nil
+	}
+
+	out := req
+	if cached != nil {
+		out = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			out.Header.Set("If-None-Match", etag)
+		}
+		if modified := cached.Header.Get("Last-Modified"); modified != "" {
+			out.Header.Set("If-Modified-Since", modified)
+		}
+	}
+	resp, err := base.RoundTrip(out)
+	if cached != nil && (err != nil || resp.StatusCode >= 500) {
+		// Stale is better than nothing, as in stale-if-error
+		if err == nil {
+			resp.Body.Close()
+		}
+		t.Logger.WithFields(logrus.Fields{"url": cached.URL, "error": err}).Debug("Serving a stale cached response")
+		stale := cached.response(req, "stale", now)
+		stale.Header.Set("Warning", `111 - "Revalidation Failed"`)
+		return stale, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		for _, name := range []string{"ETag", "Last-Modified", "Date"} {
+			if v := resp.Header.Get(name); v != "" {
+				cached.Header.Set(name, v)
+			}
+		}
+		cached.Stored = now
+		t.save(path, cached)
+		t.count(class, func(c *counts) { c.Revalidated++ })
+		return cached.response(req, "revalidated", now), nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	fresh := &entry{URL: req.URL.String(), Class: class, Header: resp.Header.Clone(), Body: body, Stored: now}
+	fresh.Header.Del("Set-Cookie")
+	t.save(path, fresh)
+	t.count(class, func(c *counts) { c.Fetched++ })
+	resp.Header.Set(Header, "miss")
+	return resp, nil
+}
+
+// count applies f to the counts of class, kept in memory until Flush.
+func (t *Transport) count(class string, f func(*counts)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.served == nil {
+		t.served = map[string]counts{}
+	}
+	c := t.served[class]
+	f(&c)
+	t.served[class] = c
+}
+
+// Flush adds how responses were served since the last Flush to the stats
+// of the cache, locking them against other jirar doing the same.
+func (t *Transport) Flush() error {
+	t.mu.Lock()
+	served := t.served
+	t.served = nil
+	t.mu.Unlock()
+	if len(served) == 0 {
+		return nil
+	}
+	return updateStats(t.Dir, func(stats map[string]counts) {
+		for class, c := range served {
+			total := stats[class]
+			total.Hits += c.Hits
+			total.Revalidated += c.Revalidated
+			total.Fetched += c.Fetched
+			stats[class] = total
+		}
+	})
+}
+
+// updateStats applies f to the stats of the cache in dir under a lock.
+func updateStats(dir string, f func(map[string]counts)) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create cache: %w", err)
+	}
+	path := filepath.Join(dir, statsFile)
+	unlock, err := filelock.Lock(path + ".lock")
+	if err != nil {
+		return fmt.Errorf("lock cache stats: %w", err)
+	}
+	defer unlock()
+
+	stats, err := loadStats(dir)
+	if err != nil {
+		return err
+	}
+	f(stats)
+	if err := write(path, stats); err != nil {
+		return fmt.Errorf("write cache stats: %w", err)
+	}
+	return nil
+}
+
+// loadStats reads the stats of the cache in dir; unreadable ones start
+// over, as they only inform.
+func loadStats(dir string) (map[string]counts, error) {
+	stats := map[string]counts{}
+	data, err := os.ReadFile(filepath.Join(dir, statsFile))
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read cache stats: %w", err)
+	}
+	if json.Unmarshal(data, &stats) != nil {
+		return map[string]counts{}, nil
+	}
+	return stats, nil
+}
+
+// response returns the cached response to req.
+func (e *entry) response(req *http.Request, how string, now time.Time) *http.Response {
+	header := e.Header.Clone()
+	header.Set(Header, how)
+	header.Set("Age", strconv.Itoa(int(now.Sub(e.Stored).Seconds())))
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// key identifies a request by URL and credentials, so users of a profile
+// do not see each other's responses.
+func key(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.URL.String() + "\n" + req.Header.Get("Authorization")))
+	return hex.EncodeToString(sum[:])
+}
+
+// load reads a cached response; nil if there is none that can be read.
+func (t *Transport) load(path string) *entry {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		t.Logger.WithError(err).WithField("path", path).Debug("Ignoring an unreadable cached response")
+		return nil
+	}
+	return &e
+}
+
+// save writes a cached response; failures only cost a later request.
+func (t *Transport) save(path string, e *entry) {
+	if err := write(path, e); err != nil {
+		t.Logger.WithError(err).WithField("url", e.URL).Debug("Could not cache the response")
+	}
+}
+
+// write replaces a file with v, so readers never see it half written.
+func write(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".entry-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ClassStats summarizes the cached responses of a class.
+type ClassStats struct {
+	Class string `json:"class"`
+	// TTL is how long responses are fresh, e.g. 24h0m0s; 0s if not cached
+	TTL     string `json:"ttl"`
+	Entries int    `json:"entries"`
+	// Fresh counts the entries served without asking Jira
+	Fresh       int   `json:"fresh"`
+	Size        int64 `json:"size"`
+	Hits        int   `json:"hits"`
+	Revalidated int   `json:"revalidated"`
+	Fetched     int   `json:"fetched"`
+}
+
+// Stats summarizes the cache in dir by class, with the TTLs given.
+func Stats(dir string, ttl map[string]time.Duration) ([]ClassStats, error) {
+	stats := make([]ClassStats, len(Classes))
+	index := map[string]*ClassStats{}
+	for i, class := range Classes {
+		stats[i] = ClassStats{Class: class, TTL: ttl[class].String()}
+		index[class] = &stats[i]
+	}
+	served, err := loadStats(dir)
+	if err != nil {
+		return nil, err
+	}
+	for class, c := range served {
+		if s, ok := index[class]; ok {
+			s.Hits, s.Revalidated, s.Fetched = c.Hits, c.Revalidated, c.Fetched
+		}
+	}
+	now := time.Now()
+	err = forEach(dir, func(path string, size int64, e *entry) error {
+		if e == nil {
+			return nil
+		}
+		s, ok := index[e.Class]
+		if !ok {
+			return nil
+		}
+		s.Entries++
+		s.Size += size
+		if now.Sub(e.Stored) < ttl[e.Class] {
+			s.Fresh++
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// Clear deletes the cached responses in dir of the classes given, or all,
+// with their stats, and returns how many were deleted.
+func Clear(dir string, classes ...string) (int, error) {
+	for _, class := range classes {
+		if !slices.Contains(Classes, class) {
+			return 0, fmt.Errorf("unknown cache class %q (one of %s)", class, strings.Join(Classes, ", "))
+		}
+	}
+	deleted := 0
+	err := forEach(dir, func(path string, _ int64, e *entry) error {
+		if len(classes) > 0 && (e == nil || !slices.Contains(classes, e.Class)) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		deleted++
+		return nil
+	})
+	if err != nil {
+		return deleted, err
+	}
+	if len(classes) == 0 {
+		if err := os.Remove(filepath.Join(dir, statsFile)); err != nil && !os.IsNotExist(err) {
+			return deleted, err
+		}
+		return deleted, nil
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return deleted, nil
+	}
+	return deleted, updateStats(dir, func(stats map[string]counts) {
+		for _, class := range classes {
+			delete(stats, class)
+		}
+	})
+}
+
+// forEach calls f with the cached responses in dir; e is nil for those
+// that cannot be read.
+func forEach(dir string, f func(path string, size int64, e *entry) error) error {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read cache: %w", err)
+	}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" || file.Name() == statsFile {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read cache: %w", err)
+		}
+		var e *entry
+		if json.Unmarshal(data, &e) != nil {
+			e = nil
+		}
+		if err := f(path, info.Size(), e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ADF is a node of an Atlassian Document Format document, the rich text
+// format of descriptions and comments in REST API v3. The root node has
+// type "doc".
+type ADF struct {
+	Type    string         `json:"type"`
+	Version int            `json:"version,omitempty"`
+	Text    string         `json:"text,omitempty"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+	Marks   []ADFMark      `json:"marks,omitempty"`
+	Content []ADF          `json:"content,omitempty"`
+}
+
+// ADFMark is a text decoration such as strong, code or link.
+type ADFMark struct {
+	Type  string         `json:"type"`
+	Attrs map[string]any `json:"attrs,omitempty"`
+}
+
+// NewADF builds a document from plain text. Blank lines separate
+// paragraphs and single newlines become hard breaks.
+func NewADF(text string) *ADF {
+	doc := &ADF{Type: "doc", Version: 1}
+	for _, para := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		p := ADF{Type: "paragraph"}
+		for i, line := range strings.Split(para, "\n") {
+			if i > 0 {
+				p.Content = append(p.Content, ADF{Type: "hardBreak"})
+			}
+			if line != "" {
+				p.Content = append(p.Content, ADF{Type: "text", Text: line})
+			}
+		}
+		doc.Content = append(doc.Content, p)
+	}
+	return doc
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Jira Server returns plain
+// strings where Cloud returns documents; strings are converted.
+func (n *ADF) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		*n = *NewADF(text)
+		return nil
+	}
+
+	type plain ADF
+	return json.Unmarshal(data, (*plain)(n))
+}
+
+// PlainText returns the text of the document without formatting.
+func (n *ADF) PlainText() string {
+	if n == nil {
+		return ""
+	}
+	var b strings.Builder
+	n.plain(&b)
+	return strings.TrimSpace(b.String())
+}
+
+// plain writes the text of n and its children.
+func (n *ADF) plain(b *strings.Builder) {
+	switch n.Type {
+	case "text":
+		b.WriteString(n.Text)
+	case "hardBreak":
+		b.WriteString("\n")
+	case "mention", "emoji", "status", "date", "inlineCard":
+		b.WriteString(n.inlineText())
+	}
+	for i := range n.Content {
+		n.Content[i].plain(b)
+	}
+	switch n.Type {
+	case "paragraph", "heading", "codeBlock", "listItem", "blockquote", "panel", "rule", "tableRow":
+		b.WriteString("\n")
+	}
+}
+
+// Mentions reports whether the document mentions the account, either as
+// an ADF mention node or as Server wiki markup such as [~accountid:ID].
+func (n *ADF) Mentions(accountID string) bool {
+	if n == nil || accountID == "" {
+		return false
+	}
+	if n.Type == "mention" {
+		if id, _ := n.Attrs["id"].(string); id == accountID {
+			return true
+		}
+	}
+	if n.Type == "text" && (strings.Contains(n.Text, "[~accountid:"+accountID+"]") || strings.Contains(n.Text, "[~"+accountID+"]")) {
+		return true
+	}
+	for i := range n.Content {
+		if n.Content[i].Mentions(accountID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Markdown renders the document as Markdown.
+func (n *ADF) Markdown() string {
+	if n == nil {
+		return ""
+	}
+	var b strings.Builder
+	for i := range n.Content {
+		n.Content[i].block(&b, "")
+	}
+	return strings.TrimSpace(b.String()) + "\n"
+}
+
+// block writes a block node; prefix is the indentation of nested lists
+// and quotes.
+func (n *ADF) block(b *strings.Builder, prefix string) {
+	switch n.Type {
+	case "paragraph":
+		b.WriteString(prefix + n.inline() + "\n\n")
+	case "heading":
+		level, _ := n.Attrs["level"].(float64)
+		b.WriteString(prefix + strings.Repeat("#", max(int(level), 1)) + " " + n.inline() + "\n\n")
+	case "codeBlock":
+		lang, _ := n.Attrs["language"].(string)
+		b.WriteString(prefix + "```" + lang + "\n" + n.inline() + "\n" + prefix + "```\n\n")
+	case "rule":
+		b.WriteString(prefix + "---\n\n")
+	case "blockquote", "panel":
+		for i := range n.Content {
+			n.Content[i].block(b, prefix+"> ")
+		}
+	case "bulletList", "orderedList":
+		for i := range n.Content {
+			marker := "- "
+			if n.Type == "orderedList" {
+				marker = fmt.Sprintf("%d. ", i+1)
+			}
+			n.Content[i].listItem(b, prefix, marker)
+		}
+		b.WriteString("\n")
+	case "table":
+		n.table(b, prefix)
+	case "mediaSingle", "mediaGroup":
+		b.WriteString(prefix + "[attachment]\n\n")
+	default:
+		if len(n.Content) > 0 {
+			for i := range n.Content {
+				n.Content[i].block(b, prefix)
+			}
+		} else if text := n.inline(); text != "" {
+			b.WriteString(prefix + text + "\n\n")
+		}
+	}
+}
+
+// listItem writes a list item whose first paragraph follows the marker.
+func (n *ADF) listItem(b *strings.Builder, prefix, marker string) {
+	indent := prefix + strings.Repeat(" ", len(marker))
+	for i := range n.Content {
+		child := &n.Content[i]
+		switch {
+		case i == 0 && child.Type == "paragraph":
+			b.WriteString(prefix + marker + child.inline() + "\n")
+		case child.Type == "bulletList" || child.Type == "orderedList":
+			var nested strings.Builder
+			child.block(&nested, indent)
+			b.WriteString(strings.TrimSuffix(nested.String(), "\n"))
+		default:
+			var nested strings.Builder
+			child.block(&nested, indent)
+			b.WriteString(strings.TrimRight(nested.String(), "\n") + "\n")
+		}
+	}
+}
+
+// table writes a table as a Markdown table with the first row as header.
+func (n *ADF) table(b *strings.Builder, prefix string) {
+	for r := range n.Content {
+		var cells []string
+		for c := range n.Content[r].Content {
+			cell := &n.Content[r].Content[c]
+			var text []string
+			for i := range cell.Content {
+				text = append(text, cell.Content[i].inline())
+			}
+			cells = append(cells, strings.ReplaceAll(strings.Join(text, " "), "|", `\|`))
+		}
+		b.WriteString(prefix + "| " + strings.Join(cells, " | ") + " |\n")
+		if r == 0 {
+			b.WriteString(prefix + "|" + strings.Repeat("---|", len(cells)) + "\n")
+		}
+	}
+	b.WriteString("\n")
+}
+
+// inline renders the inline content of n as Markdown.
+func (n *ADF) inline() string {
+	var b strings.Builder
+	for i := range n.Content {
+		child := &n.Content[i]
+		switch child.Type {
+		case "text":
+			b.WriteString(child.markedText())
+		case "hardBreak":
+			b.WriteString("  \n")
+		default:
+			if text := child.inlineText(); text != "" {
+				b.WriteString(text)
+			} else {
+				b.WriteString(child.inline())
+			}
+		}
+	}
+	return b.String()
+}
+
+// inlineText renders inline nodes that carry their text in attributes.
+func (n *ADF) inlineText() string {
+	attr := func(name string) string {
+		s, _ := n.Attrs[name].(string)
+		return s
+	}
+	switch n.Type {
+	case "mention":
+		if text := attr("text"); text != "" {
+			return text
+		}
+		return "@" + attr("id")
+	case "emoji":
+		if text := attr("text"); text != "" {
+			return text
+		}
+		return attr("shortName")
+	case "status":
+		return "[" + attr("text") + "]"
+	case "date":
+		return attr("timestamp")
+	case "inlineCard":
+		return attr("url")
+	}
+	return ""
+}
+
+// markedText applies the marks of a text node as Markdown.
+func (n *ADF) markedText() string {
+	text := n.Text
+	for _, m := range n.Marks {
+		switch m.Type {
+		case "strong":
+			text = "**" + text + "**"
+		case "em":
+			text = "_" + text + "_"
+		case "code":
+			text = "`" + text + "`"
+		case "strike":
+			text = "~~" + text + "~~"
+		case "link":
+			if href, ok := m.Attrs["href"].(string); ok {
+				text = "[" + text + "](" + href + ")"
+			}
+		}
+	}
+	return text
+}
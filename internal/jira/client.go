@@ -3,6 +3,8 @@ package jira
 
 import (
 	"context"
+	"net/http"
+	"net/url"
 )
 
 // Client defines the interface for Jira API operations.
@@ -14,12 +16,75 @@ type Client interface {
 	GetIssue(ctx context.Context, key string) (*Issue, error)
 
 	// GetCurrentUser retrieves information about the authenticated user
-	GetCurrentUser(ctx context.Context) (*User, error)
+	GetCurrentUser(ctx context.Context) (*CurrentUser, error)
 
 	// ValidateCredentials tests if the current credentials are valid
 	ValidateCredentials(ctx context.Context) error
+
+	// ListProjects retrieves the projects visible to the current user
+	ListProjects(ctx context.Context) ([]Project, error)
+
+	// ListBoards retrieves the agile boards, optionally limited to a project
+	ListBoards(ctx context.Context, projectKey string) ([]Board, error)
+
+	// GetBoard retrieves an agile board by ID
+	GetBoard(ctx context.Context, id int) (*Board, error)
+
+	// GetBoardConfiguration retrieves the column and estimation configuration of a board
+	GetBoardConfiguration(ctx context.Context, id int) (*BoardConfiguration, error)
+
+	// GetBoardIssues retrieves the issues on a board, optionally narrowed by JQL
+	GetBoardIssues(ctx context.Context, id int, jql string, opts ...SearchOption) (*SearchResult, error)
+
+	// GetServerInfo retrieves the server version and deployment type
+	GetServerInfo(ctx context.Context) (*ServerInfo, error)
+
+	// GetMyPermissions checks the current user's permissions, optionally in a project
+	GetMyPermissions(ctx context.Context, projectKey string, permissions ...string) (map[string]Permission, error)
+
+	// GetComments retrieves all comments on an issue, oldest first
+	GetComments(ctx context.Context, key string) ([]Comment, error)
+
+	// AddComment adds a comment to an issue
+	AddComment(ctx context.Context, key string, body *ADF) (*Comment, error)
+
+	// GetTransitions retrieves the transitions available on an issue
+	GetTransitions(ctx context.Context, key string) ([]Transition, error)
+
+	// TransitionIssue moves an issue through the transition with the given ID
+	TransitionIssue(ctx context.Context, key, transitionID string) error
+
+	// AssignIssue assigns an issue to an account, or unassigns it when accountID is empty
+	AssignIssue(ctx context.Context, key, accountID string) error
+
+	// FindAssignableUsers searches the users that can be assigned to an issue
+	FindAssignableUsers(ctx context.Context, key, query string) ([]User, error)
+
+	// Do performs an arbitrary authenticated request against the Jira API
+	Do(ctx context.Context, req *RawRequest) (*RawResponse, error)
 }
 
+// RawRequest describes an arbitrary Jira REST call.
+type RawRequest struct {
+	Method string
+	// Path is relative to the Jira base URL, e.g. /rest/api/3/field
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// RawResponse holds the unparsed result of a RawRequest.
+type RawResponse struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte
+}
+
+// DefaultSearchFields are the issue fields requested by SearchIssues.
+var DefaultSearchFields = []string{"summary", "status", "priority", "assignee", "updated", "created", "project", "issuetype"}
+
 // SearchOptions configures how search results are returned.
 type SearchOptions struct {
 	Limit   int
@@ -0,0 +1,134 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// pageItemKeys are the array fields Jira uses for paged results, in the
+// order they are looked for.
+var pageItemKeys = []string{"values", "issues", "comments", "worklogs", "histories", "users"}
+
+// Paginate performs a GET request and follows Jira pagination, merging the
+// items of every page into the first page's body. Both offset pagination
+// (startAt, maxResults, total, isLast) and token pagination (nextPageToken)
+// are supported; other responses are returned as is. It stops at the first
+// non-2xx response and returns that response.
+func Paginate(ctx context.Context, client Client, req *RawRequest) (*RawResponse, error) {
+	page := *req
+	page.Query = cloneValues(req.Query)
+
+	var (
+		first  *RawResponse
+		merged map[string]json.RawMessage
+		key    string
+		items  []json.RawMessage
+	)
+	for {
+		resp, err := client.Do(ctx, &page)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return resp, nil
+		}
+
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(resp.Body, &body); err != nil {
+			// Not a paged object, e.g. a plain array
+			if first == nil {
+				return resp, nil
+			}
+			return nil, fmt.Errorf("page %d of %s is not a JSON object", len(items), req.Path)
+		}
+
+		if first == nil {
+			first, merged = resp, body
+			key = pageItemsKey(body)
+			if key == "" {
+				return resp, nil
+			}
+		}
+
+		var pageItems []json.RawMessage
+		if err := json.Unmarshal(body[key], &pageItems); err != nil {
+			return nil, fmt.Errorf("parse %s of %s: %w", key, req.Path, err)
+		}
+		items = append(items, pageItems...)
+
+		if !nextPage(&page, body, len(pageItems)) {
+			break
+		}
+	}
+
+	// Paging fields describe the merged result only where Jira sent them;
+	// startAt stays that of the first page
+	merged[key], _ = json.Marshal(items)
+	delete(merged, "nextPageToken")
+	if _, ok := merged["isLast"]; ok {
+		merged["isLast"] = json.RawMessage("true")
+	}
+	if _, ok := merged["maxResults"]; ok {
+		merged["maxResults"] = json.RawMessage(strconv.Itoa(len(items)))
+	}
+
+	body, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("merge pages of %s: %w", req.Path, err)
+	}
+	first.Body = body
+	return first, nil
+}
+
+// pageItemsKey finds the array field holding the items of a page.
+func pageItemsKey(body map[string]json.RawMessage) string {
+	for _, key := range pageItemKeys {
+		if raw, ok := body[key]; ok && len(raw) > 0 && raw[0] == '[' {
+			return key
+		}
+	}
+	return ""
+}
+
+// nextPage updates req to fetch the page after body and reports whether
+// there is one.
+func nextPage(req *RawRequest, body map[string]json.RawMessage, n int) bool {
+	var token string
+	if json.Unmarshal(body["nextPageToken"], &token) == nil && token != "" {
+		req.Query.Set("nextPageToken", token)
+		return true
+	}
+
+	var isLast bool
+	if json.Unmarshal(body["isLast"], &isLast) == nil && isLast {
+		return false
+	}
+
+	var startAt, total int
+	if err := json.Unmarshal(body["startAt"], &startAt); err != nil || n == 0 {
+		return false
+	}
+	if json.Unmarshal(body["total"], &total) == nil && startAt+n >= total {
+		return false
+	}
+	if _, ok := body["total"]; !ok {
+		if _, ok := body["isLast"]; !ok {
+			return false
+		}
+	}
+
+	req.Query.Set("startAt", strconv.Itoa(startAt+n))
+	return true
+}
+
+// cloneValues copies query values so a request can be reused across pages.
+func cloneValues(v url.Values) url.Values {
+	out := url.Values{}
+	for key, values := range v {
+		out[key] = append([]string(nil), values...)
+	}
+	return out
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -21,15 +22,35 @@ type restClient struct {
 	logger *logrus.Logger
 }
 
+// ClientOption configures a REST client.
+type ClientOption func(*resty.Client)
+
+// WithTransport sends the requests of the client through rt, such as a
+// cache.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(client *resty.Client) {
+		client.SetTransport(rt)
+	}
+}
+
 // NewClient creates a new Jira REST client.
-func NewClient(cfg *config.JiraConfig, logger *logrus.Logger) Client {
+func NewClient(cfg *config.JiraConfig, logger *logrus.Logger, opts ...ClientOption) Client {
 	client := resty.New().
+		SetLogger(logger).
 		SetTimeout(30 * time.Second).
 		SetRetryCount(3).
 		SetRetryWaitTime(1 * time.Second).
 		AddRetryCondition(func(r *resty.Response, err error) bool {
+			// A write may have reached Jira before the error, so only
+			// reads are retried
+			if r == nil || r.Request == nil || !idempotent(r.Request.Method) {
+				return false
+			}
 			return r.StatusCode() >= 500 || err != nil
 		})
+	for _, opt := range opts {
+		opt(client)
+	}
 
 	return &restClient{
 		client: client,
@@ -38,12 +59,17 @@ func NewClient(cfg *config.JiraConfig, logger *logrus.Logger) Client {
 	}
 }
 
+// idempotent reports whether a request with method can be safely retried.
+func idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
 // SearchIssues implements Client interface.
 func (c *restClient) SearchIssues(ctx context.Context, jql string, opts ...SearchOption) (*SearchResult, error) {
 	options := &SearchOptions{
 		Limit:   50,
 		StartAt: 0,
-		Fields:  []string{"summary", "status", "priority", "assignee", "updated", "created", "project"},
+		Fields:  append([]string(nil), DefaultSearchFields...),
 	}
 
 	for _, opt := range opts {
@@ -52,15 +78,19 @@ func (c *restClient) SearchIssues(ctx context.Context, jql string, opts ...Searc
 
 	url := fmt.Sprintf("%s/rest/api/3/search", c.config.Domain)
 
-	resp, err := c.client.R().
+	req := c.client.R().
 		SetContext(ctx).
 		SetBasicAuth(c.config.Email, c.config.Token).
 		SetQueryParam("jql", jql).
-		SetQueryParam("fields", "summary,status,priority,assignee,updated,created,project").
+		SetQueryParam("fields", strings.Join(options.Fields, ",")).
 		SetQueryParam("maxResults", fmt.Sprintf("%d", options.Limit)).
 		SetQueryParam("startAt", fmt.Sprintf("%d", options.StartAt)).
-		SetHeader("Accept", "application/json").
-		Get(url)
+		SetHeader("Accept", "application/json")
+	if len(options.Expand) > 0 {
+		req.SetQueryParam("expand", strings.Join(options.Expand, ","))
+	}
+
+	resp, err := req.Get(url)
 
 	if err != nil {
 		c.logger.WithError(err).Error("Failed to search issues")
@@ -93,7 +123,7 @@ func (c *restClient) GetIssue(ctx context.Context, key string) (*Issue, error) {
 	resp, err := c.client.R().
 		SetContext(ctx).
 		SetBasicAuth(c.config.Email, c.config.Token).
-		SetQueryParam("fields", "summary,status,priority,assignee,updated,created,project,description,reporter").
+		SetQueryParam("fields", "summary,status,priority,assignee,updated,created,project,description,reporter,issuetype").
 		SetHeader("Accept", "application/json").
 		Get(url)
 
@@ -121,7 +151,7 @@ func (c *restClient) GetIssue(ctx context.Context, key string) (*Issue, error) {
 }
 
 // GetCurrentUser implements Client interface.
-func (c *restClient) GetCurrentUser(ctx context.Context) (*User, error) {
+func (c *restClient) GetCurrentUser(ctx context.Context) (*CurrentUser, error) {
 	url := fmt.Sprintf("%s/rest/api/3/myself", c.config.Domain)
 
 	resp, err := c.client.R().
@@ -140,7 +170,7 @@ func (c *restClient) GetCurrentUser(ctx context.Context) (*User, error) {
 		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode())
 	}
 
-	var user User
+	var user CurrentUser
 	if err := json.Unmarshal(resp.Body(), &user); err != nil {
 		c.logger.WithError(err).Error("Failed to parse user response")
 		return nil, fmt.Errorf("parse response failed: %w", err)
@@ -155,3 +185,322 @@ func (c *restClient) ValidateCredentials(ctx context.Context) error {
 	_, err := c.GetCurrentUser(ctx)
 	return err
 }
+
+// ListProjects implements Client interface.
+func (c *restClient) ListProjects(ctx context.Context) ([]Project, error) {
+	var projects []Project
+	if err := c.getJSON(ctx, "/rest/api/3/project", nil, &projects); err != nil {
+		return nil, fmt.Errorf("list projects failed: %w", err)
+	}
+
+	c.logger.WithField("count", len(projects)).Debug("Projects retrieved")
+	return projects, nil
+}
+
+// ListBoards implements Client interface.
+func (c *restClient) ListBoards(ctx context.Context, projectKey string) ([]Board, error) {
+	params := map[string]string{"maxResults": "50"}
+	if projectKey != "" {
+		params["projectKeyOrId"] = projectKey
+	}
+
+	var boards []Board
+	for {
+		params["startAt"] = fmt.Sprintf("%d", len(boards))
+
+		var page BoardList
+		if err := c.getJSON(ctx, "/rest/agile/1.0/board", params, &page); err != nil {
+			return nil, fmt.Errorf("list boards failed: %w", err)
+		}
+
+		boards = append(boards, page.Values...)
+		if page.IsLast || len(page.Values) == 0 {
+			break
+		}
+	}
+
+	c.logger.WithField("count", len(boards)).Debug("Boards retrieved")
+	return boards, nil
+}
+
+// GetBoard implements Client interface.
+func (c *restClient) GetBoard(ctx context.Context, id int) (*Board, error) {
+	var board Board
+	if err := c.getJSON(ctx, fmt.Sprintf("/rest/agile/1.0/board/%d", id), nil, &board); err != nil {
+		return nil, fmt.Errorf("get board failed: %w", err)
+	}
+	return &board, nil
+}
+
+// GetBoardConfiguration implements Client interface.
+func (c *restClient) GetBoardConfiguration(ctx context.Context, id int) (*BoardConfiguration, error) {
+	var cfg BoardConfiguration
+	if err := c.getJSON(ctx, fmt.Sprintf("/rest/agile/1.0/board/%d/configuration", id), nil, &cfg); err != nil {
+		return nil, fmt.Errorf("get board configuration failed: %w", err)
+	}
+	return &cfg, nil
+}
+
+// GetBoardIssues implements Client interface. Pages are fetched until the
+// limit is reached.
+func (c *restClient) GetBoardIssues(ctx context.Context, id int, jql string, opts ...SearchOption) (*SearchResult, error) {
+	options := &SearchOptions{
+		Limit:  50,
+		Fields: append([]string(nil), DefaultSearchFields...),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	params := map[string]string{"fields": strings.Join(options.Fields, ",")}
+	if jql != "" {
+		params["jql"] = jql
+	}
+
+	result := &SearchResult{StartAt: options.StartAt, MaxResults: options.Limit}
+	for len(result.Issues) < options.Limit {
+		params["startAt"] = fmt.Sprintf("%d", options.StartAt+len(result.Issues))
+		params["maxResults"] = fmt.Sprintf("%d", options.Limit-len(result.Issues))
+
+		var page SearchResult
+		if err := c.getJSON(ctx, fmt.Sprintf("/rest/agile/1.0/board/%d/issue", id), params, &page); err != nil {
+			return nil, fmt.Errorf("get board issues failed: %w", err)
+		}
+
+		result.Total = page.Total
+		result.Issues = append(result.Issues, page.Issues...)
+		if len(page.Issues) == 0 || options.StartAt+len(result.Issues) >= page.Total {
+			break
+		}
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"board":    id,
+		"total":    result.Total,
+		"returned": len(result.Issues),
+	}).Debug("Board issues retrieved")
+	return result, nil
+}
+
+// GetServerInfo implements Client interface.
+func (c *restClient) GetServerInfo(ctx context.Context) (*ServerInfo, error) {
+	var info ServerInfo
+	if err := c.getJSON(ctx, "/rest/api/3/serverInfo", nil, &info); err != nil {
+		return nil, fmt.Errorf("get server info failed: %w", err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"version":    info.Version,
+		"deployment": info.DeploymentType,
+	}).Debug("Server info retrieved")
+	return &info, nil
+}
+
+// GetMyPermissions implements Client interface.
+func (c *restClient) GetMyPermissions(ctx context.Context, projectKey string, permissions ...string) (map[string]Permission, error) {
+	params := map[string]string{"permissions": strings.Join(permissions, ",")}
+	if projectKey != "" {
+		params["projectKey"] = projectKey
+	}
+
+	var result struct {
+		Permissions map[string]Permission `json:"permissions"`
+	}
+	if err := c.getJSON(ctx, "/rest/api/3/mypermissions", params, &result); err != nil {
+		return nil, fmt.Errorf("get permissions failed: %w", err)
+	}
+
+	return result.Permissions, nil
+}
+
+// GetComments implements Client interface.
+func (c *restClient) GetComments(ctx context.Context, key string) ([]Comment, error) {
+	params := map[string]string{"maxResults": "100", "orderBy": "created"}
+
+	var comments []Comment
+	for {
+		params["startAt"] = fmt.Sprintf("%d", len(comments))
+
+		var page CommentPage
+		if err := c.getJSON(ctx, "/rest/api/3/issue/"+key+"/comment", params, &page); err != nil {
+			return nil, fmt.Errorf("get comments failed: %w", err)
+		}
+
+		comments = append(comments, page.Comments...)
+		if len(page.Comments) == 0 || len(comments) >= page.Total {
+			break
+		}
+	}
+
+	c.logger.WithFields(logrus.Fields{"key": key, "count": len(comments)}).Debug("Comments retrieved")
+	return comments, nil
+}
+
+// AddComment implements Client interface.
+func (c *restClient) AddComment(ctx context.Context, key string, body *ADF) (*Comment, error) {
+	var comment Comment
+	payload := map[string]any{"body": body}
+	if err := c.sendJSON(ctx, http.MethodPost, "/rest/api/3/issue/"+key+"/comment", payload, &comment); err != nil {
+		return nil, fmt.Errorf("add comment failed: %w", err)
+	}
+
+	c.logger.WithFields(logrus.Fields{"key": key, "id": comment.ID}).Debug("Comment added")
+	return &comment, nil
+}
+
+// GetTransitions implements Client interface.
+func (c *restClient) GetTransitions(ctx context.Context, key string) ([]Transition, error) {
+	var result struct {
+		Transitions []Transition `json:"transitions"`
+	}
+	if err := c.getJSON(ctx, "/rest/api/3/issue/"+key+"/transitions", nil, &result); err != nil {
+		return nil, fmt.Errorf("get transitions failed: %w", err)
+	}
+	return result.Transitions, nil
+}
+
+// TransitionIssue implements Client interface.
+func (c *restClient) TransitionIssue(ctx context.Context, key, transitionID string) error {
+	payload := map[string]any{"transition": map[string]string{"id": transitionID}}
+	if err := c.sendJSON(ctx, http.MethodPost, "/rest/api/3/issue/"+key+"/transitions", payload, nil); err != nil {
+		return fmt.Errorf("transition issue failed: %w", err)
+	}
+
+	c.logger.WithFields(logrus.Fields{"key": key, "transition": transitionID}).Debug("Issue transitioned")
+	return nil
+}
+
+// AssignIssue implements Client interface.
+func (c *restClient) AssignIssue(ctx context.Context, key, accountID string) error {
+	var id any
+	if accountID != "" {
+		id = accountID
+	}
+	payload := map[string]any{"accountId": id}
+	if err := c.sendJSON(ctx, http.MethodPut, "/rest/api/3/issue/"+key+"/assignee", payload, nil); err != nil {
+		return fmt.Errorf("assign issue failed: %w", err)
+	}
+
+	c.logger.WithFields(logrus.Fields{"key": key, "assignee": accountID}).Debug("Issue assigned")
+	return nil
+}
+
+// FindAssignableUsers implements Client interface.
+func (c *restClient) FindAssignableUsers(ctx context.Context, key, query string) ([]User, error) {
+	params := map[string]string{"issueKey": key, "maxResults": "50"}
+	if query != "" {
+		params["query"] = query
+	}
+
+	var users []User
+	if err := c.getJSON(ctx, "/rest/api/3/user/assignable/search", params, &users); err != nil {
+		return nil, fmt.Errorf("find assignable users failed: %w", err)
+	}
+	return users, nil
+}
+
+// Do implements Client interface. Non-2xx responses are returned, not
+// treated as errors.
+func (c *restClient) Do(ctx context.Context, req *RawRequest) (*RawResponse, error) {
+	r := c.client.R().
+		SetContext(ctx).
+		SetBasicAuth(c.config.Email, c.config.Token).
+		SetHeader("Accept", "application/json").
+		SetQueryParamsFromValues(req.Query)
+
+	for name, values := range req.Header {
+		for _, v := range values {
+			r.SetHeader(name, v)
+		}
+	}
+	if req.Body != nil {
+		r.SetHeader("Content-Type", "application/json").SetBody(req.Body)
+	}
+
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	resp, err := r.Execute(method, c.config.Domain+req.Path)
+	if err != nil {
+		c.logger.WithError(err).WithField("path", req.Path).Error("Request failed")
+		return nil, fmt.Errorf("%s %s failed: %w", method, req.Path, err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"method": method,
+		"path":   req.Path,
+		"status": resp.StatusCode(),
+	}).Debug("Request completed")
+
+	return &RawResponse{
+		StatusCode: resp.StatusCode(),
+		Status:     resp.Status(),
+		Header:     resp.Header(),
+		Body:       resp.Body(),
+	}, nil
+}
+
+// getJSON performs an authenticated GET against path and decodes the body.
+func (c *restClient) getJSON(ctx context.Context, path string, params map[string]string, out any) error {
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetBasicAuth(c.config.Email, c.config.Token).
+		SetQueryParams(params).
+		SetHeader("Accept", "application/json").
+		Get(c.config.Domain + path)
+
+	if err != nil {
+		c.logger.WithError(err).WithField("path", path).Error("Request failed")
+		return err
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		c.logger.WithFields(logrus.Fields{
+			"path":   path,
+			"status": resp.StatusCode(),
+		}).Error("Request returned unexpected status")
+		return fmt.Errorf("API request failed with status %d", resp.StatusCode())
+	}
+
+	if err := json.Unmarshal(resp.Body(), out); err != nil {
+		c.logger.WithError(err).WithField("path", path).Error("Failed to parse response")
+		return fmt.Errorf("parse response failed: %w", err)
+	}
+	return nil
+}
+
+// sendJSON performs an authenticated request with a JSON body against path
+// and decodes the response into out unless it is nil or the body is empty.
+func (c *restClient) sendJSON(ctx context.Context, method, path string, body, out any) error {
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetBasicAuth(c.config.Email, c.config.Token).
+		SetHeader("Accept", "application/json").
+		SetHeader("Content-Type", "application/json").
+		SetBody(body).
+		Execute(method, c.config.Domain+path)
+
+	if err != nil {
+		c.logger.WithError(err).WithField("path", path).Error("Request failed")
+		return err
+	}
+
+	if resp.StatusCode() < 200 || resp.StatusCode() > 299 {
+		c.logger.WithFields(logrus.Fields{
+			"path":   path,
+			"status": resp.StatusCode(),
+		}).Error("Request returned unexpected status")
+		return fmt.Errorf("API request failed with status %d", resp.StatusCode())
+	}
+
+	if out == nil || len(resp.Body()) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Body(), out); err != nil {
+		c.logger.WithError(err).WithField("path", path).Error("Failed to parse response")
+		return fmt.Errorf("parse response failed: %w", err)
+	}
+	return nil
+}
@@ -1,7 +1,15 @@
 // Package jira provides types for Jira API responses.
 package jira
 
-import "time"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
 
 // Issue represents a Jira issue/ticket.
 type Issue struct {
@@ -9,21 +17,92 @@ type Issue struct {
 	ID     string `json:"id"`
 	Self   string `json:"self"`
 	Fields Fields `json:"fields"`
+	// Transitions are only set when searched WithExpand("transitions")
+	Transitions []Transition `json:"transitions,omitempty"`
 }
 
 // Fields contains all issue fields.
 type Fields struct {
 	Summary     string    `json:"summary"`
-	Description string    `json:"description"`
+	Description *ADF      `json:"description"`
 	Status      Status    `json:"status"`
 	Priority    Priority  `json:"priority"`
 	Assignee    User      `json:"assignee"`
 	Reporter    User      `json:"reporter"`
-	Created     time.Time `json:"created"`
-	Updated     time.Time `json:"updated"`
-	DueDate     time.Time `json:"duedate"`
+	Created     Time      `json:"created"`
+	Updated     Time      `json:"updated"`
+	DueDate     Time      `json:"duedate"`
 	Project     Project   `json:"project"`
 	IssueType   IssueType `json:"issuetype"`
+	Parent      *Issue    `json:"parent"`
+
+	// Custom holds the fields without a typed counterpart, such as
+	// customfield_10016 or labels, keyed by field ID.
+	Custom map[string]json.RawMessage `json:"-"`
+}
+
+// typedFields are the JSON names of the typed Fields members.
+var typedFields = func() map[string]bool {
+	names := map[string]bool{}
+	t := reflect.TypeOf(Fields{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}()
+
+// UnmarshalJSON implements json.Unmarshaler, keeping untyped fields in Custom.
+func (f *Fields) UnmarshalJSON(data []byte) error {
+	type plain Fields
+	if err := json.Unmarshal(data, (*plain)(f)); err != nil {
+		return err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for name := range all {
+		if typedFields[name] {
+			delete(all, name)
+		}
+	}
+	f.Custom = nil
+	if len(all) > 0 {
+		f.Custom = all
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, writing Custom fields after the
+// typed ones in key order.
+func (f Fields) MarshalJSON() ([]byte, error) {
+	type plain Fields
+	data, err := json.Marshal(plain(f))
+	if err != nil || len(f.Custom) == 0 {
+		return data, err
+	}
+
+	names := make([]string, 0, len(f.Custom))
+	for name := range f.Custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	for _, name := range names {
+		key, _ := json.Marshal(name)
+		buf.WriteByte(',')
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(f.Custom[name])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
 }
 
 // Status represents issue status.
@@ -49,6 +128,7 @@ type Priority struct {
 
 // User represents a Jira user.
 type User struct {
+	AccountID   string `json:"accountId"`
 	Name        string `json:"name"`
 	DisplayName string `json:"displayName"`
 	Email       string `json:"emailAddress"`
@@ -77,11 +157,160 @@ type SearchResult struct {
 	Issues     []Issue `json:"issues"`
 }
 
+// Comment is a comment on an issue.
+type Comment struct {
+	ID      string `json:"id"`
+	Author  User   `json:"author"`
+	Body    *ADF   `json:"body"`
+	Created Time   `json:"created"`
+	Updated Time   `json:"updated"`
+}
+
+// CommentPage contains a page of issue comments.
+type CommentPage struct {
+	StartAt    int       `json:"startAt"`
+	MaxResults int       `json:"maxResults"`
+	Total      int       `json:"total"`
+	Comments   []Comment `json:"comments"`
+}
+
+// Transition is a workflow transition available on an issue.
+type Transition struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	To   Status `json:"to"`
+}
+
 // CurrentUser represents the authenticated user.
 type CurrentUser struct {
+	AccountID   string `json:"accountId"`
 	Name        string `json:"name"`
 	DisplayName string `json:"displayName"`
 	Email       string `json:"emailAddress"`
 	Active      bool   `json:"active"`
 	TimeZone    string `json:"timeZone"`
 }
+
+// Board represents a Jira agile board.
+type Board struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// BoardConfiguration describes the columns and estimation of an agile
+// board.
+type BoardConfiguration struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	ColumnConfig struct {
+		Columns []BoardColumn `json:"columns"`
+	} `json:"columnConfig"`
+	Estimation BoardEstimation `json:"estimation"`
+}
+
+// BoardColumn is a board column and the statuses mapped to it.
+type BoardColumn struct {
+	Name     string `json:"name"`
+	Statuses []struct {
+		ID string `json:"id"`
+	} `json:"statuses"`
+}
+
+// HasStatus reports whether the status with the given ID is mapped to
+// the column.
+func (c BoardColumn) HasStatus(id string) bool {
+	for _, s := range c.Statuses {
+		if s.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// BoardEstimation names the field a board estimates issues with, e.g.
+// story points in customfield_10016.
+type BoardEstimation struct {
+	Type  string `json:"type"`
+	Field struct {
+		FieldID     string `json:"fieldId"`
+		DisplayName string `json:"displayName"`
+	} `json:"field"`
+}
+
+// BoardList contains a page of agile boards.
+type BoardList struct {
+	StartAt    int     `json:"startAt"`
+	MaxResults int     `json:"maxResults"`
+	IsLast     bool    `json:"isLast"`
+	Values     []Board `json:"values"`
+}
+
+// ServerInfo describes the Jira server.
+type ServerInfo struct {
+	BaseURL        string `json:"baseUrl"`
+	Version        string `json:"version"`
+	DeploymentType string `json:"deploymentType"`
+	ServerTitle    string `json:"serverTitle"`
+	ServerTime     string `json:"serverTime"`
+}
+
+// IsCloud reports whether the server is Jira Cloud rather than Server or
+// Data Center.
+func (s *ServerInfo) IsCloud() bool {
+	return s.DeploymentType == "Cloud"
+}
+
+// Permission describes whether the current user holds a permission.
+type Permission struct {
+	Key            string `json:"key"`
+	Name           string `json:"name"`
+	HavePermission bool   `json:"havePermission"`
+}
+
+// timeLayouts are the timestamp formats Jira returns, most specific first.
+var timeLayouts = []string{
+	"2006-01-02T15:04:05.000-0700",
+	time.RFC3339Nano,
+	"2006-01-02",
+}
+
+// Time is a timestamp as returned by Jira, which uses an offset without a
+// colon (e.g. 2023-12-25T14:30:00.000+0000) and plain dates for due dates.
+// It marshals back in Jira's layout, or null when zero.
+type Time struct {
+	time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Time) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid time %s: %w", data, err)
+	}
+	if s == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	for _, layout := range timeLayouts {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid time %q", s)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.Format(timeLayouts[0]))
+}
@@ -0,0 +1,94 @@
+package watcher
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"jirar/internal/jira"
+)
+
+// EventType is the kind of change an Event reports.
+type EventType string
+
+// Event types.
+const (
+	// EventAssigned is a change of assignee, including to nobody
+	EventAssigned EventType = "assigned"
+	// EventStatus is a status transition
+	EventStatus EventType = "status"
+	// EventComment is a new comment by someone else
+	EventComment EventType = "comment"
+	// EventMention is a new comment by someone else mentioning the user;
+	// it replaces the comment event of that comment
+	EventMention EventType = "mention"
+	// EventPriority is a change of priority
+	EventPriority EventType = "priority"
+)
+
+// EventTypes lists the event types.
+var EventTypes = []EventType{EventAssigned, EventStatus, EventPriority, EventComment, EventMention}
+
+// Event is a change to a watched issue.
+type Event struct {
+	Type EventType `json:"type"`
+	Key  string    `json:"key"`
+	// Time is when the change happened: the comment's creation or the
+	// issue's update time
+	Time time.Time `json:"time"`
+	// From and To are the old and new status, assignee or priority names
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Comment is the new comment of comment and mention events
+	Comment *jira.Comment `json:"comment,omitempty"`
+	// Issue is the issue as fetched by the poll that found the change
+	Issue jira.Issue `json:"issue"`
+}
+
+// Text describes the event in one line, e.g. "PROJ-1 status: To Do → Done".
+func (e Event) Text() string {
+	switch e.Type {
+	case EventAssigned:
+		if e.To == "" {
+			return fmt.Sprintf("%s unassigned (was %s)", e.Key, e.From)
+		}
+		if e.From == "" {
+			return fmt.Sprintf("%s assigned to %s", e.Key, e.To)
+		}
+		return fmt.Sprintf("%s assigned to %s (was %s)", e.Key, e.To, e.From)
+	case EventStatus, EventPriority:
+		return fmt.Sprintf("%s %s: %s → %s", e.Key, e.Type, orNone(e.From), orNone(e.To))
+	case EventComment, EventMention:
+		verb := "commented"
+		if e.Type == EventMention {
+			verb = "mentioned you"
+		}
+		return fmt.Sprintf("%s %s %s: %s", e.Key, e.author(), verb, excerpt(e.Comment.Body.PlainText(), 80))
+	}
+	return fmt.Sprintf("%s %s", e.Key, e.Type)
+}
+
+// author returns the name of the comment author.
+func (e Event) author() string {
+	if e.Comment == nil || e.Comment.Author.DisplayName == "" {
+		return "Someone"
+	}
+	return e.Comment.Author.DisplayName
+}
+
+// orNone returns s, or "None" when it is empty.
+func orNone(s string) string {
+	if s == "" {
+		return "None"
+	}
+	return s
+}
+
+// excerpt joins the lines of text and cuts it to n runes.
+func excerpt(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	return text
+}
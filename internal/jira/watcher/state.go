@@ -0,0 +1,137 @@
+package watcher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"jirar/internal/jira"
+)
+
+// maxSnapshots bounds the state file; the least recently updated issues
+// are forgotten first.
+const maxSnapshots = 5000
+
+// Snapshot is what the watcher last saw of an issue.
+type Snapshot struct {
+	Updated      time.Time `json:"updated"`
+	Status       string    `json:"status"`
+	AssigneeID   string    `json:"assigneeId,omitempty"`
+	AssigneeName string    `json:"assigneeName,omitempty"`
+	Priority     string    `json:"priority,omitempty"`
+	// LastComment is the creation time of the newest comment seen
+	LastComment time.Time `json:"lastComment"`
+}
+
+// newSnapshot records the fields of issue that events are derived from.
+// lastComment is the newest comment seen.
+func newSnapshot(issue jira.Issue, lastComment time.Time) Snapshot {
+	f := issue.Fields
+	return Snapshot{
+		Updated:      f.Updated.Time,
+		Status:       f.Status.Name,
+		AssigneeID:   f.Assignee.AccountID,
+		AssigneeName: f.Assignee.DisplayName,
+		Priority:     f.Priority.Name,
+		LastComment:  lastComment,
+	}
+}
+
+// State is the checkpoint and the snapshots of a watch, persisted between
+// runs so a restart neither replays nor misses events.
+type State struct {
+	Path string `json:"-"`
+	// JQL is the watched query, kept to identify the file
+	JQL string `json:"jql"`
+	// Checkpoint is when the last successful poll started; zero before the
+	// first one
+	Checkpoint time.Time           `json:"checkpoint"`
+	Snapshots  map[string]Snapshot `json:"snapshots"`
+}
+
+// DefaultStatePath returns the state file of a watch of jql in a profile;
+// an empty profile uses the top-level configuration.
+func DefaultStatePath(profile, jql string) string {
+	if profile == "" {
+		profile = "default"
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = filepath.Join(os.TempDir(), "jirar-cache")
+	}
+	sum := sha256.Sum256([]byte(jql))
+	return filepath.Join(dir, "jirar", profile, "watch", hex.EncodeToString(sum[:8])+".json")
+}
+
+// LoadState reads the state at path. A missing file yields an empty state,
+// which makes the next poll record a baseline without events.
+func LoadState(path string) (*State, error) {
+	s := &State{Path: path, Snapshots: map[string]Snapshot{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read watch state: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("parse watch state %s: %w", path, err)
+	}
+	if s.Snapshots == nil {
+		s.Snapshots = map[string]Snapshot{}
+	}
+	return s, nil
+}
+
+// Save writes the state, replacing the file atomically.
+func (s *State) Save() error {
+	s.prune()
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("encode watch state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o700); err != nil {
+		return fmt.Errorf("create watch state directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), ".watch-*")
+	if err != nil {
+		return fmt.Errorf("write watch state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write watch state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write watch state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.Path); err != nil {
+		return fmt.Errorf("write watch state: %w", err)
+	}
+	return nil
+}
+
+// prune forgets the least recently updated issues beyond maxSnapshots.
+func (s *State) prune() {
+	if len(s.Snapshots) <= maxSnapshots {
+		return
+	}
+	keys := make([]string, 0, len(s.Snapshots))
+	for key := range s.Snapshots {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		return s.Snapshots[keys[a]].Updated.After(s.Snapshots[keys[b]].Updated)
+	})
+	for _, key := range keys[maxSnapshots:] {
+		delete(s.Snapshots, key)
+	}
+}
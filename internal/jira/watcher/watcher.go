@@ -0,0 +1,262 @@
+// Package watcher polls Jira for changes to the issues matching a JQL
+// query and reports them as typed events. Each poll asks only for issues
+// updated since the last checkpoint and diffs them against the snapshot
+// taken when they were last seen.
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"jirar/internal/jira"
+)
+
+// DefaultJQL watches the issues assigned to or watched by the user.
+const DefaultJQL = "assignee = currentUser() OR watcher = currentUser()"
+
+// Polling limits.
+const (
+	// DefaultInterval is the time between polls
+	DefaultInterval = 30 * time.Second
+	// maxBackoff caps the delay after repeated failures
+	maxBackoff = 15 * time.Minute
+	// jitter is the fraction by which delays are randomly varied
+	jitter = 0.1
+	// pageSize is the number of issues requested per search page
+	pageSize = 100
+	// baselineLimit caps the issues recorded by the first poll
+	baselineLimit = 1000
+)
+
+// orderByPattern matches the ORDER BY clause of a JQL query.
+var orderByPattern = regexp.MustCompile(`(?is)\s*\border\s+by\b.*$`)
+
+// Options configure a Watcher.
+type Options struct {
+	// JQL selects the watched issues; empty uses DefaultJQL
+	JQL string
+	// Interval is the time between polls; zero uses DefaultInterval
+	Interval time.Duration
+	// State holds the checkpoint and snapshots, see LoadState
+	State *State
+	// AccountID identifies the user for mentions and their own comments;
+	// empty looks it up on the first poll
+	AccountID string
+	Logger    *logrus.Logger
+}
+
+// Watcher detects changes to watched issues.
+type Watcher struct {
+	client jira.Client
+	opts   Options
+	state  *State
+	logger *logrus.Logger
+	now    func() time.Time
+}
+
+// New creates a Watcher polling through client.
+func New(client jira.Client, opts Options) *Watcher {
+	if opts.JQL == "" {
+		opts.JQL = DefaultJQL
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.State == nil {
+		opts.State = &State{Snapshots: map[string]Snapshot{}}
+	}
+	if opts.Logger == nil {
+		opts.Logger = logrus.StandardLogger()
+	}
+	opts.State.JQL = opts.JQL
+	return &Watcher{client: client, opts: opts, state: opts.State, logger: opts.Logger, now: time.Now}
+}
+
+// Run polls until ctx is cancelled, calling handle for every event. After
+// an error the next poll is delayed exponentially, up to 15 minutes.
+func (w *Watcher) Run(ctx context.Context, handle func(Event)) error {
+	failures := 0
+	for {
+		if err := w.Poll(ctx, handle); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			failures++
+			w.logger.WithError(err).WithField("failures", failures).Warn("Poll failed")
+		} else {
+			failures = 0
+		}
+
+		delay := w.delay(failures)
+		w.logger.WithField("delay", delay.Round(time.Second)).Debug("Waiting for next poll")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Poll checks once for changes and calls handle for each event, oldest
+// issue first. The checkpoint is saved after all events are handled. The
+// first poll of a new state records a baseline without events.
+func (w *Watcher) Poll(ctx context.Context, handle func(Event)) error {
+	start := w.now()
+	if w.opts.AccountID == "" {
+		user, err := w.client.GetCurrentUser(ctx)
+		if err != nil {
+			return fmt.Errorf("look up current user: %w", err)
+		}
+		w.opts.AccountID = user.AccountID
+	}
+
+	baseline := w.state.Checkpoint.IsZero()
+	issues, err := w.fetch(ctx, baseline)
+	if err != nil {
+		return err
+	}
+
+	// Snapshots advance only once every issue is diffed and its events
+	// handled, so a failed poll reports the same changes when retried
+	var events []Event
+	snapshots := make(map[string]Snapshot, len(issues))
+	for _, issue := range issues {
+		if baseline {
+			// Comments cannot be newer than the issue's last update
+			snapshots[issue.Key] = newSnapshot(issue, issue.Fields.Updated.Time)
+			continue
+		}
+		found, snapshot, err := w.diff(ctx, issue)
+		if err != nil {
+			return err
+		}
+		if snapshot != nil {
+			snapshots[issue.Key] = *snapshot
+		}
+		events = append(events, found...)
+	}
+	for _, e := range events {
+		handle(e)
+	}
+	maps.Copy(w.state.Snapshots, snapshots)
+
+	w.logger.WithFields(logrus.Fields{"issues": len(issues), "events": len(events), "baseline": baseline}).Debug("Polled")
+	w.state.Checkpoint = start
+	return w.state.Save()
+}
+
+// query returns the JQL of a poll: all watched issues for the baseline,
+// else those updated since the checkpoint. The relative date avoids the
+// timezone of the Jira account; a minute of overlap covers clock
+// granularity, and snapshots drop the duplicates.
+func (w *Watcher) query(baseline bool) string {
+	filter := strings.TrimSpace(orderByPattern.ReplaceAllString(w.opts.JQL, ""))
+	if baseline {
+		return fmt.Sprintf("(%s) ORDER BY updated DESC", filter)
+	}
+	minutes := int(math.Ceil(w.now().Sub(w.state.Checkpoint).Minutes())) + 1
+	return fmt.Sprintf(`(%s) AND updated >= "-%dm" ORDER BY updated ASC`, filter, minutes)
+}
+
+// fetch runs the poll query through all result pages.
+func (w *Watcher) fetch(ctx context.Context, baseline bool) ([]jira.Issue, error) {
+	jql := w.query(baseline)
+	var issues []jira.Issue
+	for {
+		res, err := w.client.SearchIssues(ctx, jql, jira.WithLimit(pageSize), jira.WithStartAt(len(issues)))
+		if err != nil {
+			return nil, fmt.Errorf("search watched issues: %w", err)
+		}
+		issues = append(issues, res.Issues...)
+		if len(res.Issues) == 0 || len(issues) >= res.Total || (baseline && len(issues) >= baselineLimit) {
+			break
+		}
+	}
+
+	sort.SliceStable(issues, func(a, b int) bool {
+		return issues[a].Fields.Updated.Before(issues[b].Fields.Updated.Time)
+	})
+	return issues, nil
+}
+
+// diff compares an issue with its snapshot and returns the events in
+// between and the new snapshot, nil if the issue did not change. An issue
+// seen for the first time only reports an assignment to the user and
+// comments since the checkpoint.
+func (w *Watcher) diff(ctx context.Context, issue jira.Issue) ([]Event, *Snapshot, error) {
+	f := issue.Fields
+	prev, seen := w.state.Snapshots[issue.Key]
+	if seen && prev.Updated.Equal(f.Updated.Time) {
+		return nil, nil, nil
+	}
+
+	event := func(t EventType, from, to string) Event {
+		return Event{Type: t, Key: issue.Key, Time: f.Updated.Time, From: from, To: to, Issue: issue}
+	}
+	var events []Event
+	switch {
+	case !seen:
+		if f.Assignee.AccountID != "" && f.Assignee.AccountID == w.opts.AccountID {
+			events = append(events, event(EventAssigned, "", f.Assignee.DisplayName))
+		}
+		prev.LastComment = w.state.Checkpoint
+	default:
+		if prev.AssigneeID != f.Assignee.AccountID {
+			events = append(events, event(EventAssigned, prev.AssigneeName, f.Assignee.DisplayName))
+		}
+		if prev.Status != f.Status.Name {
+			events = append(events, event(EventStatus, prev.Status, f.Status.Name))
+		}
+		if prev.Priority != f.Priority.Name {
+			events = append(events, event(EventPriority, prev.Priority, f.Priority.Name))
+		}
+	}
+
+	comments, err := w.client.GetComments(ctx, issue.Key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get comments of %s: %w", issue.Key, err)
+	}
+	last := prev.LastComment
+	for i := range comments {
+		c := &comments[i]
+		if !c.Created.After(prev.LastComment) {
+			continue
+		}
+		if c.Created.After(last) {
+			last = c.Created.Time
+		}
+		if c.Author.AccountID == w.opts.AccountID {
+			continue
+		}
+		t := EventComment
+		if c.Body.Mentions(w.opts.AccountID) {
+			t = EventMention
+		}
+		events = append(events, Event{Type: t, Key: issue.Key, Time: c.Created.Time, Comment: c, Issue: issue})
+	}
+
+	snapshot := newSnapshot(issue, last)
+	return events, &snapshot, nil
+}
+
+// delay returns the time until the next poll: the interval, doubled per
+// consecutive failure up to maxBackoff, varied by the jitter.
+func (w *Watcher) delay(failures int) time.Duration {
+	d := w.opts.Interval
+	for i := 0; i < failures && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if failures > 0 {
+		d = min(d, max(maxBackoff, w.opts.Interval))
+	}
+	return time.Duration(float64(d) * (1 + jitter*(2*rand.Float64()-1)))
+}
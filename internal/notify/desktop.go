@@ -0,0 +1,297 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+
+	"jirar/internal/jira"
+	"jirar/internal/jira/watcher"
+)
+
+// D-Bus names of the freedesktop notification service.
+const (
+	notificationsName      = "org.freedesktop.Notifications"
+	notificationsPath      = dbus.ObjectPath("/org/freedesktop/Notifications")
+	notificationsInterface = "org.freedesktop.Notifications"
+)
+
+// Urgency levels of the notification specification.
+const (
+	UrgencyLow      byte = 0
+	UrgencyNormal   byte = 1
+	UrgencyCritical byte = 2
+)
+
+// Actions offered on desktop notifications. ActionDefault is sent when the
+// notification itself is clicked.
+const (
+	ActionDefault = "default"
+	ActionOpen    = "open"
+	ActionRead    = "read"
+)
+
+// maxLines is the number of events a collapsed notification lists.
+const maxLines = 5
+
+// Action is a button the user pressed on the notification of an issue.
+type Action struct {
+	Key  string
+	Name string
+}
+
+// DesktopOptions configure a Desktop notifier.
+type DesktopOptions struct {
+	// Conn is the session bus; nil connects to $DBUS_SESSION_BUS_ADDRESS
+	Conn *dbus.Conn
+	// AppName identifies the sender; empty uses jirar
+	AppName string
+	// Icon returns the icon of an issue type, a file path or an icon
+	// name; nil or "" shows no icon
+	Icon func(ctx context.Context, t jira.IssueType) string
+	// OnAction is called, from another goroutine, when the user presses
+	// an action button or clicks a notification
+	OnAction func(Action)
+}
+
+// Desktop shows events as notifications of the freedesktop notification
+// service. Notifications for the same issue replace each other, listing
+// the latest events, until the user closes them.
+type Desktop struct {
+	conn    *dbus.Conn
+	owned   bool
+	obj     dbus.BusObject
+	opts    DesktopOptions
+	actions bool
+	markup  bool
+	signals chan *dbus.Signal
+
+	mu sync.Mutex
+	// ids maps issue keys to their open notification
+	ids map[string]uint32
+	// keys maps open notifications to their issue
+	keys map[uint32]string
+	// lines are the events shown by the open notification of an issue
+	lines map[string][]string
+}
+
+// NewDesktop connects to the notification service and starts listening
+// for actions.
+func NewDesktop(opts DesktopOptions) (*Desktop, error) {
+	conn, owned := opts.Conn, false
+	if conn == nil {
+		var err error
+		conn, err = dbus.ConnectSessionBus()
+		if err != nil {
+			return nil, fmt.Errorf("connect to the session bus: %w", err)
+		}
+		owned = true
+	}
+	if opts.AppName == "" {
+		opts.AppName = "jirar"
+	}
+
+	d := &Desktop{
+		conn:    conn,
+		owned:   owned,
+		obj:     conn.Object(notificationsName, notificationsPath),
+		opts:    opts,
+		signals: make(chan *dbus.Signal, 16),
+		ids:     map[string]uint32{},
+		keys:    map[uint32]string{},
+		lines:   map[string][]string{},
+	}
+
+	var caps []string
+	if err := d.obj.Call(notificationsInterface+".GetCapabilities", 0).Store(&caps); err != nil {
+		d.Close()
+		return nil, fmt.Errorf("no desktop notification service: %w", err)
+	}
+	for _, c := range caps {
+		switch c {
+		case "actions":
+			d.actions = true
+		case "body-markup":
+			d.markup = true
+		}
+	}
+
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(notificationsPath),
+		dbus.WithMatchInterface(notificationsInterface),
+	); err != nil {
+		d.Close()
+		return nil, fmt.Errorf("subscribe to notification signals: %w", err)
+	}
+	conn.Signal(d.signals)
+	go d.listen()
+	return d, nil
+}
+
+// Notify shows an event, replacing the notification of its issue if one
+// is open.
+func (d *Desktop) Notify(ctx context.Context, e watcher.Event) error {
+	d.mu.Lock()
+	lines := append([]string{d.escape(strings.TrimPrefix(e.Text(), e.Key+" "))}, d.lines[e.Key]...)
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+	}
+	replaces := d.ids[e.Key]
+	d.mu.Unlock()
+
+	var actions []string
+	if d.actions {
+		actions = []string{ActionDefault, "Open", ActionOpen, "Open", ActionRead, "Mark read"}
+	}
+	var icon string
+	if d.opts.Icon != nil {
+		icon = d.opts.Icon(ctx, e.Issue.Fields.IssueType)
+	}
+	hints := map[string]dbus.Variant{
+		"urgency":  dbus.MakeVariant(Urgency(e)),
+		"category": dbus.MakeVariant("x-jirar." + string(e.Type)),
+	}
+
+	summary := e.Key
+	if s := e.Issue.Fields.Summary; s != "" {
+		summary += " " + s
+	}
+	var id uint32
+	call := d.obj.CallWithContext(ctx, notificationsInterface+".Notify", 0,
+		d.opts.AppName, replaces, icon, summary, strings.Join(lines, "\n"), actions, hints, int32(-1))
+	if err := call.Store(&id); err != nil {
+		return fmt.Errorf("send desktop notification: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if replaces != 0 && replaces != id {
+		delete(d.keys, replaces)
+	}
+	d.ids[e.Key], d.keys[id], d.lines[e.Key] = id, e.Key, lines
+	return nil
+}
+
+// Digest shows several events as one notification listing the first of
+// them. It has no actions, as it covers several issues.
+func (d *Desktop) Digest(ctx context.Context, events []watcher.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	var lines []string
+	urgency := UrgencyLow
+	for i, e := range events {
+		urgency = max(urgency, Urgency(e))
+		if i < maxLines {
+			lines = append(lines, d.escape(e.Text()))
+		}
+	}
+	if len(events) > maxLines {
+		lines = append(lines, more(len(events)-maxLines))
+	}
+	hints := map[string]dbus.Variant{
+		"urgency":  dbus.MakeVariant(urgency),
+		"category": dbus.MakeVariant("x-jirar.digest"),
+	}
+
+	call := d.obj.CallWithContext(ctx, notificationsInterface+".Notify", 0,
+		d.opts.AppName, uint32(0), "", digestTitle(len(events)), strings.Join(lines, "\n"), []string{}, hints, int32(-1))
+	if call.Err != nil {
+		return fmt.Errorf("send desktop notification: %w", call.Err)
+	}
+	return nil
+}
+
+// CloseIssue closes the notification of an issue.
+func (d *Desktop) CloseIssue(key string) error {
+	d.mu.Lock()
+	id, ok := d.ids[key]
+	d.forget(id)
+	d.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	if err := d.obj.Call(notificationsInterface+".CloseNotification", 0, id).Err; err != nil {
+		return fmt.Errorf("close desktop notification: %w", err)
+	}
+	return nil
+}
+
+// Close stops listening and disconnects from a bus it connected to.
+func (d *Desktop) Close() error {
+	d.conn.RemoveSignal(d.signals)
+	close(d.signals)
+	if d.owned {
+		return d.conn.Close()
+	}
+	return nil
+}
+
+// listen dispatches action and close signals until Close.
+func (d *Desktop) listen() {
+	for sig := range d.signals {
+		if len(sig.Body) < 2 {
+			continue
+		}
+		id, _ := sig.Body[0].(uint32)
+
+		d.mu.Lock()
+		key, ok := d.keys[id]
+		if ok && sig.Name == notificationsInterface+".NotificationClosed" {
+			d.forget(id)
+		}
+		d.mu.Unlock()
+		if !ok || sig.Name != notificationsInterface+".ActionInvoked" {
+			continue
+		}
+
+		action, _ := sig.Body[1].(string)
+		if action == ActionDefault {
+			action = ActionOpen
+		}
+		if action == ActionRead {
+			d.CloseIssue(key)
+		}
+		if d.opts.OnAction != nil {
+			d.opts.OnAction(Action{Key: key, Name: action})
+		}
+	}
+}
+
+// forget drops the bookkeeping of a notification; d.mu must be held.
+func (d *Desktop) forget(id uint32) {
+	if key, ok := d.keys[id]; ok {
+		delete(d.ids, key)
+		delete(d.lines, key)
+		delete(d.keys, id)
+	}
+}
+
+// escape escapes text for servers that render body markup.
+func (d *Desktop) escape(text string) string {
+	if d.markup {
+		return html.EscapeString(text)
+	}
+	return text
+}
+
+// Urgency maps the priority of an event's issue to a notification
+// urgency: Highest and blocking priorities are critical, Low and Lowest
+// are low. Mentions are never low.
+func Urgency(e watcher.Event) byte {
+	urgency := UrgencyNormal
+	switch strings.ToLower(e.Issue.Fields.Priority.Name) {
+	case "highest", "blocker", "critical", "urgent":
+		urgency = UrgencyCritical
+	case "low", "lowest", "trivial", "minor":
+		urgency = UrgencyLow
+	}
+	if e.Type == watcher.EventMention && urgency == UrgencyLow {
+		urgency = UrgencyNormal
+	}
+	return urgency
+}
@@ -0,0 +1,260 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"jirar/internal/jira"
+	"jirar/internal/jira/watcher"
+)
+
+// sessionBus starts a private session bus and returns its address; the
+// test is skipped without dbus-daemon.
+func sessionBus(t *testing.T) string {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+	cmd := exec.Command(daemon, "--session", "--nofork", "--print-address=1")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	address, err := bufio.NewReader(out).ReadString('\n')
+	if err != nil {
+		t.Fatalf("read bus address: %v", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+// connect opens a private connection to the bus at address.
+func connect(t *testing.T, address string) *dbus.Conn {
+	t.Helper()
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("connect to %s: %v", address, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// notification is a Notify call received by notificationServer.
+type notification struct {
+	id       uint32
+	replaces uint32
+	summary  string
+	body     string
+	actions  []string
+	urgency  byte
+}
+
+// notificationServer is a notification service recording what it is sent.
+type notificationServer struct {
+	conn *dbus.Conn
+
+	mu     sync.Mutex
+	last   uint32
+	sent   []notification
+	closed []uint32
+}
+
+func (s *notificationServer) GetCapabilities() ([]string, *dbus.Error) {
+	return []string{"actions", "body"}, nil
+}
+
+func (s *notificationServer) Notify(app string, replaces uint32, icon, summary, body string, actions []string, hints map[string]dbus.Variant, timeout int32) (uint32, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := replaces
+	if id == 0 {
+		s.last++
+		id = s.last
+	}
+	urgency, _ := hints["urgency"].Value().(byte)
+	s.sent = append(s.sent, notification{id: id, replaces: replaces, summary: summary, body: body, actions: actions, urgency: urgency})
+	return id, nil
+}
+
+func (s *notificationServer) CloseNotification(id uint32) *dbus.Error {
+	s.mu.Lock()
+	s.closed = append(s.closed, id)
+	s.mu.Unlock()
+	s.conn.Emit(notificationsPath, notificationsInterface+".NotificationClosed", id, uint32(3))
+	return nil
+}
+
+// invoke emits the signal of the user pressing action on notification id.
+func (s *notificationServer) invoke(id uint32, action string) {
+	s.conn.Emit(notificationsPath, notificationsInterface+".ActionInvoked", id, action)
+}
+
+// testDesktop starts a bus with a notification server and a Desktop
+// notifier on it, sending its actions to the returned channel.
+func testDesktop(t *testing.T) (*Desktop, *notificationServer, chan Action) {
+	t.Helper()
+	address := sessionBus(t)
+
+	server := &notificationServer{conn: connect(t, address)}
+	if err := server.conn.Export(server, notificationsPath, notificationsInterface); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := server.conn.RequestName(notificationsName, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("own %s: %v", notificationsName, err)
+	}
+
+	actions := make(chan Action, 4)
+	d, err := NewDesktop(DesktopOptions{
+		Conn:     connect(t, address),
+		OnAction: func(a Action) { actions <- a },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d, server, actions
+}
+
+// event is an event of type on key with a priority.
+func event(typ watcher.EventType, key, priority string) watcher.Event {
+	e := watcher.Event{Type: typ, Key: key, Time: time.Now(), From: "To Do", To: "Done"}
+	e.Issue.Key = key
+	e.Issue.Fields.Summary = "Fix login"
+	e.Issue.Fields.Priority.Name = priority
+	if typ == watcher.EventComment || typ == watcher.EventMention {
+		e.Comment = &jira.Comment{Author: jira.User{DisplayName: "Bob"}, Body: jira.NewADF("Looks good")}
+	}
+	return e
+}
+
+func TestUrgency(t *testing.T) {
+	tests := []struct {
+		typ      watcher.EventType
+		priority string
+		want     byte
+	}{
+		{watcher.EventStatus, "Highest", UrgencyCritical},
+		{watcher.EventStatus, "Blocker", UrgencyCritical},
+		{watcher.EventStatus, "Medium", UrgencyNormal},
+		{watcher.EventStatus, "", UrgencyNormal},
+		{watcher.EventStatus, "Lowest", UrgencyLow},
+		{watcher.EventComment, "Minor", UrgencyLow},
+		{watcher.EventMention, "Low", UrgencyNormal},
+		{watcher.EventMention, "Critical", UrgencyCritical},
+	}
+	for _, tt := range tests {
+		if got := Urgency(event(tt.typ, "PROJ-1", tt.priority)); got != tt.want {
+			t.Errorf("Urgency(%s, %q) = %d, want %d", tt.typ, tt.priority, got, tt.want)
+		}
+	}
+}
+
+func TestDesktopNotify(t *testing.T) {
+	d, server, _ := testDesktop(t)
+	ctx := context.Background()
+
+	for _, e := range []watcher.Event{
+		event(watcher.EventStatus, "PROJ-1", "Highest"),
+		event(watcher.EventComment, "PROJ-1", "Highest"),
+		event(watcher.EventStatus, "PROJ-2", "Low"),
+	} {
+		if err := d.Notify(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.sent) != 3 {
+		t.Fatalf("sent %d notifications, want 3", len(server.sent))
+	}
+	first, second, other := server.sent[0], server.sent[1], server.sent[2]
+	if first.summary != "PROJ-1 Fix login" || first.urgency != UrgencyCritical {
+		t.Errorf("first = %q with urgency %d, want PROJ-1 Fix login, critical", first.summary, first.urgency)
+	}
+	if !strings.Contains(strings.Join(first.actions, " "), ActionRead+" Mark read") {
+		t.Errorf("actions = %v, want Mark read", first.actions)
+	}
+	// Events on the same issue replace its notification, latest first
+	if second.replaces != first.id {
+		t.Errorf("second replaces %d, want %d", second.replaces, first.id)
+	}
+	if want := "Bob commented: Looks good\nstatus: To Do → Done"; second.body != want {
+		t.Errorf("second body = %q, want %q", second.body, want)
+	}
+	if other.replaces != 0 || other.id == first.id || other.urgency != UrgencyLow {
+		t.Errorf("other issue = %+v, want a new low urgency notification", other)
+	}
+}
+
+func TestDesktopActions(t *testing.T) {
+	d, server, actions := testDesktop(t)
+	ctx := context.Background()
+
+	if err := d.Notify(ctx, event(watcher.EventStatus, "PROJ-1", "Medium")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Notify(ctx, event(watcher.EventStatus, "PROJ-2", "Medium")); err != nil {
+		t.Fatal(err)
+	}
+	server.mu.Lock()
+	id1, id2 := server.sent[0].id, server.sent[1].id
+	server.mu.Unlock()
+
+	receive := func() Action {
+		t.Helper()
+		select {
+		case a := <-actions:
+			return a
+		case <-time.After(5 * time.Second):
+			t.Fatal("no action received")
+		}
+		return Action{}
+	}
+
+	// Clicking the notification opens the issue
+	server.invoke(id1, ActionDefault)
+	if a := receive(); a != (Action{Key: "PROJ-1", Name: ActionOpen}) {
+		t.Errorf("click = %+v, want open PROJ-1", a)
+	}
+	server.invoke(id2, ActionOpen)
+	if a := receive(); a != (Action{Key: "PROJ-2", Name: ActionOpen}) {
+		t.Errorf("open = %+v, want open PROJ-2", a)
+	}
+
+	// Mark read closes the notification, so the next event starts anew
+	server.invoke(id1, ActionRead)
+	if a := receive(); a != (Action{Key: "PROJ-1", Name: ActionRead}) {
+		t.Errorf("mark read = %+v, want read PROJ-1", a)
+	}
+	server.mu.Lock()
+	closed := server.closed
+	server.mu.Unlock()
+	if len(closed) != 1 || closed[0] != id1 {
+		t.Errorf("closed %v, want [%d]", closed, id1)
+	}
+	if err := d.Notify(ctx, event(watcher.EventStatus, "PROJ-1", "Medium")); err != nil {
+		t.Fatal(err)
+	}
+	server.mu.Lock()
+	last := server.sent[len(server.sent)-1]
+	server.mu.Unlock()
+	if last.replaces != 0 {
+		t.Errorf("notification after mark read replaces %d, want a new one", last.replaces)
+	}
+}
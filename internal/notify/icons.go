@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"jirar/internal/jira"
+)
+
+// Icons downloads issue type icons from Jira once and keeps them on disk,
+// since notification services load icons from files.
+type Icons struct {
+	client jira.Client
+	domain string
+	dir    string
+
+	mu    sync.Mutex
+	paths map[string]string
+}
+
+// NewIcons creates an icon cache in dir for icons served by the Jira site
+// at domain.
+func NewIcons(client jira.Client, domain, dir string) *Icons {
+	return &Icons{client: client, domain: strings.TrimRight(domain, "/"), dir: dir, paths: map[string]string{}}
+}
+
+// DefaultIconDir returns the icon directory under the user cache directory.
+func DefaultIconDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = filepath.Join(os.TempDir(), "jirar-cache")
+	}
+	return filepath.Join(dir, "jirar", "icons")
+}
+
+// Path returns the file of the issue type's icon, or "" when it has none
+// or the download fails. Failures are not retried during the run.
+func (i *Icons) Path(ctx context.Context, t jira.IssueType) string {
+	if t.IconURL == "" {
+		return ""
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if path, ok := i.paths[t.IconURL]; ok {
+		return path
+	}
+	path, err := i.fetch(ctx, t.IconURL)
+	if err != nil {
+		path = ""
+	}
+	i.paths[t.IconURL] = path
+	return path
+}
+
+// fetch downloads an icon unless a previous run stored it.
+func (i *Icons) fetch(ctx context.Context, iconURL string) (string, error) {
+	sum := sha256.Sum256([]byte(iconURL))
+	base := filepath.Join(i.dir, hex.EncodeToString(sum[:8]))
+	for _, ext := range []string{".svg", ".png", ".gif", ".jpg"} {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext, nil
+		}
+	}
+
+	// Icons on the Jira site need the credentials of the client
+	u, err := url.Parse(iconURL)
+	if err != nil || !strings.HasPrefix(iconURL, i.domain+"/") {
+		return "", fmt.Errorf("icon %s is not served by %s", iconURL, i.domain)
+	}
+	resp, err := i.client.Do(ctx, &jira.RawRequest{
+		Path:   u.Path,
+		Query:  u.Query(),
+		Header: http.Header{"Accept": {"image/*"}},
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download icon: %s", resp.Status)
+	}
+
+	ext := ".png"
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		switch mediaType {
+		case "image/svg+xml":
+			ext = ".svg"
+		case "image/gif":
+			ext = ".gif"
+		case "image/jpeg":
+			ext = ".jpg"
+		}
+	}
+	if err := os.MkdirAll(i.dir, 0o700); err != nil {
+		return "", fmt.Errorf("create icon directory: %w", err)
+	}
+	if err := os.WriteFile(base+ext, resp.Body, 0o600); err != nil {
+		return "", fmt.Errorf("write icon: %w", err)
+	}
+	return base + ext, nil
+}
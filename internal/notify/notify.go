@@ -0,0 +1,26 @@
+// Package notify delivers watch events to the user: as desktop
+// notifications, or through sinks that post to Slack, Microsoft Teams,
+// Discord, ntfy or any JSON webhook.
+package notify
+
+import (
+	"context"
+
+	"jirar/internal/jira/watcher"
+)
+
+// Notifier delivers watch events.
+type Notifier interface {
+	// Notify delivers an event. Sinks keep an event they failed to deliver
+	// and retry it later, so an error does not mean it is lost.
+	Notify(ctx context.Context, e watcher.Event) error
+	// Digest delivers several events as one message.
+	Digest(ctx context.Context, events []watcher.Event) error
+	// Close releases the notifier's resources.
+	Close() error
+}
+
+var (
+	_ Notifier = (*Desktop)(nil)
+	_ Notifier = (*Sink)(nil)
+)
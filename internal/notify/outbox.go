@@ -0,0 +1,297 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Retry limits of the outbox.
+const (
+	// retryBase is the delay after the first failed attempt; it doubles
+	// with every further attempt
+	retryBase = 30 * time.Second
+	// retryMax caps the delay between attempts
+	retryMax = time.Hour
+	// maxAge is how long a message is retried before it is given up
+	maxAge = 24 * time.Hour
+	// failedDir holds the messages that were given up, for inspection
+	failedDir = "failed"
+)
+
+// Message is a request waiting in an outbox.
+type Message struct {
+	ID     string      `json:"id"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	// Body is kept byte for byte, since it may be signed
+	Body    []byte    `json:"body"`
+	Created time.Time `json:"created"`
+	// Attempts counts the failed deliveries so far
+	Attempts int `json:"attempts"`
+	// Next is when the message is due again after a failure
+	Next time.Time `json:"next,omitzero"`
+	// Error is the reason the last attempt failed
+	Error string `json:"error,omitempty"`
+}
+
+// Outbox keeps messages on disk, one file each, until their endpoint
+// accepts them. Failed messages are retried after doubling delays, oldest
+// first, and given up after a day or when the endpoint rejects them.
+type Outbox struct {
+	dir    string
+	client *http.Client
+	logger *logrus.Logger
+	now    func() time.Time
+
+	mu sync.Mutex
+}
+
+// permanentError is a rejection that retrying will not fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// retryError is a failure worth retrying, possibly after a delay the
+// endpoint asked for.
+type retryError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryError) Error() string { return e.err.Error() }
+func (e *retryError) Unwrap() error { return e.err }
+
+// NewOutbox opens the outbox in dir, sending through client.
+func NewOutbox(dir string, client *http.Client, logger *logrus.Logger) *Outbox {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	return &Outbox{dir: dir, client: client, logger: logger, now: time.Now}
+}
+
+// DefaultOutboxDir returns the outbox directory of a sink under the user
+// cache directory.
+func DefaultOutboxDir(profile, sink string) string {
+	if profile == "" {
+		profile = "default"
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = filepath.Join(os.TempDir(), "jirar-cache")
+	}
+	return filepath.Join(dir, "jirar", profile, "outbox", sink)
+}
+
+// Add stores a message for delivery by the next Flush and returns its ID.
+func (o *Outbox) Add(m Message) (string, error) {
+	var suffix [4]byte
+	rand.Read(suffix[:])
+	m.Created = o.now()
+	// The name sorts by creation, which is the delivery order
+	m.ID = fmt.Sprintf("%019d-%s", m.Created.UnixNano(), hex.EncodeToString(suffix[:]))
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return m.ID, o.write(m)
+}
+
+// Pending returns the queued messages, oldest first.
+func (o *Outbox) Pending() ([]Message, error) {
+	entries, err := os.ReadDir(o.dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read outbox: %w", err)
+	}
+	var messages []Message
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(o.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read outbox: %w", err)
+		}
+		var m Message
+		if err := json.Unmarshal(data, &m); err != nil {
+			o.logger.WithError(err).WithField("file", entry.Name()).Warn("Skipping corrupt outbox message")
+			continue
+		}
+		messages = append(messages, m)
+	}
+	sort.Slice(messages, func(a, b int) bool { return messages[a].ID < messages[b].ID })
+	return messages, nil
+}
+
+// Flush sends the messages that are due, oldest first. It stops at the
+// first failure worth retrying, so an endpoint that is down is tried once
+// per flush and the order of messages is kept. The error lists the
+// messages that failed.
+func (o *Outbox) Flush(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	messages, err := o.Pending()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, m := range messages {
+		now := o.now()
+		if m.Next.After(now) {
+			// Later messages wait for the one before them
+			break
+		}
+
+		err := o.send(ctx, m)
+		if err == nil {
+			if err := os.Remove(o.path(m.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("remove delivered message: %w", err)
+			}
+			o.logger.WithFields(logrus.Fields{"id": m.ID, "attempts": m.Attempts + 1}).Debug("Delivered notification")
+			continue
+		}
+		if ctx.Err() != nil {
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+
+		m.Attempts++
+		m.Error = err.Error()
+		var permanent *permanentError
+		if errors.As(err, &permanent) || now.Sub(m.Created) >= maxAge {
+			if err := o.giveUp(m); err != nil {
+				return err
+			}
+			if permanent != nil {
+				errs = append(errs, fmt.Errorf("rejected: %w", err))
+			} else {
+				errs = append(errs, fmt.Errorf("gave up after %d attempts: %w", m.Attempts, err))
+			}
+			continue
+		}
+
+		m.Next = now.Add(retryDelay(m.Attempts, err))
+		if err := o.write(m); err != nil {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("%w (retrying at %s)", err, m.Next.Format(time.TimeOnly)))
+		break
+	}
+	return errors.Join(errs...)
+}
+
+// send posts a message. Throttling, timeouts and server errors are worth
+// retrying; other rejections are permanent.
+func (o *Outbox) send(ctx context.Context, m Message) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.URL, bytes.NewReader(m.Body))
+	if err != nil {
+		return &permanentError{fmt.Errorf("build request: %w", err)}
+	}
+	for name, values := range m.Header {
+		req.Header[name] = values
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return &retryError{err: err}
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+
+	err = fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= 500 {
+		seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return &retryError{err: err, after: time.Duration(seconds) * time.Second}
+	}
+	return &permanentError{err}
+}
+
+// retryDelay returns the delay before the next attempt: retryBase doubled
+// per attempt up to retryMax, or longer if the endpoint asked for it.
+func retryDelay(attempts int, err error) time.Duration {
+	d := retryBase
+	for i := 1; i < attempts && d < retryMax; i++ {
+		d *= 2
+	}
+	d = min(d, retryMax)
+	var retry *retryError
+	if errors.As(err, &retry) && retry.after > d {
+		d = retry.after
+	}
+	return d
+}
+
+// giveUp moves a message to the failed directory.
+func (o *Outbox) giveUp(m Message) error {
+	dir := filepath.Join(o.dir, failedDir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create outbox directory: %w", err)
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("encode outbox message: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, m.ID+".json"), data, 0o600); err != nil {
+		return fmt.Errorf("write outbox message: %w", err)
+	}
+	if err := os.Remove(o.path(m.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove outbox message: %w", err)
+	}
+	return nil
+}
+
+// write stores a message atomically; o.mu must be held.
+func (o *Outbox) write(m Message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("encode outbox message: %w", err)
+	}
+	if err := os.MkdirAll(o.dir, 0o700); err != nil {
+		return fmt.Errorf("create outbox directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(o.dir, ".message-*")
+	if err != nil {
+		return fmt.Errorf("write outbox message: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write outbox message: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write outbox message: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), o.path(m.ID)); err != nil {
+		return fmt.Errorf("write outbox message: %w", err)
+	}
+	return nil
+}
+
+// path returns the file of a queued message.
+func (o *Outbox) path(id string) string {
+	return filepath.Join(o.dir, id+".json")
+}
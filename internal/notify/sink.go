@@ -0,0 +1,518 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"jirar/internal/jira/watcher"
+	"jirar/internal/output"
+)
+
+// Sink types.
+const (
+	SinkSlack   = "slack"
+	SinkTeams   = "teams"
+	SinkDiscord = "discord"
+	SinkNtfy    = "ntfy"
+	SinkWebhook = "webhook"
+)
+
+// SinkTypes lists the sink types.
+var SinkTypes = []string{SinkSlack, SinkTeams, SinkDiscord, SinkNtfy, SinkWebhook}
+
+// SignatureHeader carries the HMAC-SHA256 of a webhook payload, as
+// "sha256=" followed by the hex digest.
+const SignatureHeader = "X-Jirar-Signature-256"
+
+// Default templates of the title and text of a message. Templates see the
+// event as JSON: .key, .type, .text (the change without the key), .url,
+// .issue and .event, e.g. {{.issue.fields.priority.name}}.
+const (
+	DefaultTitleTemplate = `{{.key}} {{.issue.fields.summary}}`
+	DefaultTextTemplate  = `{{.text}}`
+)
+
+// Embed colors of Discord by urgency.
+var discordColors = map[byte]int{UrgencyLow: 0x9ca3af, UrgencyNormal: 0x3b82f6, UrgencyCritical: 0xe5484d}
+
+// SinkOptions configure a Sink.
+type SinkOptions struct {
+	// Name identifies the sink in logs
+	Name string
+	// Type is one of SinkTypes
+	Type string
+	// URL is the incoming webhook, ntfy topic or endpoint
+	URL string
+	// Secret signs webhook payloads, see SignatureHeader
+	Secret string
+	// Token is sent as a bearer token
+	Token string
+	// Title and Text are templates; empty uses the defaults
+	Title string
+	Text  string
+	// Headers are added to every request
+	Headers map[string]string
+	// Domain is the Jira site, for links to issues
+	Domain string
+	// Outbox is the directory of undelivered messages
+	Outbox string
+	Client *http.Client
+	Logger *logrus.Logger
+}
+
+// Sink posts events to a chat or webhook. Messages go through an on-disk
+// outbox, so those the endpoint fails to take are retried by later calls
+// to Notify or Flush, even after a restart.
+type Sink struct {
+	opts   SinkOptions
+	title  *template.Template
+	text   *template.Template
+	outbox *Outbox
+}
+
+// message is an event rendered for a sink, or a digest of Items.
+type message struct {
+	Title string
+	Text  string
+	URL   string
+	Event watcher.Event
+	Items []message
+}
+
+// maxItems is the number of events a digest lists; the rest are counted.
+const maxItems = 40
+
+// NewSink validates the options and parses the templates of a sink.
+func NewSink(opts SinkOptions) (*Sink, error) {
+	known := false
+	for _, t := range SinkTypes {
+		known = known || t == opts.Type
+	}
+	if !known {
+		return nil, fmt.Errorf("sink %s: unknown type %q (use %s)", opts.Name, opts.Type, strings.Join(SinkTypes, ", "))
+	}
+	if u, err := url.Parse(opts.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("sink %s: url must be an http or https URL", opts.Name)
+	}
+	if opts.Type == SinkNtfy && strings.Trim(parseURL(opts.URL).Path, "/") == "" {
+		return nil, fmt.Errorf("sink %s: ntfy url must name a topic, e.g. https://ntfy.sh/mytopic", opts.Name)
+	}
+	if opts.Title == "" {
+		opts.Title = DefaultTitleTemplate
+	}
+	if opts.Text == "" {
+		opts.Text = DefaultTextTemplate
+	}
+
+	s := &Sink{opts: opts, outbox: NewOutbox(opts.Outbox, opts.Client, opts.Logger)}
+	var err error
+	if s.title, err = template.New("title").Funcs(output.TemplateFuncs()).Option("missingkey=zero").Parse(opts.Title); err != nil {
+		return nil, fmt.Errorf("sink %s: invalid title template: %w", opts.Name, err)
+	}
+	if s.text, err = template.New("text").Funcs(output.TemplateFuncs()).Option("missingkey=zero").Parse(opts.Text); err != nil {
+		return nil, fmt.Errorf("sink %s: invalid text template: %w", opts.Name, err)
+	}
+	return s, nil
+}
+
+// Name returns the name of the sink.
+func (s *Sink) Name() string {
+	return s.opts.Name
+}
+
+// Notify queues an event and flushes the outbox.
+func (s *Sink) Notify(ctx context.Context, e watcher.Event) error {
+	m, err := s.render(e)
+	if err != nil {
+		return err
+	}
+	return s.queue(ctx, m)
+}
+
+// Digest queues several events as one message and flushes the outbox.
+func (s *Sink) Digest(ctx context.Context, events []watcher.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	m := message{Title: digestTitle(len(events))}
+	for _, e := range events {
+		item, err := s.render(e)
+		if err != nil {
+			return err
+		}
+		m.Items = append(m.Items, item)
+	}
+	return s.queue(ctx, m)
+}
+
+// queue adds a message to the outbox and flushes it. An error means the
+// message or an earlier one is waiting for a retry, or was given up.
+func (s *Sink) queue(ctx context.Context, m message) error {
+	msg, err := s.request(m)
+	if err != nil {
+		return err
+	}
+	id, err := s.outbox.Add(msg)
+	if err != nil {
+		return err
+	}
+	if err := s.Flush(ctx); err != nil {
+		return err
+	}
+
+	// The event waits while an earlier message is due for a retry
+	pending, err := s.outbox.Pending()
+	if err != nil {
+		return err
+	}
+	for i, m := range pending {
+		if m.ID == id {
+			return fmt.Errorf("sink %s: queued behind %d undelivered messages, retrying at %s",
+				s.opts.Name, i, pending[0].Next.Format(time.TimeOnly))
+		}
+	}
+	return nil
+}
+
+// Flush retries the queued messages that are due.
+func (s *Sink) Flush(ctx context.Context) error {
+	if err := s.outbox.Flush(ctx); err != nil {
+		return fmt.Errorf("sink %s: %w", s.opts.Name, err)
+	}
+	return nil
+}
+
+// Pending returns the messages waiting in the outbox.
+func (s *Sink) Pending() ([]Message, error) {
+	return s.outbox.Pending()
+}
+
+// Close does nothing; queued messages stay on disk for the next run.
+func (s *Sink) Close() error {
+	return nil
+}
+
+// render executes the templates of the sink for an event.
+func (s *Sink) render(e watcher.Event) (message, error) {
+	m := message{URL: output.BrowseURL(s.opts.Domain, e.Key), Event: e}
+	data, err := output.Generic(map[string]any{
+		"key":   e.Key,
+		"type":  e.Type,
+		"text":  strings.TrimPrefix(e.Text(), e.Key+" "),
+		"url":   m.URL,
+		"issue": e.Issue,
+		"event": e,
+	})
+	if err != nil {
+		return m, err
+	}
+
+	var buf bytes.Buffer
+	if err := s.title.Execute(&buf, data); err != nil {
+		return m, fmt.Errorf("sink %s: render title: %w", s.opts.Name, err)
+	}
+	m.Title = strings.TrimSpace(buf.String())
+	buf.Reset()
+	if err := s.text.Execute(&buf, data); err != nil {
+		return m, fmt.Errorf("sink %s: render text: %w", s.opts.Name, err)
+	}
+	m.Text = strings.TrimSpace(buf.String())
+	return m, nil
+}
+
+// request builds the outbox message posting m in the sink's format.
+func (s *Sink) request(m message) (Message, error) {
+	target := s.opts.URL
+	var payload any
+	switch s.opts.Type {
+	case SinkSlack:
+		payload = slackPayload(m)
+	case SinkTeams:
+		payload = teamsPayload(m)
+	case SinkDiscord:
+		payload = discordPayload(m)
+	case SinkNtfy:
+		target, payload = ntfyPayload(s.opts.URL, m)
+	default:
+		payload = webhookPayload(m)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(payload); err != nil {
+		return Message{}, fmt.Errorf("sink %s: encode payload: %w", s.opts.Name, err)
+	}
+	body := bytes.TrimSpace(buf.Bytes())
+
+	header := http.Header{}
+	for name, value := range s.opts.Headers {
+		header.Set(name, value)
+	}
+	header.Set("Content-Type", "application/json")
+	header.Set("User-Agent", "jirar")
+	if s.opts.Token != "" {
+		header.Set("Authorization", "Bearer "+s.opts.Token)
+	}
+	if s.opts.Type == SinkWebhook {
+		event := string(m.Event.Type)
+		if m.Items != nil {
+			event = "digest"
+		}
+		header.Set("X-Jirar-Event", event)
+		if s.opts.Secret != "" {
+			header.Set(SignatureHeader, Sign(s.opts.Secret, body))
+		}
+	}
+	return Message{URL: target, Header: header, Body: body}, nil
+}
+
+// Sign returns the signature of a webhook payload as sent in
+// SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// facts returns the name and value of the issue fields shown next to a
+// message, skipping empty ones.
+func facts(e watcher.Event) [][2]string {
+	f := e.Issue.Fields
+	var out [][2]string
+	for _, fact := range [][2]string{
+		{"Type", f.IssueType.Name},
+		{"Status", f.Status.Name},
+		{"Priority", f.Priority.Name},
+		{"Assignee", f.Assignee.DisplayName},
+	} {
+		if fact[1] != "" {
+			out = append(out, fact)
+		}
+	}
+	return out
+}
+
+// digestTitle returns the title of a digest of n events.
+func digestTitle(n int) string {
+	if n == 1 {
+		return "1 Jira update"
+	}
+	return fmt.Sprintf("%d Jira updates", n)
+}
+
+// urgency returns the urgency of a message: the highest of a digest.
+func (m message) urgency() byte {
+	if m.Items == nil {
+		return Urgency(m.Event)
+	}
+	urgency := UrgencyLow
+	for _, item := range m.Items {
+		urgency = max(urgency, item.urgency())
+	}
+	return urgency
+}
+
+// listed returns the items a digest shows and the number left out.
+func (m message) listed() ([]message, int) {
+	if len(m.Items) > maxItems {
+		return m.Items[:maxItems], len(m.Items) - maxItems
+	}
+	return m.Items, 0
+}
+
+// more describes the events a digest leaves out.
+func more(n int) string {
+	return fmt.Sprintf("…and %d more", n)
+}
+
+// slackPayload builds a Block Kit message for a Slack incoming webhook.
+// The text is the fallback shown in notifications.
+func slackPayload(m message) any {
+	section := func(text string) any {
+		return map[string]any{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": text}}
+	}
+	note := func(text string) any {
+		return map[string]any{"type": "context", "elements": []any{map[string]any{"type": "mrkdwn", "text": text}}}
+	}
+	link := func(m message) string {
+		return fmt.Sprintf("*<%s|%s>*\n%s", m.URL, slackEscape(m.Title), slackEscape(m.Text))
+	}
+
+	if m.Items != nil {
+		items, rest := m.listed()
+		blocks := []any{section("*" + slackEscape(m.Title) + "*")}
+		for _, item := range items {
+			blocks = append(blocks, section(link(item)))
+		}
+		if rest > 0 {
+			blocks = append(blocks, note(more(rest)))
+		}
+		return map[string]any{"text": m.Title, "blocks": blocks}
+	}
+
+	var details []string
+	for _, fact := range facts(m.Event) {
+		details = append(details, slackEscape(fact[1]))
+	}
+	blocks := []any{section(link(m))}
+	if len(details) > 0 {
+		blocks = append(blocks, note(strings.Join(details, " · ")))
+	}
+	return map[string]any{"text": m.Title + ": " + m.Text, "blocks": blocks}
+}
+
+// slackEscape escapes the characters Slack reserves for links and mentions.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// teamsPayload builds an Adaptive Card message for a Teams incoming
+// webhook or workflow.
+func teamsPayload(m message) any {
+	title := map[string]any{"type": "TextBlock", "text": m.Title, "weight": "Bolder", "size": "Medium", "wrap": true}
+	if m.urgency() == UrgencyCritical {
+		title["color"] = "Attention"
+	}
+	body := []any{title}
+	var actions []any
+	if m.Items != nil {
+		items, rest := m.listed()
+		for _, item := range items {
+			body = append(body,
+				map[string]any{"type": "TextBlock", "text": fmt.Sprintf("[%s](%s)", item.Title, item.URL), "weight": "Bolder", "wrap": true, "spacing": "Medium"},
+				map[string]any{"type": "TextBlock", "text": item.Text, "wrap": true, "spacing": "None"})
+		}
+		if rest > 0 {
+			body = append(body, map[string]any{"type": "TextBlock", "text": more(rest), "isSubtle": true})
+		}
+	} else {
+		body = append(body, map[string]any{"type": "TextBlock", "text": m.Text, "wrap": true})
+		var factSet []any
+		for _, fact := range facts(m.Event) {
+			factSet = append(factSet, map[string]any{"title": fact[0], "value": fact[1]})
+		}
+		if len(factSet) > 0 {
+			body = append(body, map[string]any{"type": "FactSet", "facts": factSet})
+		}
+		actions = []any{map[string]any{"type": "Action.OpenUrl", "title": "Open in Jira", "url": m.URL}}
+	}
+
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+	if actions != nil {
+		card["actions"] = actions
+	}
+	return map[string]any{
+		"type": "message",
+		"attachments": []any{map[string]any{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     card,
+		}},
+	}
+}
+
+// discordPayload builds an embed for a Discord webhook, colored by
+// urgency.
+func discordPayload(m message) any {
+	embed := map[string]any{
+		"title": output.Truncate(256, m.Title),
+		"color": discordColors[m.urgency()],
+	}
+	if m.Items != nil {
+		items, rest := m.listed()
+		var lines []string
+		for _, item := range items {
+			lines = append(lines, fmt.Sprintf("**[%s](%s)** %s", item.Title, item.URL, item.Text))
+		}
+		if rest > 0 {
+			lines = append(lines, more(rest))
+		}
+		embed["description"] = output.Truncate(4096, strings.Join(lines, "\n"))
+		return map[string]any{"username": "jirar", "embeds": []any{embed}}
+	}
+
+	var fields []any
+	for _, fact := range facts(m.Event) {
+		fields = append(fields, map[string]any{"name": fact[0], "value": fact[1], "inline": true})
+	}
+	embed["url"] = m.URL
+	embed["description"] = output.Truncate(4096, m.Text)
+	embed["fields"] = fields
+	if !m.Event.Time.IsZero() {
+		embed["timestamp"] = m.Event.Time.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	return map[string]any{"username": "jirar", "embeds": []any{embed}}
+}
+
+// ntfyPayload builds a JSON message for ntfy, which is published to the
+// server root with the topic in the body.
+func ntfyPayload(topicURL string, m message) (string, any) {
+	u := parseURL(topicURL)
+	path := strings.Trim(u.Path, "/")
+	topic := path[strings.LastIndex(path, "/")+1:]
+	u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), topic)
+	u.RawQuery = ""
+
+	payload := map[string]any{
+		"topic":    topic,
+		"title":    m.Title,
+		"message":  m.Text,
+		"priority": map[byte]int{UrgencyLow: 2, UrgencyNormal: 3, UrgencyCritical: 5}[m.urgency()],
+	}
+	if m.Items != nil {
+		items, rest := m.listed()
+		var lines []string
+		for _, item := range items {
+			lines = append(lines, item.Title+": "+item.Text)
+		}
+		if rest > 0 {
+			lines = append(lines, more(rest))
+		}
+		payload["message"] = strings.Join(lines, "\n")
+		payload["tags"] = []string{"digest"}
+		return u.String(), payload
+	}
+	payload["tags"] = []string{string(m.Event.Type)}
+	payload["click"] = m.URL
+	payload["actions"] = []any{map[string]any{"action": "view", "label": "Open", "url": m.URL}}
+	return u.String(), payload
+}
+
+// webhookPayload builds the JSON of a generic webhook: the rendered
+// message with its event, or with the items of a digest.
+func webhookPayload(m message) any {
+	if m.Items == nil {
+		return map[string]any{"title": m.Title, "text": m.Text, "url": m.URL, "event": m.Event}
+	}
+	items := make([]any, len(m.Items))
+	for i, item := range m.Items {
+		items[i] = webhookPayload(item)
+	}
+	return map[string]any{"title": m.Title, "digest": items}
+}
+
+// parseURL parses a URL that NewSink validated; it never fails.
+func parseURL(raw string) *url.URL {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return &url.URL{}
+	}
+	return u
+}
@@ -0,0 +1,224 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"jirar/internal/jira"
+)
+
+// DefaultColumns are the issue table columns used when none are
+// configured. Without terminal hyperlinks on the key, a link column follows.
+const DefaultColumns = "key,status,updated,summary"
+
+// Column is an issue table column.
+type Column struct {
+	// Header is the column title
+	Header string
+	// Field is the Jira field ID the column needs, if any
+	Field string
+
+	value  func(IssueItem) string
+	styled func(IssueItem, Styler) string
+}
+
+// Styler renders cells: statuses, priorities, links and timestamps.
+type Styler interface {
+	Status(jira.Status) string
+	Priority(jira.Priority) string
+	Link(url, text string) string
+	Time(jira.Time) string
+}
+
+// Value renders the column for an issue.
+func (c Column) Value(item IssueItem) string {
+	return c.value(item)
+}
+
+// Render renders the column for an issue, styled when the column has a
+// style and s is not nil.
+func (c Column) Render(item IssueItem, s Styler) string {
+	if s != nil && c.styled != nil {
+		return c.styled(item, s)
+	}
+	return c.value(item)
+}
+
+// builtinColumns are the columns known by name.
+var builtinColumns = map[string]Column{
+	"key": {
+		value:  func(i IssueItem) string { return i.Key },
+		styled: func(i IssueItem, s Styler) string { return s.Link(i.URL, i.Key) },
+	},
+	"summary": {Field: "summary", value: func(i IssueItem) string { return i.Fields.Summary }},
+	"status": {
+		Field:  "status",
+		value:  func(i IssueItem) string { return i.Fields.Status.Name },
+		styled: func(i IssueItem, s Styler) string { return s.Status(i.Fields.Status) },
+	},
+	"priority": {
+		Field:  "priority",
+		value:  func(i IssueItem) string { return i.Fields.Priority.Name },
+		styled: func(i IssueItem, s Styler) string { return s.Priority(i.Fields.Priority) },
+	},
+	"assignee": {Field: "assignee", value: func(i IssueItem) string { return i.Fields.Assignee.DisplayName }},
+	"reporter": {Field: "reporter", value: func(i IssueItem) string { return i.Fields.Reporter.DisplayName }},
+	"type":     {Field: "issuetype", value: func(i IssueItem) string { return i.Fields.IssueType.Name }},
+	"project":  {Field: "project", value: func(i IssueItem) string { return i.Fields.Project.Key }},
+	"created": {
+		Field:  "created",
+		value:  func(i IssueItem) string { return formatTime(i.Fields.Created) },
+		styled: func(i IssueItem, s Styler) string { return s.Time(i.Fields.Created) },
+	},
+	"updated": {
+		Field:  "updated",
+		value:  func(i IssueItem) string { return formatTime(i.Fields.Updated) },
+		styled: func(i IssueItem, s Styler) string { return s.Time(i.Fields.Updated) },
+	},
+	"due":  {Field: "duedate", value: func(i IssueItem) string { return formatDate(i.Fields.DueDate) }},
+	"link": {value: func(i IssueItem) string { return i.URL }},
+}
+
+// ParseColumns resolves a comma-separated column list. Each entry is a
+// built-in column, an alias, or a Jira field ID such as customfield_10016,
+// optionally prefixed with a header: "sp:customfield_10016".
+func ParseColumns(spec string, aliases map[string]string) ([]Column, error) {
+	var columns []Column
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		header, name, ok := strings.Cut(entry, ":")
+		if !ok {
+			name = header
+		}
+		if header == "" || name == "" {
+			return nil, fmt.Errorf("invalid column %q", entry)
+		}
+
+		column, err := resolveColumn(strings.ToLower(name), aliases)
+		if err != nil {
+			return nil, err
+		}
+		column.Header = header
+		columns = append(columns, column)
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns in %q", spec)
+	}
+	return columns, nil
+}
+
+// resolveColumn looks name up as a built-in column, then as an alias, and
+// otherwise takes it as a field ID.
+func resolveColumn(name string, aliases map[string]string) (Column, error) {
+	for seen := map[string]bool{}; ; seen[name] = true {
+		if column, ok := builtinColumns[name]; ok {
+			return column, nil
+		}
+		target, ok := aliases[name]
+		if !ok {
+			break
+		}
+		if seen[name] {
+			return Column{}, fmt.Errorf("column alias %q refers to itself", name)
+		}
+		name = strings.ToLower(target)
+	}
+
+	field := name
+	return Column{
+		Field: field,
+		value: func(i IssueItem) string { return FieldValue(i.Fields.Custom[field]) },
+	}, nil
+}
+
+// ColumnFields returns the Jira fields the columns need beyond the defaults.
+func ColumnFields(columns []Column) []string {
+	var fields []string
+	for _, c := range columns {
+		if c.Field == "" || contains(jira.DefaultSearchFields, c.Field) || contains(fields, c.Field) {
+			continue
+		}
+		fields = append(fields, c.Field)
+	}
+	return fields
+}
+
+// FieldValue renders a raw field value for a table cell: names of objects
+// such as sprints or components, numbers without trailing zeros, and lists
+// joined with commas.
+func FieldValue(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return string(raw)
+	}
+	return cellValue(v)
+}
+
+// cellValue renders a decoded JSON value.
+func cellValue(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case bool:
+		return strconv.FormatBool(t)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case []any:
+		parts := make([]string, 0, len(t))
+		for _, item := range t {
+			if s := cellValue(item); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, ", ")
+	case map[string]any:
+		for _, key := range []string{"name", "displayName", "value", "key"} {
+			if s, ok := t[key].(string); ok {
+				return s
+			}
+		}
+		b, _ := json.Marshal(t)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// formatTime renders a timestamp as RFC 3339, or nothing when unset.
+func formatTime(t jira.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// formatDate renders a date, or nothing when unset.
+func formatDate(t jira.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// contains reports whether list holds s.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package output
+
+import (
+	"fmt"
+
+	"jirar/internal/jira"
+)
+
+// IssueList is the JSON envelope for issue results.
+type IssueList struct {
+	Issues []IssueItem `json:"issues"`
+	Total  int         `json:"total"`
+	Start  int         `json:"start"`
+	Limit  int         `json:"limit"`
+}
+
+// IssueItem is an issue with its browse URL.
+type IssueItem struct {
+	jira.Issue
+	URL string `json:"url"`
+}
+
+// BrowseURL returns the web URL of an issue.
+func BrowseURL(domain, key string) string {
+	return fmt.Sprintf("%s/browse/%s", domain, key)
+}
+
+// Issues builds the result for a search page with a table of the given
+// columns. Table cells are styled with s when it is not nil.
+func Issues(domain string, res *jira.SearchResult, limit int, columns []Column, s Styler) Result {
+	list := IssueList{
+		Issues: make([]IssueItem, 0, len(res.Issues)),
+		Total:  res.Total,
+		Start:  res.StartAt,
+		Limit:  limit,
+	}
+	records := make([]any, 0, len(res.Issues))
+	keys := make([]string, 0, len(res.Issues))
+	table := Table{}
+	for _, c := range columns {
+		table.Headers = append(table.Headers, c.Header)
+	}
+
+	for _, issue := range res.Issues {
+		item := IssueItem{Issue: issue, URL: BrowseURL(domain, issue.Key)}
+		list.Issues = append(list.Issues, item)
+		records = append(records, item)
+		keys = append(keys, issue.Key)
+
+		row := make([]string, len(columns))
+		for i, c := range columns {
+			row[i] = c.Render(item, s)
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	return Result{Data: list, Records: records, Table: table, Keys: keys}
+}
@@ -0,0 +1,75 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/itchyny/gojq"
+)
+
+// compileJQ parses and compiles a jq expression.
+func compileJQ(expr string) (*gojq.Code, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("parse --jq expression: %w", err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("compile --jq expression: %w", err)
+	}
+	return code, nil
+}
+
+// Filter runs the printer's jq expression over a raw JSON document, such as
+// an API response body.
+func (p *Printer) Filter(w io.Writer, data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("--jq needs a JSON document: %w", err)
+	}
+	return p.runJQ(w, v)
+}
+
+// filterValue runs the jq expression over the JSON model of v.
+func (p *Printer) filterValue(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode output: %w", err)
+	}
+	return p.Filter(w, data)
+}
+
+// runJQ writes each result of the jq expression: strings as raw lines and
+// anything else as indented JSON.
+func (p *Printer) runJQ(w io.Writer, input any) error {
+	iter := p.query.Run(input)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			return nil
+		}
+		if err, ok := v.(error); ok {
+			var halt *gojq.HaltError
+			if errors.As(err, &halt) && halt.Value() == nil {
+				return nil
+			}
+			return fmt.Errorf("--jq: %w", err)
+		}
+
+		if s, ok := v.(string); ok {
+			fmt.Fprintln(w, s)
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := writeJSON(&buf, v, true); err != nil {
+			return err
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+}
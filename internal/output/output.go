@@ -0,0 +1,298 @@
+// Package output renders command results in the format chosen with -o.
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/itchyny/gojq"
+	"go.yaml.in/yaml/v3"
+)
+
+// Supported formats.
+const (
+	FormatTable    = "table"
+	FormatJSON     = "json"
+	FormatYAML     = "yaml"
+	FormatCSV      = "csv"
+	FormatTSV      = "tsv"
+	FormatNDJSON   = "ndjson"
+	FormatMarkdown = "markdown"
+	FormatKeys     = "keys"
+	FormatTemplate = "template"
+)
+
+// Formats lists the accepted values of -o in help order.
+var Formats = []string{
+	FormatTable, FormatJSON, FormatYAML, FormatCSV, FormatTSV,
+	FormatNDJSON, FormatMarkdown, FormatKeys, FormatTemplate + "=...",
+}
+
+// Options selects and configures an output format.
+type Options struct {
+	// Format is one of Formats; "template=TEXT" carries the template inline
+	Format string
+	// Template is a Go template applied to the JSON model
+	Template string
+	// JQ is a jq expression applied to the JSON model instead of a format
+	JQ string
+	// Width is the terminal width tables are fitted to; 0 disables fitting
+	Width int
+	// Compact draws tables without borders
+	Compact bool
+}
+
+// Table is the tabular view of a result.
+type Table struct {
+	Headers []string
+	Rows    [][]string
+}
+
+// Result is what a command hands to a Printer.
+type Result struct {
+	// Data is the structured value rendered by json, yaml and template
+	Data any
+	// Records are written one per line by ndjson; defaults to Data
+	Records []any
+	// Table is rendered by table, csv, tsv and markdown
+	Table Table
+	// Keys are written one per line by keys; defaults to the first column
+	Keys []string
+	// Human, when set, replaces the table rendering of the table format
+	Human func(w io.Writer) error
+}
+
+// Printer renders results in one format.
+type Printer struct {
+	format   string
+	template *template.Template
+	query    *gojq.Code
+	width    int
+	compact  bool
+}
+
+// New creates a Printer for opts.
+func New(opts Options) (*Printer, error) {
+	format, text, _ := strings.Cut(opts.Format, "=")
+	if format == "" {
+		format = FormatTable
+	}
+	if opts.Template != "" {
+		format, text = FormatTemplate, opts.Template
+	}
+
+	p := &Printer{format: format, width: opts.Width, compact: opts.Compact}
+	if opts.JQ != "" {
+		if opts.Template != "" {
+			return nil, fmt.Errorf("--jq and --template cannot be combined")
+		}
+		code, err := compileJQ(opts.JQ)
+		if err != nil {
+			return nil, err
+		}
+		p.format, p.query = FormatJSON, code
+		return p, nil
+	}
+
+	switch format {
+	case FormatTable, FormatJSON, FormatYAML, FormatCSV, FormatTSV, FormatNDJSON, FormatMarkdown, FormatKeys:
+	case FormatTemplate:
+		if text == "" {
+			return nil, fmt.Errorf("template format needs a template: -o template='{{...}}' or --template")
+		}
+		tmpl, err := template.New("output").Funcs(TemplateFuncs()).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("parse template: %w", err)
+		}
+		p.template = tmpl
+	default:
+		return nil, fmt.Errorf("unknown output format %q (use %s)", format, strings.Join(Formats, ", "))
+	}
+	return p, nil
+}
+
+// Format returns the selected format name.
+func (p *Printer) Format() string {
+	return p.format
+}
+
+// Width returns the terminal width output is fitted to, or 0 when output
+// is not a terminal.
+func (p *Printer) Width() int {
+	return p.width
+}
+
+// Filtering reports whether output goes through a jq expression.
+func (p *Printer) Filtering() bool {
+	return p.query != nil
+}
+
+// Structured reports whether the format renders the JSON model.
+func (p *Printer) Structured() bool {
+	switch p.format {
+	case FormatJSON, FormatYAML, FormatNDJSON, FormatTemplate:
+		return true
+	}
+	return false
+}
+
+// Render writes r to w.
+func (p *Printer) Render(w io.Writer, r Result) error {
+	if p.query != nil {
+		return p.filterValue(w, r.Data)
+	}
+
+	switch p.format {
+	case FormatJSON:
+		return writeJSON(w, r.Data, true)
+	case FormatNDJSON:
+		records := r.Records
+		if records == nil {
+			records = []any{r.Data}
+		}
+		for _, record := range records {
+			if err := writeJSON(w, record, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatYAML:
+		return writeYAML(w, r.Data)
+	case FormatTemplate:
+		data, err := Generic(r.Data)
+		if err != nil {
+			return err
+		}
+		if err := p.template.Execute(w, data); err != nil {
+			return fmt.Errorf("execute template: %w", err)
+		}
+		return nil
+	case FormatCSV:
+		return writeDelimited(w, r.Table, ',')
+	case FormatTSV:
+		return writeDelimited(w, r.Table, '\t')
+	case FormatMarkdown:
+		return writeMarkdown(w, r.Table)
+	case FormatKeys:
+		keys := r.Keys
+		if keys == nil {
+			for _, row := range r.Table.Rows {
+				if len(row) > 0 {
+					keys = append(keys, row[0])
+				}
+			}
+		}
+		for _, key := range keys {
+			fmt.Fprintln(w, key)
+		}
+		return nil
+	default:
+		if r.Human != nil {
+			return r.Human(w)
+		}
+		return writeTable(w, r.Table, p.width, p.compact)
+	}
+}
+
+// Generic converts v into the maps, slices and scalars of its JSON
+// encoding, so templates and filters see the same model as -o json.
+func Generic(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode output: %w", err)
+	}
+
+	var out any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode output: %w", err)
+	}
+	return out, nil
+}
+
+// writeJSON encodes v as JSON, indented or on a single line.
+func writeJSON(w io.Writer, v any, indent bool) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("encode json: %w", err)
+	}
+	return nil
+}
+
+// writeYAML encodes v as YAML with the key order of its JSON encoding.
+func writeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode yaml: %w", err)
+	}
+
+	// JSON is YAML, so parsing it keeps field order
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return fmt.Errorf("encode yaml: %w", err)
+	}
+	blockStyle(&node)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return fmt.Errorf("encode yaml: %w", err)
+	}
+	return enc.Close()
+}
+
+// blockStyle clears flow and quoting styles inherited from the JSON source.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, child := range n.Content {
+		blockStyle(child)
+	}
+}
+
+// writeDelimited writes the table as CSV or TSV with a header row.
+func writeDelimited(w io.Writer, t Table, comma rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if err := cw.Write(t.Headers); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(t.Rows); err != nil {
+		return fmt.Errorf("write rows: %w", err)
+	}
+	return nil
+}
+
+// writeMarkdown writes the table as a GitHub-flavoured Markdown table.
+func writeMarkdown(w io.Writer, t Table) error {
+	escape := func(s string) string {
+		return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+	}
+	row := func(cells []string) {
+		escaped := make([]string, len(cells))
+		for i, c := range cells {
+			escaped[i] = escape(c)
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(escaped, " | "))
+	}
+
+	row(t.Headers)
+	sep := make([]string, len(t.Headers))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	fmt.Fprintf(w, "|%s|\n", strings.Join(sep, "|"))
+	for _, r := range t.Rows {
+		row(r)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+)
+
+// minColumnWidth is the narrowest a column is squeezed to when fitting.
+const minColumnWidth = 5
+
+// escapePattern matches ANSI color sequences and OSC 8 hyperlink markers.
+var escapePattern = regexp.MustCompile("\x1b\\[[0-9;]*m|\x1b\\]8;[^\x1b\a]*(\x1b\\\\|\a)")
+
+// StripEscapes removes terminal escape sequences from s.
+func StripEscapes(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	return escapePattern.ReplaceAllString(s, "")
+}
+
+// cellWidth returns the display width of s without escape sequences.
+func cellWidth(s string) int {
+	return runewidth.StringWidth(StripEscapes(s))
+}
+
+// truncateCell shortens s to width columns. Styled cells that need cutting
+// lose their styling.
+func truncateCell(s string, width int) string {
+	if cellWidth(s) <= width {
+		return s
+	}
+	return runewidth.Truncate(StripEscapes(s), width, "…")
+}
+
+// writeTable renders the table for the terminal. Columns whose values are
+// all numbers are right-aligned, and with a width the widest columns are
+// truncated with an ellipsis until the table fits.
+func writeTable(w io.Writer, t Table, width int, compact bool) error {
+	if width > 0 {
+		t = fitTable(t, width, compact)
+	}
+	if compact {
+		return writeCompact(w, t)
+	}
+
+	align := make(tw.Alignment, len(t.Headers))
+	for i := range t.Headers {
+		align[i] = tw.AlignLeft
+		if numericColumn(t, i) {
+			align[i] = tw.AlignRight
+		}
+	}
+
+	table := tablewriter.NewTable(w,
+		tablewriter.WithRowAutoWrap(tw.WrapNone),
+		tablewriter.WithHeaderAutoWrap(tw.WrapNone),
+		tablewriter.WithRowAlignmentConfig(tw.CellAlignment{Global: tw.AlignLeft, PerColumn: align}),
+	)
+	table.Header(t.Headers)
+	if err := table.Bulk(t.Rows); err != nil {
+		return fmt.Errorf("render table: %w", err)
+	}
+	return table.Render()
+}
+
+// writeCompact renders the table without borders, one line per row with
+// columns separated by two spaces.
+func writeCompact(w io.Writer, t Table) error {
+	widths := columnWidths(t)
+	line := func(cells []string) {
+		var b strings.Builder
+		for i, cell := range cells {
+			pad := strings.Repeat(" ", max(widths[i]-cellWidth(cell), 0))
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			if numericColumn(t, i) {
+				b.WriteString(pad + cell)
+			} else if i < len(cells)-1 {
+				b.WriteString(cell + pad)
+			} else {
+				b.WriteString(cell)
+			}
+		}
+		fmt.Fprintln(w, b.String())
+	}
+
+	headers := make([]string, len(t.Headers))
+	for i, h := range t.Headers {
+		headers[i] = strings.ToUpper(h)
+	}
+	line(headers)
+	for _, row := range t.Rows {
+		line(row)
+	}
+	return nil
+}
+
+// columnWidths returns the display width of each column.
+func columnWidths(t Table) []int {
+	widths := make([]int, len(t.Headers))
+	for i, h := range t.Headers {
+		widths[i] = cellWidth(h)
+	}
+	for _, row := range t.Rows {
+		for i, cell := range row {
+			if i < len(widths) {
+				widths[i] = max(widths[i], cellWidth(cell))
+			}
+		}
+	}
+	return widths
+}
+
+// fitTable truncates cells so the rendered table is at most width wide.
+func fitTable(t Table, width int, compact bool) Table {
+	widths := columnWidths(t)
+
+	// Each column adds a border and a space of padding on both sides, or
+	// a two-space gap in compact tables
+	budget := width - 3*len(widths) - 1
+	if compact {
+		budget = width - 2*(len(widths)-1)
+	}
+	total := 0
+	for _, w := range widths {
+		total += w
+	}
+	if total <= budget {
+		return t
+	}
+
+	for total > budget {
+		widest := 0
+		for i, w := range widths {
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= minColumnWidth {
+			break
+		}
+		widths[widest]--
+		total--
+	}
+
+	fitted := Table{Headers: make([]string, len(t.Headers)), Rows: make([][]string, len(t.Rows))}
+	for i, h := range t.Headers {
+		fitted.Headers[i] = truncateCell(h, widths[i])
+	}
+	for r, row := range t.Rows {
+		fitted.Rows[r] = make([]string, len(row))
+		for i, cell := range row {
+			if i < len(widths) {
+				cell = truncateCell(cell, widths[i])
+			}
+			fitted.Rows[r][i] = cell
+		}
+	}
+	return fitted
+}
+
+// numericColumn reports whether every non-empty cell of column i is a number.
+func numericColumn(t Table, i int) bool {
+	numeric := false
+	for _, row := range t.Rows {
+		if i >= len(row) || row[i] == "" {
+			continue
+		}
+		if _, err := strconv.ParseFloat(StripEscapes(row[i]), 64); err != nil {
+			return false
+		}
+		numeric = true
+	}
+	return numeric
+}
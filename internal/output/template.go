@@ -0,0 +1,130 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// timeLayouts are the timestamp formats accepted by the time helpers.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.000-0700",
+	"2006-01-02",
+}
+
+// TemplateFuncs returns the helper functions available to --template.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"join": func(sep string, v any) string {
+			items, _ := v.([]any)
+			parts := make([]string, len(items))
+			for i, item := range items {
+				parts[i] = fmt.Sprint(item)
+			}
+			return strings.Join(parts, sep)
+		},
+		"pluck": func(field string, v any) []any {
+			items, _ := v.([]any)
+			out := make([]any, 0, len(items))
+			for _, item := range items {
+				if m, ok := item.(map[string]any); ok {
+					out = append(out, m[field])
+				}
+			}
+			return out
+		},
+		"upper":    strings.ToUpper,
+		"lower":    strings.ToLower,
+		"trim":     strings.TrimSpace,
+		"replace":  func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains": func(sub, s string) bool { return strings.Contains(s, sub) },
+		"truncate": Truncate,
+		"pad": func(width int, v any) string {
+			return fmt.Sprintf("%-*s", width, fmt.Sprint(v))
+		},
+		"default": func(def, v any) any {
+			if v == nil || v == "" {
+				return def
+			}
+			return v
+		},
+		"date": func(layout string, v any) string {
+			t, ok := ParseTime(v)
+			if !ok {
+				return ""
+			}
+			return t.Local().Format(layout)
+		},
+		"timeago": func(v any) string {
+			t, ok := ParseTime(v)
+			if !ok {
+				return ""
+			}
+			return TimeAgo(t, time.Now())
+		},
+	}
+}
+
+// ParseTime interprets a time.Time or a timestamp string from the JSON model.
+func ParseTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, !t.IsZero()
+	case string:
+		for _, layout := range timeLayouts {
+			if parsed, err := time.Parse(layout, t); err == nil {
+				return parsed, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// TimeAgo describes t relative to now, e.g. "3h ago" or "in 2d".
+func TimeAgo(t, now time.Time) string {
+	d := now.Sub(t)
+	suffix := " ago"
+	if d < 0 {
+		d, suffix = -d, ""
+	}
+
+	var s string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		s = fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		s = fmt.Sprintf("%dh", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		s = fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d < 365*24*time.Hour:
+		s = fmt.Sprintf("%dmo", int(d.Hours()/24/30))
+	default:
+		s = fmt.Sprintf("%dy", int(d.Hours()/24/365))
+	}
+
+	if suffix == "" {
+		return "in " + s
+	}
+	return s + suffix
+}
+
+// Truncate shortens s to at most n runes, ending with an ellipsis.
+func Truncate(n int, s string) string {
+	r := []rune(s)
+	if n <= 0 || len(r) <= n {
+		return s
+	}
+	if n == 1 {
+		return "…"
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,142 @@
+// Package recent keeps the issues a user visited and was assigned in a
+// small per-profile cache, so pickers have something to offer before the
+// first request returns.
+package recent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"jirar/internal/jira"
+)
+
+// maxVisits is the number of visited issues kept.
+const maxVisits = 50
+
+// Visit is an issue the user looked at.
+type Visit struct {
+	Issue   jira.Issue `json:"issue"`
+	Visited time.Time  `json:"visited"`
+}
+
+// Cache holds the recently visited and the assigned issues of a profile.
+type Cache struct {
+	Path       string       `json:"-"`
+	Visits     []Visit      `json:"visits"`
+	Assigned   []jira.Issue `json:"assigned"`
+	AssignedAt time.Time    `json:"assignedAt"`
+}
+
+// DefaultPath returns the cache file of a profile; an empty profile uses
+// the top-level configuration.
+func DefaultPath(profile string) string {
+	if profile == "" {
+		profile = "default"
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = filepath.Join(os.TempDir(), "jirar-cache")
+	}
+	return filepath.Join(dir, "jirar", profile, "recent.json")
+}
+
+// Load reads the cache at path. A missing or unreadable file yields an
+// empty cache, since it only ever holds hints.
+func Load(path string) (*Cache, error) {
+	c := &Cache{Path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, fmt.Errorf("read recent issues: %w", err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return &Cache{Path: path}, fmt.Errorf("parse recent issues: %w", err)
+	}
+	return c, nil
+}
+
+// Visit records that issue was visited at t, moving it to the front. Fields
+// missing from issue are kept from an earlier visit.
+func (c *Cache) Visit(issue jira.Issue, t time.Time) {
+	visits := []Visit{{Issue: issue, Visited: t}}
+	for _, v := range c.Visits {
+		if v.Issue.Key != issue.Key {
+			visits = append(visits, v)
+			continue
+		}
+		if issue.Fields.Summary == "" {
+			visits[0].Issue = v.Issue
+		}
+	}
+	if len(visits) > maxVisits {
+		visits = visits[:maxVisits]
+	}
+	c.Visits = visits
+}
+
+// SetAssigned replaces the assigned issues, fetched at t.
+func (c *Cache) SetAssigned(issues []jira.Issue, t time.Time) {
+	c.Assigned, c.AssignedAt = issues, t
+}
+
+// Issues returns the visited issues, most recent first, followed by the
+// assigned issues not visited. Visited issues take the fresher fields of
+// their assigned copy.
+func (c *Cache) Issues() []jira.Issue {
+	assigned := make(map[string]jira.Issue, len(c.Assigned))
+	for _, issue := range c.Assigned {
+		assigned[issue.Key] = issue
+	}
+
+	issues := make([]jira.Issue, 0, len(c.Visits)+len(c.Assigned))
+	seen := map[string]bool{}
+	for _, v := range c.Visits {
+		issue := v.Issue
+		if fresh, ok := assigned[issue.Key]; ok {
+			issue = fresh
+		}
+		issues = append(issues, issue)
+		seen[issue.Key] = true
+	}
+	for _, issue := range c.Assigned {
+		if !seen[issue.Key] {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// Save writes the cache, replacing the file atomically.
+func (c *Cache) Save() error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("encode recent issues: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0o700); err != nil {
+		return fmt.Errorf("create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.Path), ".recent-*")
+	if err != nil {
+		return fmt.Errorf("write recent issues: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write recent issues: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write recent issues: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.Path); err != nil {
+		return fmt.Errorf("write recent issues: %w", err)
+	}
+	return nil
+}
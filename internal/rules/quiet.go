@@ -0,0 +1,108 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Quiet describes when notifications are held back: a daily range of hours
+// and optionally the whole weekend, in the user's timezone.
+type Quiet struct {
+	// Start and End are the times of day of the range; equal means none.
+	// A range past midnight, such as 22:00-08:00, has End before Start.
+	Start, End time.Duration
+	// Weekends holds back notifications on Saturdays and Sundays
+	Weekends bool
+	// Location is the user's timezone; nil is local time
+	Location *time.Location
+}
+
+// ParseQuiet parses a range such as "22:00-08:00"; empty means no quiet
+// hours.
+func ParseQuiet(hours string, weekends bool, loc *time.Location) (Quiet, error) {
+	q := Quiet{Weekends: weekends, Location: loc}
+	if strings.TrimSpace(hours) == "" {
+		return q, nil
+	}
+	from, to, ok := strings.Cut(hours, "-")
+	if !ok {
+		return q, fmt.Errorf("invalid quiet hours %q (use a range such as 22:00-08:00)", hours)
+	}
+	var err error
+	if q.Start, err = parseClock(from); err != nil {
+		return q, fmt.Errorf("invalid quiet hours %q: %w", hours, err)
+	}
+	if q.End, err = parseClock(to); err != nil {
+		return q, fmt.Errorf("invalid quiet hours %q: %w", hours, err)
+	}
+	return q, nil
+}
+
+// parseClock parses a time of day such as 8:00 or 22:30.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time such as 22:00", strings.TrimSpace(s))
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Active reports whether t falls in the quiet time.
+func (q Quiet) Active(t time.Time) bool {
+	t = q.in(t)
+	if q.Weekends && (t.Weekday() == time.Saturday || t.Weekday() == time.Sunday) {
+		return true
+	}
+	if q.Start == q.End {
+		return false
+	}
+	clock := clockOf(t)
+	if q.Start < q.End {
+		return clock >= q.Start && clock < q.End
+	}
+	return clock >= q.Start || clock < q.End
+}
+
+// Until returns when the quiet time around t ends, or t if it is not quiet.
+func (q Quiet) Until(t time.Time) time.Time {
+	t = q.in(t)
+	// A weekend and the hours around it end within a few steps
+	for i := 0; i < 8 && q.Active(t); i++ {
+		day := midnight(t)
+		if q.Weekends && (t.Weekday() == time.Saturday || t.Weekday() == time.Sunday) {
+			t = day.AddDate(0, 0, 1)
+			continue
+		}
+		end := at(day, q.End)
+		if q.Start > q.End && clockOf(t) >= q.Start {
+			end = at(day.AddDate(0, 0, 1), q.End)
+		}
+		t = end
+	}
+	return t
+}
+
+// in converts t to the quiet time's location.
+func (q Quiet) in(t time.Time) time.Time {
+	if q.Location == nil {
+		return t.Local()
+	}
+	return t.In(q.Location)
+}
+
+// at returns the time of day clock on day, which is also right on days
+// with a daylight saving change.
+func at(day time.Time, clock time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(clock/time.Hour), int(clock%time.Hour/time.Minute), 0, 0, day.Location())
+}
+
+// clockOf returns the time of day of t, to the minute.
+func clockOf(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+// midnight returns the start of t's day in its location.
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
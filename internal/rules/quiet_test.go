@@ -0,0 +1,132 @@
+package rules
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// berlin changes to summer time on 2026-03-29 and back on 2026-10-25.
+var berlin = func() *time.Location {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}()
+
+// wall parses a wall clock time in Berlin; 2026-10-14 is a Wednesday.
+func wall(t *testing.T, s string) time.Time {
+	t.Helper()
+	tm, err := time.ParseInLocation("2006-01-02 15:04", s, berlin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tm
+}
+
+// quiet parses hours for Berlin.
+func quiet(t *testing.T, hours string, weekends bool) Quiet {
+	t.Helper()
+	q, err := ParseQuiet(hours, weekends, berlin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func TestParseQuiet(t *testing.T) {
+	q, err := ParseQuiet(" 22:30 - 7:05 ", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Start != 22*time.Hour+30*time.Minute || q.End != 7*time.Hour+5*time.Minute {
+		t.Errorf("range = %s-%s, want 22h30m-7h5m", q.Start, q.End)
+	}
+	for _, hours := range []string{"22:00", "22:00-25:00", "night-08:00", "22-08"} {
+		if _, err := ParseQuiet(hours, false, nil); err == nil {
+			t.Errorf("ParseQuiet(%q) succeeded, want an error", hours)
+		}
+	}
+}
+
+func TestQuietActive(t *testing.T) {
+	tests := []struct {
+		name     string
+		hours    string
+		weekends bool
+		at       string
+		want     bool
+	}{
+		{"before midnight", "22:00-08:00", false, "2026-10-14 23:30", true},
+		{"at the start", "22:00-08:00", false, "2026-10-14 22:00", true},
+		{"before the start", "22:00-08:00", false, "2026-10-14 21:59", false},
+		{"a minute before the end", "22:00-08:00", false, "2026-10-14 07:59", true},
+		{"at the end", "22:00-08:00", false, "2026-10-14 08:00", false},
+		{"midday", "22:00-08:00", false, "2026-10-14 12:00", false},
+		{"within a range on one day", "12:00-13:30", false, "2026-10-14 12:00", true},
+		{"at the end of a range on one day", "12:00-13:30", false, "2026-10-14 13:30", false},
+		{"an empty range", "08:00-08:00", false, "2026-10-14 08:00", false},
+		{"no hours", "", false, "2026-10-14 23:30", false},
+		{"Saturday with weekends", "", true, "2026-10-17 12:00", true},
+		{"Sunday night with weekends", "22:00-08:00", true, "2026-10-18 23:59", true},
+		{"Monday morning after a weekend", "", true, "2026-10-19 00:00", false},
+		{"Friday evening before a weekend", "", true, "2026-10-16 23:00", false},
+		{"Saturday without weekends", "22:00-08:00", false, "2026-10-17 12:00", false},
+		{"morning of the change to summer time", "22:00-08:00", false, "2026-03-29 07:30", true},
+		{"after the skipped hour", "01:00-03:00", false, "2026-03-29 03:30", false},
+		{"the repeated hour", "01:00-03:00", false, "2026-10-25 02:30", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quiet(t, tt.hours, tt.weekends).Active(wall(t, tt.at)); got != tt.want {
+				t.Errorf("%s at %s = %v, want %v", tt.hours, tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuietActiveInLocation(t *testing.T) {
+	// 21:30 UTC is 23:30 in Berlin summer time
+	utc := time.Date(2026, 10, 14, 21, 30, 0, 0, time.UTC)
+	if !quiet(t, "22:00-08:00", false).Active(utc) {
+		t.Errorf("%s is not quiet in Berlin", utc)
+	}
+	if q, _ := ParseQuiet("22:00-08:00", false, time.UTC); q.Active(utc) {
+		t.Errorf("%s is quiet in UTC", utc)
+	}
+}
+
+func TestQuietUntil(t *testing.T) {
+	tests := []struct {
+		name     string
+		hours    string
+		weekends bool
+		at       string
+		want     string
+		// elapsed is the real time until want when a clock change
+		// falls in between
+		elapsed time.Duration
+	}{
+		{name: "before midnight", hours: "22:00-08:00", at: "2026-10-14 23:30", want: "2026-10-15 08:00"},
+		{name: "after midnight", hours: "22:00-08:00", at: "2026-10-14 07:59", want: "2026-10-14 08:00"},
+		{name: "not quiet", hours: "22:00-08:00", at: "2026-10-14 12:00", want: "2026-10-14 12:00"},
+		{name: "a range on one day", hours: "12:00-13:30", at: "2026-10-14 12:15", want: "2026-10-14 13:30"},
+		{name: "a weekend", weekends: true, at: "2026-10-17 10:00", want: "2026-10-19 00:00"},
+		{name: "a night into a weekend", hours: "22:00-08:00", weekends: true, at: "2026-10-16 23:00", want: "2026-10-19 08:00"},
+		{name: "a night before the change to summer time", hours: "22:00-08:00", at: "2026-03-28 23:30", want: "2026-03-29 08:00", elapsed: 7*time.Hour + 30*time.Minute},
+		{name: "a night before the change to winter time", hours: "22:00-08:00", at: "2026-10-24 23:30", want: "2026-10-25 08:00", elapsed: 9*time.Hour + 30*time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := wall(t, tt.at)
+			got := quiet(t, tt.hours, tt.weekends).Until(from)
+			if want := wall(t, tt.want); !got.Equal(want) {
+				t.Errorf("%s from %s = %s, want %s", tt.hours, tt.at, got, want)
+			}
+			if tt.elapsed != 0 && got.Sub(from) != tt.elapsed {
+				t.Errorf("%s from %s ends after %s, want %s", tt.hours, tt.at, got.Sub(from), tt.elapsed)
+			}
+		})
+	}
+}
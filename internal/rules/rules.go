@@ -0,0 +1,326 @@
+// Package rules decides where and when watch events are delivered. Rules
+// match events by type and by an expression over the event and its issue,
+// and send them to sinks at once or batched into periodic digests. Events
+// arriving during quiet hours are held until they end.
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"github.com/sirupsen/logrus"
+
+	"jirar/internal/jira/watcher"
+	"jirar/internal/notify"
+)
+
+// maxHeld caps the events a rule holds for its next digest; the oldest are
+// dropped first.
+const maxHeld = 500
+
+// Rule sends the events it matches to sinks.
+type Rule struct {
+	Name string
+	// Filter is the JQL of the watcher whose events the rule sees; empty
+	// sees the events of every source
+	Filter string
+	// Events are the event types matched; empty matches all
+	Events []watcher.EventType
+	// When is an expression over event and issue, e.g. event.type ==
+	// "comment" && issue.fields.priority.name in ["Highest", "High"];
+	// empty matches all
+	When string
+	// Sinks name the notifiers of matched events
+	Sinks []string
+	// Digest batches matched events into one message per period; zero
+	// sends each event at once
+	Digest time.Duration
+	// IgnoreQuiet sends events during quiet hours
+	IgnoreQuiet bool
+
+	program *vm.Program
+}
+
+// Options configure an Engine.
+type Options struct {
+	Rules []Rule
+	Quiet Quiet
+	// Notifiers are the sinks by name
+	Notifiers map[string]notify.Notifier
+	// StatePath keeps held events across restarts; empty keeps them in
+	// memory only
+	StatePath string
+	Logger    *logrus.Logger
+}
+
+// Engine routes events to notifiers by rules.
+type Engine struct {
+	opts   Options
+	rules  []*Rule
+	logger *logrus.Logger
+	now    func() time.Time
+
+	mu sync.Mutex
+	// held are the events waiting for a digest, by rule name
+	held map[string]*batch
+}
+
+// batch is the events a rule holds until they are due.
+type batch struct {
+	Due    time.Time       `json:"due"`
+	Events []watcher.Event `json:"events"`
+}
+
+// DefaultStatePath returns the file of held events under the user cache
+// directory.
+func DefaultStatePath(profile string) string {
+	if profile == "" {
+		profile = "default"
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = filepath.Join(os.TempDir(), "jirar-cache")
+	}
+	return filepath.Join(dir, "jirar", profile, "held.json")
+}
+
+// Compile checks the rule's expression, so errors surface before the first
+// event.
+func (r *Rule) Compile() error {
+	for _, t := range r.Events {
+		if !slices.Contains(watcher.EventTypes, t) {
+			return fmt.Errorf("rule %s: unknown event type %q", r.Name, t)
+		}
+	}
+	if r.When == "" {
+		return nil
+	}
+	env := map[string]any{"event": map[string]any{}, "issue": map[string]any{}}
+	program, err := expr.Compile(r.When, expr.Env(env), expr.AsBool())
+	if err != nil {
+		return fmt.Errorf("rule %s: invalid condition: %w", r.Name, err)
+	}
+	r.program = program
+	return nil
+}
+
+// Match reports whether the rule takes an event found by the watcher of
+// filter; an empty filter is any source. env is the expression
+// environment from Env.
+func (r *Rule) Match(filter string, e watcher.Event, env map[string]any) (bool, error) {
+	if r.Filter != "" && filter != "" && r.Filter != filter {
+		return false, nil
+	}
+	if len(r.Events) > 0 && !slices.Contains(r.Events, e.Type) {
+		return false, nil
+	}
+	if r.program == nil {
+		return true, nil
+	}
+	out, err := expr.Run(r.program, env)
+	if err != nil {
+		return false, fmt.Errorf("rule %s: %w", r.Name, err)
+	}
+	return out.(bool), nil
+}
+
+// Env returns the expression environment of an event: event and issue as
+// in the JSON output, e.g. issue.fields.status.name.
+func Env(e watcher.Event) (map[string]any, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("encode event: %w", err)
+	}
+	var event map[string]any
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("decode event: %w", err)
+	}
+	return map[string]any{"event": event, "issue": event["issue"]}, nil
+}
+
+// New compiles the rules and loads the events held by a previous run.
+func New(opts Options) (*Engine, error) {
+	if opts.Logger == nil {
+		opts.Logger = logrus.StandardLogger()
+	}
+	e := &Engine{opts: opts, logger: opts.Logger, now: time.Now, held: map[string]*batch{}}
+	for i := range opts.Rules {
+		r := &opts.Rules[i]
+		if err := r.Compile(); err != nil {
+			return nil, err
+		}
+		for _, name := range r.Sinks {
+			if opts.Notifiers[name] == nil {
+				return nil, fmt.Errorf("rule %s: unknown sink %q", r.Name, name)
+			}
+		}
+		e.rules = append(e.rules, r)
+	}
+
+	if opts.StatePath == "" {
+		return e, nil
+	}
+	data, err := os.ReadFile(opts.StatePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read held events: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &e.held); err != nil {
+			e.logger.WithError(err).Warn("Dropping unreadable held events")
+			e.held = map[string]*batch{}
+		}
+	}
+	return e, nil
+}
+
+// Handle routes an event found by the watcher of filter, or pushed from
+// elsewhere when filter is empty. Each sink gets the event at most once
+// at a time; a rule that batches or is quiet holds it for later.
+func (e *Engine) Handle(ctx context.Context, filter string, ev watcher.Event) {
+	env, err := Env(ev)
+	if err != nil {
+		e.logger.WithError(err).Warn("Cannot evaluate rules")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.now()
+	quiet := e.opts.Quiet.Active(now)
+	var sinks []string
+	held := false
+	for _, r := range e.rules {
+		ok, err := r.Match(filter, ev, env)
+		if err != nil {
+			e.logger.WithError(err).WithField("key", ev.Key).Warn("Rule failed")
+		}
+		if !ok {
+			continue
+		}
+		if r.Digest <= 0 && (!quiet || r.IgnoreQuiet) {
+			sinks = append(sinks, r.Sinks...)
+			continue
+		}
+
+		b := e.held[r.Name]
+		if b == nil {
+			b = &batch{Due: now.Add(r.Digest)}
+			if r.Digest <= 0 {
+				b.Due = e.opts.Quiet.Until(now)
+			}
+			e.held[r.Name] = b
+		}
+		b.Events = append(b.Events, ev)
+		if len(b.Events) > maxHeld {
+			b.Events = b.Events[len(b.Events)-maxHeld:]
+		}
+		held = true
+		e.logger.WithFields(logrus.Fields{"rule": r.Name, "key": ev.Key, "due": b.Due}).Debug("Holding event")
+	}
+
+	slices.Sort(sinks)
+	for _, name := range slices.Compact(sinks) {
+		if err := e.opts.Notifiers[name].Notify(ctx, ev); err != nil {
+			e.logger.WithError(err).WithField("sink", name).Warn("Notification failed")
+		}
+	}
+	if held {
+		e.save()
+	}
+}
+
+// Flush sends the digests that are due, unless quiet hours hold them.
+// Events held for rules the engine does not run wait for a run that does.
+func (e *Engine) Flush(ctx context.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.now()
+	changed := false
+	for _, r := range e.rules {
+		b := e.held[r.Name]
+		if b == nil || now.Before(b.Due) {
+			continue
+		}
+		if !r.IgnoreQuiet && e.opts.Quiet.Active(now) {
+			b.Due = e.opts.Quiet.Until(now)
+			changed = true
+			continue
+		}
+
+		for _, name := range r.Sinks {
+			if err := e.opts.Notifiers[name].Digest(ctx, b.Events); err != nil {
+				e.logger.WithError(err).WithField("sink", name).Warn("Digest failed")
+			}
+		}
+		e.logger.WithFields(logrus.Fields{"rule": r.Name, "events": len(b.Events)}).Debug("Sent digest")
+		delete(e.held, r.Name)
+		changed = true
+	}
+	if changed {
+		e.save()
+	}
+}
+
+// Run flushes due digests and retries undelivered messages every interval
+// until ctx is cancelled.
+func (e *Engine) Run(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		e.Flush(ctx)
+		for name, n := range e.opts.Notifiers {
+			sink, ok := n.(*notify.Sink)
+			if !ok {
+				continue
+			}
+			if err := sink.Flush(ctx); err != nil && ctx.Err() == nil {
+				e.logger.WithError(err).WithField("sink", name).Warn("Notification retry failed")
+			}
+		}
+	}
+}
+
+// Close closes the notifiers.
+func (e *Engine) Close() error {
+	var errs []error
+	for _, n := range e.opts.Notifiers {
+		errs = append(errs, n.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// save writes the held events; e.mu must be held. Failures are logged, as
+// the events are still delivered by this run.
+func (e *Engine) save() {
+	path := e.opts.StatePath
+	if path == "" {
+		return
+	}
+	data, err := json.Marshal(e.held)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o700)
+	}
+	if err == nil {
+		tmp := path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o600); err == nil {
+			err = os.Rename(tmp, path)
+		}
+	}
+	if err != nil {
+		e.logger.WithError(err).Warn("Failed to save held events")
+	}
+}
@@ -0,0 +1,192 @@
+package rules
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"jirar/internal/jira/watcher"
+	"jirar/internal/notify"
+)
+
+// recorder is a notifier recording the keys of what it is sent.
+type recorder struct {
+	mu       sync.Mutex
+	notified []string
+	digests  [][]string
+}
+
+func (r *recorder) Notify(ctx context.Context, e watcher.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notified = append(r.notified, e.Key)
+	return nil
+}
+
+func (r *recorder) Digest(ctx context.Context, events []watcher.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var keys []string
+	for _, e := range events {
+		keys = append(keys, e.Key)
+	}
+	r.digests = append(r.digests, keys)
+	return nil
+}
+
+func (r *recorder) Close() error { return nil }
+
+// sent returns the keys notified and the digests sent.
+func (r *recorder) sent() ([]string, [][]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.notified), slices.Clone(r.digests)
+}
+
+// testEngine runs rules quiet from 22:00 to 08:00 in Berlin, sending to
+// the recorder named "chat", with held events kept at statePath.
+func testEngine(t *testing.T, statePath string, rules ...Rule) (*Engine, *recorder, *time.Time) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	chat := &recorder{}
+	e, err := New(Options{
+		Rules:     rules,
+		Quiet:     quiet(t, "22:00-08:00", false),
+		Notifiers: map[string]notify.Notifier{"chat": chat},
+		StatePath: statePath,
+		Logger:    logger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := new(time.Time)
+	e.now = func() time.Time { return *now }
+	return e, chat, now
+}
+
+// issueEvent is an event of type on key.
+func issueEvent(typ watcher.EventType, key string) watcher.Event {
+	e := watcher.Event{Type: typ, Key: key}
+	e.Issue.Key = key
+	e.Issue.Fields.Priority.Name = "High"
+	return e
+}
+
+func TestEngineHandle(t *testing.T) {
+	e, chat, now := testEngine(t, "",
+		Rule{Name: "status", Events: []watcher.EventType{watcher.EventStatus}, Sinks: []string{"chat"}},
+		Rule{Name: "high", When: `issue.fields.priority.name == "High"`, Sinks: []string{"chat"}},
+		Rule{Name: "comments", Events: []watcher.EventType{watcher.EventComment}, Sinks: []string{"chat"}},
+	)
+	ctx := context.Background()
+	*now = wall(t, "2026-10-14 12:00")
+
+	// Both the status and high rules match; the sink gets it once
+	e.Handle(ctx, "", issueEvent(watcher.EventStatus, "PROJ-1"))
+	e.Handle(ctx, "", issueEvent(watcher.EventAssigned, "PROJ-2"))
+	notified, digests := chat.sent()
+	if !slices.Equal(notified, []string{"PROJ-1", "PROJ-2"}) || len(digests) != 0 {
+		t.Errorf("sent %v and digests %v, want PROJ-1 and PROJ-2 at once", notified, digests)
+	}
+}
+
+func TestEngineQuiet(t *testing.T) {
+	e, chat, now := testEngine(t, "",
+		Rule{Name: "all", Sinks: []string{"chat"}},
+	)
+	ctx := context.Background()
+
+	*now = wall(t, "2026-10-14 23:30")
+	e.Handle(ctx, "", issueEvent(watcher.EventStatus, "PROJ-1"))
+	*now = wall(t, "2026-10-15 02:00")
+	e.Handle(ctx, "", issueEvent(watcher.EventComment, "PROJ-2"))
+	*now = wall(t, "2026-10-15 07:59")
+	e.Flush(ctx)
+	if notified, digests := chat.sent(); len(notified) != 0 || len(digests) != 0 {
+		t.Fatalf("sent %v and digests %v during quiet hours, want nothing", notified, digests)
+	}
+
+	*now = wall(t, "2026-10-15 08:00")
+	e.Flush(ctx)
+	e.Flush(ctx)
+	notified, digests := chat.sent()
+	if len(notified) != 0 || len(digests) != 1 || !slices.Equal(digests[0], []string{"PROJ-1", "PROJ-2"}) {
+		t.Errorf("sent %v and digests %v, want one digest of PROJ-1 and PROJ-2", notified, digests)
+	}
+}
+
+func TestEngineIgnoreQuiet(t *testing.T) {
+	e, chat, now := testEngine(t, "",
+		Rule{Name: "urgent", Sinks: []string{"chat"}, IgnoreQuiet: true},
+	)
+	*now = wall(t, "2026-10-14 23:30")
+	e.Handle(context.Background(), "", issueEvent(watcher.EventMention, "PROJ-1"))
+	if notified, _ := chat.sent(); !slices.Equal(notified, []string{"PROJ-1"}) {
+		t.Errorf("sent %v, want PROJ-1 despite quiet hours", notified)
+	}
+}
+
+func TestEngineDigest(t *testing.T) {
+	e, chat, now := testEngine(t, "",
+		Rule{Name: "hourly", Sinks: []string{"chat"}, Digest: time.Hour},
+	)
+	ctx := context.Background()
+
+	*now = wall(t, "2026-10-14 10:00")
+	e.Handle(ctx, "", issueEvent(watcher.EventStatus, "PROJ-1"))
+	*now = wall(t, "2026-10-14 10:20")
+	e.Handle(ctx, "", issueEvent(watcher.EventStatus, "PROJ-2"))
+	*now = wall(t, "2026-10-14 10:59")
+	e.Flush(ctx)
+	if _, digests := chat.sent(); len(digests) != 0 {
+		t.Fatalf("digests %v before the period ends, want none", digests)
+	}
+
+	// The period runs from the first event
+	*now = wall(t, "2026-10-14 11:00")
+	e.Flush(ctx)
+	notified, digests := chat.sent()
+	if len(notified) != 0 || len(digests) != 1 || !slices.Equal(digests[0], []string{"PROJ-1", "PROJ-2"}) {
+		t.Fatalf("sent %v and digests %v, want one digest of PROJ-1 and PROJ-2", notified, digests)
+	}
+
+	// A digest due in quiet hours waits for them to end
+	*now = wall(t, "2026-10-14 21:30")
+	e.Handle(ctx, "", issueEvent(watcher.EventStatus, "PROJ-3"))
+	*now = wall(t, "2026-10-14 22:30")
+	e.Flush(ctx)
+	*now = wall(t, "2026-10-15 07:00")
+	e.Flush(ctx)
+	if _, digests := chat.sent(); len(digests) != 1 {
+		t.Fatalf("digests %v during quiet hours, want the first only", digests)
+	}
+	*now = wall(t, "2026-10-15 08:00")
+	e.Flush(ctx)
+	if _, digests := chat.sent(); len(digests) != 2 || !slices.Equal(digests[1], []string{"PROJ-3"}) {
+		t.Errorf("digests %v, want PROJ-3 after quiet hours", digests)
+	}
+}
+
+func TestEngineHeldAcrossRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "held.json")
+	rule := Rule{Name: "all", Sinks: []string{"chat"}}
+	ctx := context.Background()
+
+	first, _, now := testEngine(t, path, rule)
+	*now = wall(t, "2026-10-14 23:30")
+	first.Handle(ctx, "", issueEvent(watcher.EventStatus, "PROJ-1"))
+
+	second, chat, now := testEngine(t, path, rule)
+	*now = wall(t, "2026-10-15 08:00")
+	second.Flush(ctx)
+	if _, digests := chat.sent(); len(digests) != 1 || !slices.Equal(digests[0], []string{"PROJ-1"}) {
+		t.Errorf("digests %v, want PROJ-1 held by the previous run", digests)
+	}
+}
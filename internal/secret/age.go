@@ -0,0 +1,139 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// ageStore keeps the secret in a file encrypted with an age X25519 identity.
+type ageStore struct {
+	path     string
+	identity string
+}
+
+// NewAgeStore creates a Store backed by an age-encrypted file. The identity
+// file is created on first Set if it does not exist yet.
+func NewAgeStore(path, identity string) Store {
+	return &ageStore{path: path, identity: identity}
+}
+
+// Get implements Store interface.
+func (s *ageStore) Get(_ context.Context) (string, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%s: %w", s, ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("open secret file: %w", err)
+	}
+	defer f.Close()
+
+	identities, err := s.readIdentities()
+	if err != nil {
+		return "", err
+	}
+
+	r, err := age.Decrypt(f, identities...)
+	if err != nil {
+		return "", fmt.Errorf("decrypt %s: %w", s.path, err)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("decrypt %s: %w", s.path, err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Set implements Store interface.
+func (s *ageStore) Set(_ context.Context, value string) error {
+	identity, err := s.loadOrCreateIdentity()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, identity.Recipient())
+	if err != nil {
+		return fmt.Errorf("encrypt secret: %w", err)
+	}
+	if _, err := io.WriteString(w, value); err != nil {
+		return fmt.Errorf("encrypt secret: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("encrypt secret: %w", err)
+	}
+
+	return writePrivateFile(s.path, buf.Bytes())
+}
+
+// String implements Store interface.
+func (s *ageStore) String() string {
+	return "age:" + s.path
+}
+
+// readIdentities parses the identity file used for decryption.
+func (s *ageStore) readIdentities() ([]age.Identity, error) {
+	if s.identity == "" {
+		return nil, fmt.Errorf("age identity file is not configured")
+	}
+
+	f, err := os.Open(s.identity)
+	if err != nil {
+		return nil, fmt.Errorf("open age identity: %w", err)
+	}
+	defer f.Close()
+
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse age identity: %w", err)
+	}
+	return identities, nil
+}
+
+// loadOrCreateIdentity returns the X25519 identity, generating one if the
+// identity file is missing.
+func (s *ageStore) loadOrCreateIdentity() (*age.X25519Identity, error) {
+	identities, err := s.readIdentities()
+	if err == nil {
+		for _, id := range identities {
+			if x, ok := id.(*age.X25519Identity); ok {
+				return x, nil
+			}
+		}
+		return nil, fmt.Errorf("age identity %s has no X25519 key", s.identity)
+	}
+	if _, statErr := os.Stat(s.identity); s.identity == "" || !errors.Is(statErr, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return nil, fmt.Errorf("generate age identity: %w", err)
+	}
+	if err := writePrivateFile(s.identity, []byte(identity.String()+"\n")); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// writePrivateFile writes data readable only by the current user.
+func writePrivateFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package secret
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// helperStore delegates to an external credential helper using the
+// git-credential protocol. Helpers that simply print the token are also
+// accepted.
+type helperStore struct {
+	command  string
+	host     string
+	username string
+}
+
+// NewHelperStore creates a Store backed by an external command.
+func NewHelperStore(command, host, username string) Store {
+	return &helperStore{command: command, host: host, username: username}
+}
+
+// Get implements Store interface.
+func (s *helperStore) Get(ctx context.Context) (string, error) {
+	out, err := s.run(ctx, "get", "")
+	if err != nil {
+		return "", err
+	}
+
+	token := parseHelperOutput(out)
+	if token == "" {
+		return "", fmt.Errorf("%s: %w", s, ErrNotFound)
+	}
+	return token, nil
+}
+
+// Set implements Store interface.
+func (s *helperStore) Set(ctx context.Context, value string) error {
+	_, err := s.run(ctx, "store", value)
+	return err
+}
+
+// String implements Store interface.
+func (s *helperStore) String() string {
+	return "helper:" + s.command
+}
+
+// run invokes the helper with the given action and credential description.
+func (s *helperStore) run(ctx context.Context, action, password string) ([]byte, error) {
+	var input bytes.Buffer
+	input.WriteString("protocol=https\n")
+	if s.host != "" {
+		fmt.Fprintf(&input, "host=%s\n", s.host)
+	}
+	if s.username != "" {
+		fmt.Fprintf(&input, "username=%s\n", s.username)
+	}
+	if password != "" {
+		fmt.Fprintf(&input, "password=%s\n", password)
+	}
+	input.WriteString("\n")
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.command+" "+action)
+	cmd.Stdin = &input
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("credential helper %s failed: %w", action, err)
+	}
+	return out, nil
+}
+
+// parseHelperOutput extracts the password from git-credential output, falling
+// back to the raw output for helpers that print only the token on one line.
+func parseHelperOutput(out []byte) string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if value, ok := strings.CutPrefix(line, "password="); ok {
+			return value
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 1 {
+		return lines[0]
+	}
+	return ""
+}
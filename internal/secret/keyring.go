@@ -0,0 +1,53 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keyringStore keeps the secret in the system keyring. On Linux this is the
+// freedesktop Secret Service reached over the D-Bus session bus.
+type keyringStore struct {
+	service string
+	account string
+}
+
+// NewKeyringStore creates a Store backed by the system keyring.
+func NewKeyringStore(service, account string) Store {
+	return &keyringStore{service: service, account: account}
+}
+
+// KeyringAvailable reports whether the system keyring can be reached, e.g.
+// whether a Secret Service runs on the session bus.
+func KeyringAvailable() bool {
+	_, err := keyring.Get(DefaultService, "jirar-probe")
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
+
+// Get implements Store interface.
+func (s *keyringStore) Get(_ context.Context) (string, error) {
+	value, err := keyring.Get(s.service, s.account)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", fmt.Errorf("%s: %w", s, ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("read keyring: %w", err)
+	}
+	return value, nil
+}
+
+// Set implements Store interface.
+func (s *keyringStore) Set(_ context.Context, value string) error {
+	if err := keyring.Set(s.service, s.account, value); err != nil {
+		return fmt.Errorf("write keyring: %w", err)
+	}
+	return nil
+}
+
+// String implements Store interface.
+func (s *keyringStore) String() string {
+	return fmt.Sprintf("keyring:%s/%s", s.service, s.account)
+}
@@ -0,0 +1,99 @@
+// Package secret resolves credentials from pluggable secret backends.
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound is returned when a backend holds no secret for a reference.
+var ErrNotFound = errors.New("secret not found")
+
+// DefaultService is the keyring service name used when a reference omits it.
+const DefaultService = "jirar"
+
+// Store defines a backend that can read and write a single secret.
+type Store interface {
+	// Get returns the stored secret
+	Get(ctx context.Context) (string, error)
+
+	// Set replaces the stored secret
+	Set(ctx context.Context, value string) error
+
+	// String describes the backend location without revealing the secret
+	String() string
+}
+
+// Options carries the context a backend needs to locate a secret.
+type Options struct {
+	// Account identifies the user, typically the Jira email
+	Account string
+	// Host is the Jira host, passed to credential helpers
+	Host string
+	// AgeIdentity is the age identity file used to decrypt age secrets
+	AgeIdentity string
+}
+
+// Open returns the Store addressed by ref.
+//
+// Supported references:
+//
+//	keyring:[service[/account]]   Secret Service keyring over D-Bus
+//	age:path/to/token.age         age-encrypted file
+//	helper:command                git-credential style helper
+func Open(ref string, opts Options) (Store, error) {
+	scheme, rest, ok := strings.Cut(ref, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid secret reference %q: missing backend prefix", ref)
+	}
+
+	switch scheme {
+	case "keyring":
+		service, account := DefaultService, opts.Account
+		if rest != "" {
+			if s, a, found := strings.Cut(rest, "/"); found {
+				service, account = s, a
+			} else {
+				service = rest
+			}
+		}
+		if account == "" {
+			return nil, fmt.Errorf("keyring reference %q needs an account", ref)
+		}
+		return NewKeyringStore(service, account), nil
+	case "age":
+		if rest == "" {
+			return nil, fmt.Errorf("age reference %q needs a file path", ref)
+		}
+		return NewAgeStore(ExpandHome(rest), ExpandHome(opts.AgeIdentity)), nil
+	case "helper":
+		if rest == "" {
+			return nil, fmt.Errorf("helper reference %q needs a command", ref)
+		}
+		return NewHelperStore(rest, opts.Host, opts.Account), nil
+	default:
+		return nil, fmt.Errorf("unknown secret backend %q", scheme)
+	}
+}
+
+// Redact masks a secret for display, keeping only whether it is set.
+func Redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "********"
+}
+
+// ExpandHome replaces a leading ~ with the user's home directory.
+func ExpandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
@@ -0,0 +1,297 @@
+// Package statusline keeps what shell prompts and status bars show: the
+// counts of the user's open and in-progress issues, which the daemon keeps
+// current, and the comments of others the user has not read yet, which
+// watchers record. Everything is read from a small file per profile, so a
+// status line never waits for Jira.
+package statusline
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"jirar/internal/filelock"
+	"jirar/internal/jira"
+	"jirar/internal/jira/watcher"
+	"jirar/internal/recent"
+)
+
+// DefaultFormat is the template of a status line without --format.
+const DefaultFormat = "{{.Assigned}} 🔥{{.InProgress}} 💬{{.Unread}}"
+
+// JQL of the counts.
+const (
+	AssignedJQL   = "assignee = currentUser() AND statusCategory != Done"
+	InProgressJQL = `assignee = currentUser() AND statusCategory = "In Progress"`
+)
+
+// keepFor is how long an unread comment is counted.
+const keepFor = 14 * 24 * time.Hour
+
+// State is what status lines are drawn from.
+type State struct {
+	Assigned   int `json:"assigned"`
+	InProgress int `json:"inProgress"`
+	// Counted is when the counts were fetched; zero if never
+	Counted time.Time `json:"counted,omitzero"`
+	// Activity is the latest comment of others on each issue
+	Activity map[string]Activity `json:"activity,omitempty"`
+}
+
+// Activity is a comment on an issue.
+type Activity struct {
+	At time.Time `json:"at"`
+	// Mention is set when a comment since the issue was read mentions
+	// the user
+	Mention bool `json:"mention,omitempty"`
+}
+
+// Line is the data of a status line template.
+type Line struct {
+	Assigned   int
+	InProgress int
+	// Unread counts the issues with comments of others since they were
+	// read; Mentions those among them that mention the user
+	Unread   int
+	Mentions int
+	// Keys are the issues with unread comments, latest first
+	Keys []string
+	// Counted is when the counts were fetched and Age how long ago
+	Counted time.Time
+	Age     time.Duration
+	// Stale is set when the counts are older than expected, e.g. because
+	// the daemon is not running
+	Stale bool
+}
+
+// DefaultPath returns the state file of a profile under the user cache
+// directory.
+func DefaultPath(profile string) string {
+	if profile == "" {
+		profile = "default"
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = filepath.Join(os.TempDir(), "jirar-cache")
+	}
+	return filepath.Join(dir, "jirar", profile, "status.json")
+}
+
+// Load reads the state at path; a missing file is an empty state.
+func Load(path string) (*State, error) {
+	s := &State{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("read status: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return &State{}, fmt.Errorf("parse status: %w", err)
+	}
+	return s, nil
+}
+
+// Update applies f to the state at path and saves it, holding a lock so
+// the daemon, watchers and --mark-read do not undo each other's changes.
+// An unreadable state is started over, as it is rebuilt as events arrive.
+func Update(path string, f func(*State)) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create cache directory: %w", err)
+	}
+	unlock, err := filelock.Lock(path + ".lock")
+	if err != nil {
+		return fmt.Errorf("lock status: %w", err)
+	}
+	defer unlock()
+
+	s, _ := Load(path)
+	f(s)
+	for key, a := range s.Activity {
+		if time.Since(a.At) > keepFor {
+			delete(s.Activity, key)
+		}
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("encode status: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".status-*")
+	if err != nil {
+		return fmt.Errorf("write status: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write status: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write status: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write status: %w", err)
+	}
+	return nil
+}
+
+// Record notes the comment of a comment or mention event; other events
+// are ignored.
+func (s *State) Record(e watcher.Event) {
+	if e.Type != watcher.EventComment && e.Type != watcher.EventMention {
+		return
+	}
+	if s.Activity == nil {
+		s.Activity = map[string]Activity{}
+	}
+	a := s.Activity[e.Key]
+	if e.Time.After(a.At) {
+		a.At = e.Time
+	}
+	a.Mention = a.Mention || e.Type == watcher.EventMention
+	s.Activity[e.Key] = a
+}
+
+// MarkRead forgets the comments on the issues of keys, or on all issues
+// without keys.
+func (s *State) MarkRead(keys ...string) {
+	if len(keys) == 0 {
+		s.Activity = nil
+		return
+	}
+	for _, key := range keys {
+		delete(s.Activity, key)
+	}
+}
+
+// Line returns the data of a status line at now. Comments on issues
+// visited after them are read; counts older than staleAfter are stale.
+func (s *State) Line(visited map[string]time.Time, now time.Time, staleAfter time.Duration) Line {
+	line := Line{Assigned: s.Assigned, InProgress: s.InProgress, Counted: s.Counted}
+	if !s.Counted.IsZero() {
+		line.Age = now.Sub(s.Counted).Truncate(time.Second)
+	}
+	line.Stale = s.Counted.IsZero() || line.Age > staleAfter
+
+	for key, a := range s.Activity {
+		if visited[key].After(a.At) {
+			continue
+		}
+		line.Keys = append(line.Keys, key)
+		line.Unread++
+		if a.Mention {
+			line.Mentions++
+		}
+	}
+	sort.Slice(line.Keys, func(i, j int) bool {
+		return s.Activity[line.Keys[i]].At.After(s.Activity[line.Keys[j]].At)
+	})
+	return line
+}
+
+// Count fetches the counts of the user's open and in-progress issues.
+func Count(ctx context.Context, client jira.Client) (assigned, inProgress int, err error) {
+	res, err := client.SearchIssues(ctx, AssignedJQL, jira.WithLimit(1), jira.WithFields("status"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("count assigned issues: %w", err)
+	}
+	assigned = res.Total
+	res, err = client.SearchIssues(ctx, InProgressJQL, jira.WithLimit(1), jira.WithFields("status"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("count issues in progress: %w", err)
+	}
+	return assigned, res.Total, nil
+}
+
+// Options configure Print.
+type Options struct {
+	// Profile selects the files of a profile; empty is the default one
+	Profile string
+	// Format is the template of the line; empty is DefaultFormat
+	Format string
+	// StaleAfter is the age from which counts are stale
+	StaleAfter time.Duration
+	// MarkRead forgets the unread comments first
+	MarkRead bool
+}
+
+// Print writes the status line of a profile from its local files only.
+// Comments on issues visited since, e.g. with jirar open, are read. Until
+// the daemon or a watcher has written anything it prints nothing, so
+// prompts stay clean.
+func Print(w io.Writer, opts Options) error {
+	tmpl, err := Parse(cmp.Or(opts.Format, DefaultFormat))
+	if err != nil {
+		return err
+	}
+	path := DefaultPath(opts.Profile)
+	if opts.MarkRead {
+		if err := Update(path, func(s *State) { s.MarkRead() }); err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	state, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	// Visits only mark comments read; a broken file marks none
+	cache, _ := recent.Load(recent.DefaultPath(opts.Profile))
+	visited := make(map[string]time.Time, len(cache.Visits))
+	for _, v := range cache.Visits {
+		visited[v.Issue.Key] = v.Visited
+	}
+	return Render(w, tmpl, state.Line(visited, time.Now(), opts.StaleAfter))
+}
+
+// Formats are templates that can be given by name.
+var Formats = map[string]string{
+	// waybar is the JSON of a waybar custom module with return-type json
+	"waybar": `{"text":{{json (printf "%d 🔥%d 💬%d" .Assigned .InProgress .Unread)}},"tooltip":{{json (join .Keys "\n")}},` +
+		`"class":"{{if .Stale}}stale{{else if .Mentions}}mention{{else if .Unread}}unread{{else}}read{{end}}"}`,
+}
+
+// Parse parses a status line template or the name of one of Formats. It
+// has a join function, as in {{join .Keys " "}}, and json, which quotes a
+// value for JSON output.
+func Parse(format string) (*template.Template, error) {
+	if named, ok := Formats[format]; ok {
+		format = named
+	}
+	funcs := template.FuncMap{
+		"join": strings.Join,
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}
+	tmpl, err := template.New("status-line").Funcs(funcs).Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Render writes a status line and a newline.
+func Render(w io.Writer, tmpl *template.Template, line Line) error {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, line); err != nil {
+		return fmt.Errorf("render status line: %w", err)
+	}
+	_, err := fmt.Fprintln(w, strings.TrimRight(b.String(), "\n"))
+	return err
+}
@@ -0,0 +1,405 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"jirar/internal/jira"
+)
+
+// ErrOffline is returned for what needs Jira while offline.
+var ErrOffline = errors.New("not available offline")
+
+// Client answers jira.Client from a store. Searches are evaluated
+// locally over the stored issues, so they only see the synced scopes.
+// What the store cannot answer, such as boards or JQL it cannot
+// evaluate, is asked of Online; without it, offline, it fails with
+// ErrOffline. Changes go to Online and refetch the issue into the store;
+// offline, they are queued in the outbox for Push and shown in the store
+// at once.
+type Client struct {
+	store  *Store
+	online jira.Client
+	logger *logrus.Logger
+}
+
+var _ jira.Client = (*Client)(nil)
+
+// NewClient returns a client reading from st; online is nil offline.
+func NewClient(st *Store, online jira.Client, logger *logrus.Logger) *Client {
+	return &Client{store: st, online: online, logger: logger}
+}
+
+// fallback asks Jira with f when the store could not answer what, or
+// returns why it could not offline.
+func fallback[R any](c *Client, what string, err error, f func(jira.Client) (R, error)) (R, error) {
+	if c.online == nil {
+		var zero R
+		if err == nil {
+			err = ErrOffline
+		}
+		return zero, fmt.Errorf("%s: %w", what, err)
+	}
+	if err != nil {
+		c.logger.WithError(err).WithField("request", what).Debug("Asking Jira for what the local store lacks")
+	}
+	return f(c.online)
+}
+
+// env returns what queries are evaluated with.
+func (c *Client) env() (*env, error) {
+	e := &env{
+		loc:    time.Local,
+		now:    time.Now(),
+		fields: map[string]string{},
+		comments: func(key string) []jira.Comment {
+			comments, _ := c.store.Comments(key)
+			return comments
+		},
+	}
+	me, err := c.store.Myself()
+	if err != nil && !errors.Is(err, ErrNotStored) {
+		return nil, err
+	}
+	if me != nil {
+		e.me = me.AccountID
+		// JQL dates are in the timezone of the Jira account
+		if loc, err := time.LoadLocation(me.TimeZone); err == nil && me.TimeZone != "" {
+			e.loc = loc
+		}
+	}
+	fields, err := c.store.Fields()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		e.fields[strings.ToLower(f.Name)] = f.ID
+		e.fields[strings.ToLower(f.ID)] = f.ID
+	}
+	return e, nil
+}
+
+// search evaluates jql over the stored issues.
+func (c *Client) search(jql string) ([]jira.Issue, error) {
+	q, err := parse(jql)
+	if err != nil {
+		return nil, err
+	}
+	e, err := c.env()
+	if err != nil {
+		return nil, err
+	}
+	match, err := e.compile(q.where)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []jira.Issue
+	err = c.store.ForEachIssue(func(issue *jira.Issue) error {
+		if match(issue) {
+			issues = append(issues, *issue)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := e.sortIssues(issues, q.order); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// SearchIssues implements jira.Client.
+func (c *Client) SearchIssues(ctx context.Context, jql string, opts ...jira.SearchOption) (*jira.SearchResult, error) {
+	issues, err := c.search(jql)
+	if errors.Is(err, ErrUnsupported) || errors.Is(err, errInvalidJQL) {
+		return fallback(c, "search", err, func(online jira.Client) (*jira.SearchResult, error) {
+			return online.SearchIssues(ctx, jql, opts...)
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	options := jira.SearchOptions{Limit: 50}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	res := &jira.SearchResult{StartAt: options.StartAt, MaxResults: options.Limit, Total: len(issues)}
+	if options.StartAt < len(issues) {
+		res.Issues = issues[options.StartAt:min(len(issues), options.StartAt+options.Limit)]
+	}
+	return res, nil
+}
+
+// GetIssue implements jira.Client.
+func (c *Client) GetIssue(ctx context.Context, key string) (*jira.Issue, error) {
+	issue, err := c.store.Issue(key)
+	if errors.Is(err, ErrNotStored) {
+		return fallback(c, "get issue", err, func(online jira.Client) (*jira.Issue, error) {
+			return online.GetIssue(ctx, key)
+		})
+	}
+	return issue, err
+}
+
+// GetCurrentUser implements jira.Client.
+func (c *Client) GetCurrentUser(ctx context.Context) (*jira.CurrentUser, error) {
+	user, err := c.store.Myself()
+	if errors.Is(err, ErrNotStored) {
+		return fallback(c, "get current user", err, func(online jira.Client) (*jira.CurrentUser, error) {
+			return online.GetCurrentUser(ctx)
+		})
+	}
+	return user, err
+}
+
+// ValidateCredentials implements jira.Client. The credentials were valid
+// when the store was synced.
+func (c *Client) ValidateCredentials(ctx context.Context) error {
+	return nil
+}
+
+// ListProjects implements jira.Client.
+func (c *Client) ListProjects(ctx context.Context) ([]jira.Project, error) {
+	projects, err := c.store.Projects()
+	if err == nil && len(projects) == 0 {
+		err = ErrNotStored
+	}
+	if errors.Is(err, ErrNotStored) {
+		return fallback(c, "list projects", err, func(online jira.Client) ([]jira.Project, error) {
+			return online.ListProjects(ctx)
+		})
+	}
+	return projects, err
+}
+
+// ListBoards implements jira.Client.
+func (c *Client) ListBoards(ctx context.Context, projectKey string) ([]jira.Board, error) {
+	return fallback(c, "list boards", nil, func(online jira.Client) ([]jira.Board, error) {
+		return online.ListBoards(ctx, projectKey)
+	})
+}
+
+// GetBoard implements jira.Client.
+func (c *Client) GetBoard(ctx context.Context, id int) (*jira.Board, error) {
+	return fallback(c, "get board", nil, func(online jira.Client) (*jira.Board, error) {
+		return online.GetBoard(ctx, id)
+	})
+}
+
+// GetBoardConfiguration implements jira.Client.
+func (c *Client) GetBoardConfiguration(ctx context.Context, id int) (*jira.BoardConfiguration, error) {
+	return fallback(c, "get board configuration", nil, func(online jira.Client) (*jira.BoardConfiguration, error) {
+		return online.GetBoardConfiguration(ctx, id)
+	})
+}
+
+// GetBoardIssues implements jira.Client.
+func (c *Client) GetBoardIssues(ctx context.Context, id int, jql string, opts ...jira.SearchOption) (*jira.SearchResult, error) {
+	return fallback(c, "get board issues", nil, func(online jira.Client) (*jira.SearchResult, error) {
+		return online.GetBoardIssues(ctx, id, jql, opts...)
+	})
+}
+
+// GetServerInfo implements jira.Client.
+func (c *Client) GetServerInfo(ctx context.Context) (*jira.ServerInfo, error) {
+	return fallback(c, "get server info", nil, func(online jira.Client) (*jira.ServerInfo, error) {
+		return online.GetServerInfo(ctx)
+	})
+}
+
+// GetMyPermissions implements jira.Client.
+func (c *Client) GetMyPermissions(ctx context.Context, projectKey string, permissions ...string) (map[string]jira.Permission, error) {
+	return fallback(c, "get permissions", nil, func(online jira.Client) (map[string]jira.Permission, error) {
+		return online.GetMyPermissions(ctx, projectKey, permissions...)
+	})
+}
+
+// GetComments implements jira.Client.
+func (c *Client) GetComments(ctx context.Context, key string) ([]jira.Comment, error) {
+	comments, err := c.store.Comments(key)
+	if errors.Is(err, ErrNotStored) {
+		return fallback(c, "get comments", err, func(online jira.Client) ([]jira.Comment, error) {
+			return online.GetComments(ctx, key)
+		})
+	}
+	return comments, err
+}
+
+// AddComment implements jira.Client.
+func (c *Client) AddComment(ctx context.Context, key string, body *jira.ADF) (*jira.Comment, error) {
+	if c.online == nil {
+		comment := &jira.Comment{Body: body, Created: jira.Time{Time: time.Now()}}
+		if me, err := c.store.Myself(); err == nil {
+			comment.Author = jira.User{AccountID: me.AccountID, DisplayName: me.DisplayName, Email: me.Email}
+		}
+		op := Op{Key: key, Action: fmt.Sprintf("comment %q", excerpt(body.PlainText(), 40)), Method: http.MethodPost, Path: "/rest/api/3/issue/" + key + "/comment"}
+		if err := c.queue(&op, map[string]any{"body": body}, change{comment: comment}); err != nil {
+			return nil, err
+		}
+		return comment, nil
+	}
+	comment, err := fallback(c, "add comment", nil, func(online jira.Client) (*jira.Comment, error) {
+		return online.AddComment(ctx, key, body)
+	})
+	if err == nil {
+		c.refresh(ctx, key)
+	}
+	return comment, err
+}
+
+// GetTransitions implements jira.Client. Offline, the transitions the
+// issue had when synced are returned.
+func (c *Client) GetTransitions(ctx context.Context, key string) ([]jira.Transition, error) {
+	if c.online == nil {
+		return c.store.Transitions(key)
+	}
+	return fallback(c, "get transitions", nil, func(online jira.Client) ([]jira.Transition, error) {
+		return online.GetTransitions(ctx, key)
+	})
+}
+
+// TransitionIssue implements jira.Client.
+func (c *Client) TransitionIssue(ctx context.Context, key, transitionID string) error {
+	if c.online == nil {
+		transitions, err := c.store.Transitions(key)
+		if err != nil {
+			return err
+		}
+		for _, t := range transitions {
+			if t.ID == transitionID {
+				op := Op{Key: key, Action: "transition to " + t.To.Name, Method: http.MethodPost, Path: "/rest/api/3/issue/" + key + "/transitions"}
+				return c.queue(&op, map[string]any{"transition": map[string]string{"id": transitionID}}, change{status: &t.To})
+			}
+		}
+		return fmt.Errorf("transition %s of %s is not stored", transitionID, key)
+	}
+	_, err := fallback(c, "transition issue", nil, func(online jira.Client) (struct{}, error) {
+		return struct{}{}, online.TransitionIssue(ctx, key, transitionID)
+	})
+	if err == nil {
+		c.refresh(ctx, key)
+	}
+	return err
+}
+
+// AssignIssue implements jira.Client.
+func (c *Client) AssignIssue(ctx context.Context, key, accountID string) error {
+	if c.online == nil {
+		op := Op{Key: key, Action: "unassign", Method: http.MethodPut, Path: "/rest/api/3/issue/" + key + "/assignee"}
+		assignee := jira.User{}
+		var id any
+		if accountID != "" {
+			users, err := c.store.Users()
+			if err != nil {
+				return err
+			}
+			assignee.AccountID, id = accountID, accountID
+			for _, u := range users {
+				if u.AccountID == accountID {
+					assignee = u
+				}
+			}
+			op.Action = "assign to " + cmp.Or(assignee.DisplayName, accountID)
+		}
+		return c.queue(&op, map[string]any{"accountId": id}, change{assignee: &assignee})
+	}
+	_, err := fallback(c, "assign issue", nil, func(online jira.Client) (struct{}, error) {
+		return struct{}{}, online.AssignIssue(ctx, key, accountID)
+	})
+	if err == nil {
+		c.refresh(ctx, key)
+	}
+	return err
+}
+
+// FindAssignableUsers implements jira.Client. Offline, the stored users
+// are searched, as who may be assigned is only known to Jira.
+func (c *Client) FindAssignableUsers(ctx context.Context, key, query string) ([]jira.User, error) {
+	if c.online != nil {
+		return c.online.FindAssignableUsers(ctx, key, query)
+	}
+	users, err := c.store.Users()
+	if err != nil {
+		return nil, err
+	}
+	query = strings.ToLower(query)
+	var found []jira.User
+	for _, u := range users {
+		if strings.Contains(strings.ToLower(u.DisplayName), query) || strings.Contains(strings.ToLower(u.Email), query) {
+			found = append(found, u)
+		}
+	}
+	return found, nil
+}
+
+// Do implements jira.Client. Offline, changes to issues, such as edits
+// and worklogs, are queued and answered with 202 Accepted.
+func (c *Client) Do(ctx context.Context, req *jira.RawRequest) (*jira.RawResponse, error) {
+	m := issuePathPattern.FindStringSubmatch(req.Path)
+	if c.online == nil && req.Method != "" && req.Method != http.MethodGet && m != nil {
+		if len(req.Body) > 0 && !json.Valid(req.Body) {
+			return nil, fmt.Errorf("%s %s: only JSON bodies can be queued offline", req.Method, req.Path)
+		}
+		op := Op{Key: m[1], Action: req.Method + " " + req.Path, Method: req.Method, Path: req.Path, Query: req.Query, Header: req.Header}
+		var body any
+		if len(req.Body) > 0 {
+			body = json.RawMessage(req.Body)
+		}
+		if err := c.queue(&op, body, change{}); err != nil {
+			return nil, err
+		}
+		queued, err := json.Marshal(map[string]any{"queued": op})
+		if err != nil {
+			return nil, err
+		}
+		return &jira.RawResponse{StatusCode: http.StatusAccepted, Status: "202 Accepted", Header: http.Header{}, Body: queued}, nil
+	}
+	return fallback(c, req.Method+" "+req.Path, nil, func(online jira.Client) (*jira.RawResponse, error) {
+		return online.Do(ctx, req)
+	})
+}
+
+// queue queues a change offline with the body of its request, if any.
+func (c *Client) queue(op *Op, body any, ch change) error {
+	op.Key = strings.ToUpper(op.Key)
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encode %s: %w", op.Action, err)
+		}
+		op.Body = data
+	}
+	if err := c.store.queue(op, ch); err != nil {
+		return fmt.Errorf("queue %s of %s: %w", op.Action, op.Key, err)
+	}
+	c.logger.WithFields(logrus.Fields{"key": op.Key, "action": op.Action, "id": op.ID}).Debug("Queued change offline")
+	return nil
+}
+
+// excerpt shortens text to n runes on one line.
+func excerpt(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return text
+}
+
+// refresh refetches a changed issue into the store; failures only leave
+// it stale until the next sync.
+func (c *Client) refresh(ctx context.Context, key string) {
+	if err := Refresh(ctx, c.online, c.store, key); err != nil {
+		c.logger.WithError(err).WithField("key", key).Debug("Could not refresh the stored issue")
+	}
+}
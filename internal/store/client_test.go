@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"jirar/internal/jira"
+)
+
+// onlineClient answers searches as Jira would, recording the JQL asked.
+type onlineClient struct {
+	jira.Client
+	asked []string
+}
+
+func (c *onlineClient) SearchIssues(ctx context.Context, jql string, opts ...jira.SearchOption) (*jira.SearchResult, error) {
+	c.asked = append(c.asked, jql)
+	return &jira.SearchResult{Total: 1, Issues: []jira.Issue{{Key: "JIRA-1"}}}, nil
+}
+
+// testStore returns a store holding testIssues, synced as Jane.
+func testStore(t *testing.T) *Store {
+	t.Helper()
+	st, err := Open(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	var issues []jira.Issue
+	for _, ti := range testIssues {
+		issues = append(issues, ti.issue(t))
+	}
+	if err := st.PutIssues("project in (PROJ, OPS)", issues, nil); err != nil {
+		t.Fatal(err)
+	}
+	myself := &jira.CurrentUser{AccountID: jane.AccountID, DisplayName: jane.DisplayName, TimeZone: "UTC"}
+	if err := st.PutMetadata(Metadata{Myself: myself}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestSearchIssues(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		jql     string
+		offline bool
+		want    []string
+		// asked is set when Jira must answer
+		asked bool
+		err   error
+	}{
+		{name: "evaluated locally online", jql: "assignee = currentUser() ORDER BY key", want: []string{"OPS-3", "PROJ-1"}},
+		{name: "evaluated locally offline", jql: "assignee = currentUser() ORDER BY key", offline: true, want: []string{"OPS-3", "PROJ-1"}},
+		{name: "unsupported JQL asks Jira", jql: "sprint in openSprints()", want: []string{"JIRA-1"}, asked: true},
+		{name: "invalid JQL asks Jira", jql: "project = PROJ ORDER BY", want: []string{"JIRA-1"}, asked: true},
+		{name: "unsupported JQL fails offline", jql: "sprint in openSprints()", offline: true, err: ErrUnsupported},
+		{name: "invalid JQL fails offline", jql: "project = PROJ ORDER BY", offline: true, err: errInvalidJQL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			online := &onlineClient{}
+			client := NewClient(testStore(t), online, logger)
+			if tt.offline {
+				client = NewClient(client.store, nil, logger)
+			}
+
+			res, err := client.SearchIssues(ctx, tt.jql)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("%s: %v, want %v", tt.jql, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", tt.jql, err)
+			}
+			var keys []string
+			for _, issue := range res.Issues {
+				keys = append(keys, issue.Key)
+			}
+			if !slices.Equal(keys, tt.want) {
+				t.Errorf("%s = %v, want %v", tt.jql, keys, tt.want)
+			}
+			if asked := len(online.asked) > 0; asked != tt.asked {
+				t.Errorf("%s: Jira asked %v, want %v", tt.jql, online.asked, tt.asked)
+			}
+		})
+	}
+}
+
+func TestSearchIssuesPages(t *testing.T) {
+	client := NewClient(testStore(t), nil, logrus.New())
+	res, err := client.SearchIssues(context.Background(), "ORDER BY key", jira.WithStartAt(1), jira.WithLimit(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != 4 || len(res.Issues) != 2 || res.Issues[0].Key != "PROJ-1" || res.Issues[1].Key != "PROJ-2" {
+		t.Errorf("page = %d of %d from %v, want PROJ-1 and PROJ-2 of 4", len(res.Issues), res.Total, res.Issues)
+	}
+}
@@ -0,0 +1,673 @@
+package store
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"jirar/internal/jira"
+)
+
+// env is what queries are evaluated with.
+type env struct {
+	// me is the account ID of the current user; empty if unknown
+	me  string
+	loc *time.Location
+	now time.Time
+	// fields maps lowercase field names and IDs to field IDs
+	fields map[string]string
+	// comments returns the stored comments of an issue
+	comments func(key string) []jira.Comment
+}
+
+// value is the value of a field of an issue.
+type value struct {
+	// ids are the names, keys and IDs the value equals; none when empty
+	ids  []string
+	text string
+	time time.Time
+	num  float64
+	// isNum is set for numbers
+	isNum bool
+	// sort orders values that are neither times nor numbers
+	sort string
+}
+
+// empty reports whether the field has no value.
+func (v value) empty() bool {
+	return len(v.ids) == 0 && v.text == "" && v.time.IsZero() && !v.isNum
+}
+
+// fieldKind tells how a field compares with operands.
+type fieldKind int
+
+const (
+	// kindName matches names, keys and IDs
+	kindName fieldKind = iota
+	kindText
+	kindDate
+	// kindCustom is a field of unknown type; operands are tried as dates
+	// and numbers
+	kindCustom
+)
+
+// getter reads a field of an issue.
+type getter func(*jira.Issue) value
+
+// categoryNames are the names of the status categories by key, and their
+// order.
+var categoryNames = map[string]string{"new": "To Do", "indeterminate": "In Progress", "done": "Done"}
+
+var categoryOrder = map[string]string{"new": "1", "indeterminate": "2", "done": "3"}
+
+// priorityRanks order the default priorities, highest last.
+var priorityRanks = map[string]int{"lowest": 1, "low": 2, "medium": 3, "high": 4, "highest": 5}
+
+// systemFields are JQL names of system fields kept in Fields.Custom.
+var systemFields = map[string]string{
+	"fixversion":      "fixVersions",
+	"affectedversion": "versions",
+	"component":       "components",
+	"labels":          "labels",
+	"resolution":      "resolution",
+	"resolved":        "resolutiondate",
+	"resolutiondate":  "resolutiondate",
+	"environment":     "environment",
+}
+
+// customIDPattern matches cf[10016], the JQL name of customfield_10016.
+var customIDPattern = regexp.MustCompile(`(?i)^cf\[(\d+)\]$`)
+
+// field returns how to read the field name of JQL.
+func (e *env) field(name string) (getter, fieldKind, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "key", "issuekey", "issue", "id":
+		return func(i *jira.Issue) value {
+			project, number, _ := strings.Cut(i.Key, "-")
+			return value{ids: nonEmpty(i.Key, i.ID), sort: fmt.Sprintf("%s-%012s", project, number)}
+		}, kindName, nil
+	case "project":
+		return func(i *jira.Issue) value {
+			p := i.Fields.Project
+			return value{ids: nonEmpty(p.Key, p.Name, p.ID), sort: p.Key}
+		}, kindName, nil
+	case "status":
+		return func(i *jira.Issue) value {
+			s := i.Fields.Status
+			return value{ids: nonEmpty(s.Name, s.ID), sort: s.Name}
+		}, kindName, nil
+	case "statuscategory":
+		return func(i *jira.Issue) value {
+			c := i.Fields.Status.StatusCategory
+			return value{ids: nonEmpty(c.Key, c.Name, categoryNames[c.Key]), sort: categoryOrder[c.Key]}
+		}, kindName, nil
+	case "priority":
+		return func(i *jira.Issue) value {
+			p := i.Fields.Priority
+			return value{ids: nonEmpty(p.Name, p.ID), sort: strconv.Itoa(priorityRank(p))}
+		}, kindName, nil
+	case "assignee":
+		return func(i *jira.Issue) value { return userValue(i.Fields.Assignee) }, kindName, nil
+	case "reporter":
+		return func(i *jira.Issue) value { return userValue(i.Fields.Reporter) }, kindName, nil
+	case "type", "issuetype":
+		return func(i *jira.Issue) value {
+			t := i.Fields.IssueType
+			return value{ids: nonEmpty(t.Name, t.ID), sort: t.Name}
+		}, kindName, nil
+	case "parent":
+		return func(i *jira.Issue) value {
+			if i.Fields.Parent == nil {
+				return value{}
+			}
+			return value{ids: nonEmpty(i.Fields.Parent.Key, i.Fields.Parent.ID), sort: i.Fields.Parent.Key}
+		}, kindName, nil
+	case "watcher":
+		return func(i *jira.Issue) value {
+			var watches struct {
+				IsWatching bool `json:"isWatching"`
+			}
+			if json.Unmarshal(i.Fields.Custom["watches"], &watches) != nil || !watches.IsWatching {
+				return value{}
+			}
+			return value{ids: nonEmpty(e.me)}
+		}, kindName, nil
+	case "summary":
+		return func(i *jira.Issue) value {
+			return value{ids: nonEmpty(i.Fields.Summary), text: i.Fields.Summary, sort: i.Fields.Summary}
+		}, kindText, nil
+	case "description":
+		return func(i *jira.Issue) value { return value{text: i.Fields.Description.PlainText()} }, kindText, nil
+	case "comment":
+		return func(i *jira.Issue) value { return value{text: e.commentText(i.Key)} }, kindText, nil
+	case "text":
+		return func(i *jira.Issue) value {
+			parts := []string{i.Fields.Summary, i.Fields.Description.PlainText(), e.commentText(i.Key)}
+			for _, raw := range i.Fields.Custom {
+				var s string
+				if json.Unmarshal(raw, &s) == nil {
+					parts = append(parts, s)
+				}
+			}
+			return value{text: strings.Join(parts, "\n")}
+		}, kindText, nil
+	case "created", "createddate":
+		return func(i *jira.Issue) value { return value{time: i.Fields.Created.Time} }, kindDate, nil
+	case "updated", "updateddate":
+		return func(i *jira.Issue) value { return value{time: i.Fields.Updated.Time} }, kindDate, nil
+	case "due", "duedate":
+		return func(i *jira.Issue) value { return value{time: i.Fields.DueDate.Time} }, kindDate, nil
+	}
+
+	id, err := e.fieldID(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	return func(i *jira.Issue) value { return rawValue(i.Fields.Custom[id]) }, kindCustom, nil
+}
+
+// fieldID returns the ID of a field not in Fields, e.g. customfield_10016
+// for "Story Points" or cf[10016].
+func (e *env) fieldID(name string) (string, error) {
+	lower := strings.ToLower(strings.TrimSpace(name))
+	if id, ok := systemFields[lower]; ok {
+		return id, nil
+	}
+	if m := customIDPattern.FindStringSubmatch(lower); m != nil {
+		return "customfield_" + m[1], nil
+	}
+	if id, ok := e.fields[lower]; ok {
+		return id, nil
+	}
+	if strings.HasPrefix(lower, "customfield_") {
+		return lower, nil
+	}
+	return "", fmt.Errorf("field %q: %w", name, ErrUnsupported)
+}
+
+// commentText returns the text of an issue's stored comments.
+func (e *env) commentText(key string) string {
+	var parts []string
+	for _, c := range e.comments(key) {
+		parts = append(parts, c.Body.PlainText())
+	}
+	return strings.Join(parts, "\n")
+}
+
+// operand values resolved for a field.
+type resolved struct {
+	empty bool
+	text  string
+	time  time.Time
+	// day is set for dates without a time
+	day   bool
+	num   float64
+	isNum bool
+}
+
+// resolve evaluates an operand for a field of kind.
+func (e *env) resolve(o operand, kind fieldKind) (resolved, error) {
+	if o.empty() {
+		return resolved{empty: true}, nil
+	}
+	if o.fn != "" {
+		if o.fn == "currentuser" {
+			if e.me == "" {
+				return resolved{}, fmt.Errorf("currentUser(): the current user is not stored yet: %w", ErrUnsupported)
+			}
+			return resolved{text: e.me}, nil
+		}
+		t, err := e.dateFunc(o.fn, o.args)
+		if err != nil {
+			return resolved{}, fmt.Errorf("%s(): %w", o.text, err)
+		}
+		return resolved{time: t}, nil
+	}
+
+	r := resolved{text: o.text}
+	if kind == kindDate || kind == kindCustom {
+		t, day, ok := e.parseDate(o.text)
+		if !ok && kind == kindDate {
+			return resolved{}, fmt.Errorf("invalid date %q", o.text)
+		}
+		r.time, r.day = t, day
+	}
+	if n, err := strconv.ParseFloat(o.text, 64); err == nil && kind == kindCustom {
+		r.num, r.isNum = n, true
+	}
+	return r, nil
+}
+
+// relativePattern matches relative dates and function increments such as
+// -1d, 2w or +3h.
+var relativePattern = regexp.MustCompile(`^([+-]?)(\d+)([yMwdhm]?)$`)
+
+// dateLayouts are the absolute dates of JQL.
+var dateLayouts = []struct {
+	layout string
+	day    bool
+}{
+	{"2006-01-02 15:04", false},
+	{"2006/01/02 15:04", false},
+	{"2006-01-02", true},
+	{"2006/01/02", true},
+}
+
+// parseDate parses an absolute date in the user's timezone or a date
+// relative to now.
+func (e *env) parseDate(s string) (time.Time, bool, bool) {
+	if m := relativePattern.FindStringSubmatch(s); m != nil && m[3] != "" && m[3] != "y" && m[3] != "M" {
+		t, _ := shift(e.now, m, "")
+		return t, false, true
+	}
+	for _, l := range dateLayouts {
+		if t, err := time.ParseInLocation(l.layout, s, e.loc); err == nil {
+			return t, l.day, true
+		}
+	}
+	return time.Time{}, false, false
+}
+
+// dateFunc evaluates now(), startOfDay() and the like, with an optional
+// increment such as -1 or "+2d".
+func (e *env) dateFunc(fn string, args []string) (time.Time, error) {
+	if fn == "now" {
+		return e.now, nil
+	}
+	now := e.now.In(e.loc)
+	y, m, d := now.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, e.loc)
+
+	var start time.Time
+	var unit string
+	switch strings.TrimPrefix(strings.TrimPrefix(fn, "start"), "end") {
+	case "ofday":
+		start, unit = day, "d"
+	case "ofweek":
+		start, unit = day.AddDate(0, 0, -int(now.Weekday())), "w"
+	case "ofmonth":
+		start, unit = time.Date(y, m, 1, 0, 0, 0, 0, e.loc), "M"
+	case "ofyear":
+		start, unit = time.Date(y, 1, 1, 0, 0, 0, 0, e.loc), "y"
+	default:
+		return time.Time{}, ErrUnsupported
+	}
+
+	if len(args) > 0 {
+		m := relativePattern.FindStringSubmatch(args[0])
+		if m == nil {
+			return time.Time{}, fmt.Errorf("invalid increment %q", args[0])
+		}
+		start, _ = shift(start, m, unit)
+	}
+	if strings.HasPrefix(fn, "end") {
+		end, _ := shift(start, []string{"", "", "1", unit}, unit)
+		return end.Add(-time.Millisecond), nil
+	}
+	return start, nil
+}
+
+// shift moves t by a match of relativePattern, in unit when the match has
+// none.
+func shift(t time.Time, m []string, unit string) (time.Time, bool) {
+	n, err := strconv.Atoi(m[2])
+	if err != nil {
+		return t, false
+	}
+	if m[1] == "-" {
+		n = -n
+	}
+	switch cmp.Or(m[3], unit) {
+	case "y":
+		return t.AddDate(n, 0, 0), true
+	case "M":
+		return t.AddDate(0, n, 0), true
+	case "w":
+		return t.AddDate(0, 0, 7*n), true
+	case "d":
+		return t.AddDate(0, 0, n), true
+	case "h":
+		return t.Add(time.Duration(n) * time.Hour), true
+	case "m":
+		return t.Add(time.Duration(n) * time.Minute), true
+	}
+	return t, false
+}
+
+// matcher reports whether an issue matches a condition.
+type matcher func(*jira.Issue) bool
+
+// compile prepares the condition of a query for e.
+func (e *env) compile(x expr) (matcher, error) {
+	switch x := x.(type) {
+	case nil:
+		return func(*jira.Issue) bool { return true }, nil
+	case andExpr:
+		matchers, err := e.compileAll(x)
+		if err != nil {
+			return nil, err
+		}
+		return func(i *jira.Issue) bool {
+			for _, m := range matchers {
+				if !m(i) {
+					return false
+				}
+			}
+			return true
+		}, nil
+	case orExpr:
+		matchers, err := e.compileAll(x)
+		if err != nil {
+			return nil, err
+		}
+		return func(i *jira.Issue) bool {
+			for _, m := range matchers {
+				if m(i) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case notExpr:
+		m, err := e.compile(x.expr)
+		if err != nil {
+			return nil, err
+		}
+		return func(i *jira.Issue) bool { return !m(i) }, nil
+	case clause:
+		return e.compileClause(x)
+	}
+	return nil, fmt.Errorf("unknown expression %T", x)
+}
+
+// compileAll prepares the conditions of AND or OR.
+func (e *env) compileAll(parts []expr) ([]matcher, error) {
+	matchers := make([]matcher, len(parts))
+	for i, part := range parts {
+		m, err := e.compile(part)
+		if err != nil {
+			return nil, err
+		}
+		matchers[i] = m
+	}
+	return matchers, nil
+}
+
+// compileClause prepares a clause such as status = Done.
+func (e *env) compileClause(c clause) (matcher, error) {
+	get, kind, err := e.field(c.field)
+	if err != nil {
+		return nil, err
+	}
+	field := strings.ToLower(c.field)
+
+	operands := make([]resolved, len(c.operands))
+	for i, o := range c.operands {
+		r, err := e.resolve(o, kind)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.field, err)
+		}
+		// resolution = Unresolved is an empty resolution
+		if field == "resolution" && !o.quoted && strings.EqualFold(o.text, "unresolved") {
+			r = resolved{empty: true}
+		}
+		if field == "watcher" && (r.empty || r.text != e.me) {
+			return nil, fmt.Errorf("watcher other than currentUser(): %w", ErrUnsupported)
+		}
+		operands[i] = r
+	}
+	anyEqual := func(v value) bool {
+		return slices.ContainsFunc(operands, func(r resolved) bool { return e.equal(v, r) })
+	}
+
+	switch c.op {
+	case "is":
+		return func(i *jira.Issue) bool { return get(i).empty() }, nil
+	case "is not":
+		return func(i *jira.Issue) bool { return !get(i).empty() }, nil
+	case "=", "in":
+		return func(i *jira.Issue) bool { return anyEqual(get(i)) }, nil
+	case "!=", "not in":
+		// As in Jira, empty fields match neither
+		return func(i *jira.Issue) bool {
+			v := get(i)
+			return !v.empty() && !anyEqual(v)
+		}, nil
+	case "~", "!~":
+		terms := searchTerms(c.operands[0].text)
+		return func(i *jira.Issue) bool { return contains(get(i), terms) == (c.op == "~") }, nil
+	case ">", ">=", "<", "<=":
+		if kind == kindName || kind == kindText {
+			return nil, fmt.Errorf("%s %s: %w", c.field, c.op, ErrUnsupported)
+		}
+		r := operands[0]
+		return func(i *jira.Issue) bool {
+			n, ok := compareOperand(get(i), r)
+			if !ok {
+				return false
+			}
+			switch c.op {
+			case ">":
+				return n > 0
+			case ">=":
+				return n >= 0
+			case "<":
+				return n < 0
+			}
+			return n <= 0
+		}, nil
+	}
+	return nil, fmt.Errorf("operator %s: %w", c.op, ErrUnsupported)
+}
+
+// equal reports whether a field value equals an operand.
+func (e *env) equal(v value, r resolved) bool {
+	switch {
+	case r.empty:
+		return v.empty()
+	case !r.time.IsZero() && !v.time.IsZero():
+		if r.day {
+			y1, m1, d1 := v.time.In(e.loc).Date()
+			y2, m2, d2 := r.time.Date()
+			return y1 == y2 && m1 == m2 && d1 == d2
+		}
+		return v.time.Truncate(time.Minute).Equal(r.time.Truncate(time.Minute))
+	case r.isNum && v.isNum:
+		return v.num == r.num
+	}
+	return slices.ContainsFunc(v.ids, func(id string) bool { return strings.EqualFold(id, r.text) })
+}
+
+// compareOperand compares a date or number with an operand.
+func compareOperand(v value, r resolved) (int, bool) {
+	switch {
+	case !r.time.IsZero() && !v.time.IsZero():
+		return v.time.Compare(r.time), true
+	case r.isNum && v.isNum:
+		return cmp.Compare(v.num, r.num), true
+	}
+	return 0, false
+}
+
+// searchTerms splits the text of ~ into lowercase words, dropping
+// wildcards.
+func searchTerms(s string) []string {
+	var terms []string
+	for _, word := range strings.Fields(strings.ToLower(s)) {
+		if word = strings.Trim(word, `*?"`); word != "" {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// contains reports whether the text of a value holds all terms.
+func contains(v value, terms []string) bool {
+	text := v.text
+	if text == "" {
+		text = strings.Join(v.ids, " ")
+	}
+	text = strings.ToLower(text)
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return len(terms) > 0
+}
+
+// compareValues orders two values of a field; empty values come first.
+func compareValues(a, b value) int {
+	switch {
+	case !a.time.IsZero() || !b.time.IsZero():
+		return a.time.Compare(b.time)
+	case a.isNum || b.isNum:
+		if a.isNum != b.isNum {
+			if a.isNum {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Compare(a.num, b.num)
+	}
+	return strings.Compare(strings.ToLower(sortText(a)), strings.ToLower(sortText(b)))
+}
+
+// sortText returns what a value sorts by among names.
+func sortText(v value) string {
+	if v.sort != "" || len(v.ids) == 0 {
+		return v.sort
+	}
+	return v.ids[0]
+}
+
+// sortIssues orders issues by the ORDER BY of a query, newest key first
+// without one.
+func (e *env) sortIssues(issues []jira.Issue, order []sortKey) error {
+	if len(order) == 0 {
+		order = []sortKey{{field: "key", desc: true}}
+	}
+	getters := make([]getter, len(order))
+	for i, key := range order {
+		get, _, err := e.field(key.field)
+		if err != nil {
+			return err
+		}
+		getters[i] = get
+	}
+
+	values := make(map[string][]value, len(issues))
+	for i := range issues {
+		row := make([]value, len(getters))
+		for j, get := range getters {
+			row[j] = get(&issues[i])
+		}
+		values[issues[i].Key] = row
+	}
+	slices.SortStableFunc(issues, func(a, b jira.Issue) int {
+		for j, key := range order {
+			n := compareValues(values[a.Key][j], values[b.Key][j])
+			if key.desc {
+				n = -n
+			}
+			if n != 0 {
+				return n
+			}
+		}
+		return 0
+	})
+	return nil
+}
+
+// priorityRank orders a priority, higher ranks first when descending:
+// by name for the default priorities, else by ID, which Jira numbers
+// from the highest.
+func priorityRank(p jira.Priority) int {
+	if rank, ok := priorityRanks[strings.ToLower(p.Name)]; ok {
+		return rank
+	}
+	if id, err := strconv.Atoi(p.ID); err == nil && id < 100 {
+		return 100 - id
+	}
+	return 0
+}
+
+// userValue is the value of a user field.
+func userValue(u jira.User) value {
+	return value{ids: nonEmpty(u.AccountID, u.DisplayName, u.Email, u.Name), sort: u.DisplayName}
+}
+
+// rawValue is the value of a field kept as JSON: strings, numbers, dates,
+// options, users and lists of them.
+func rawValue(raw json.RawMessage) value {
+	var v any
+	if len(raw) == 0 || json.Unmarshal(raw, &v) != nil {
+		return value{}
+	}
+	var out value
+	out.add(v)
+	out.sort = sortText(out)
+	return out
+}
+
+// add adds a decoded JSON value to v.
+func (v *value) add(x any) {
+	switch x := x.(type) {
+	case string:
+		v.ids = append(v.ids, x)
+		v.text = strings.TrimSpace(v.text + " " + x)
+		if len(x) >= 10 && x[4] == '-' && v.time.IsZero() {
+			var t jira.Time
+			if json.Unmarshal(strconv.AppendQuote(nil, x), &t) == nil {
+				v.time = t.Time
+			}
+		}
+	case float64:
+		v.ids = append(v.ids, strconv.FormatFloat(x, 'f', -1, 64))
+		v.num, v.isNum = x, true
+	case bool:
+		v.ids = append(v.ids, strconv.FormatBool(x))
+	case []any:
+		for _, item := range x {
+			v.add(item)
+		}
+	case map[string]any:
+		if x["type"] == "doc" {
+			data, _ := json.Marshal(x)
+			var doc jira.ADF
+			if json.Unmarshal(data, &doc) == nil {
+				v.text = strings.TrimSpace(v.text + " " + doc.PlainText())
+			}
+			return
+		}
+		for _, key := range []string{"value", "name", "key", "displayName", "accountId", "emailAddress", "id"} {
+			if s, ok := x[key].(string); ok && s != "" {
+				v.ids = append(v.ids, s)
+			}
+		}
+		for _, key := range []string{"value", "name", "displayName"} {
+			if s, ok := x[key].(string); ok {
+				v.text = strings.TrimSpace(v.text + " " + s)
+			}
+		}
+		if child, ok := x["child"]; ok {
+			v.add(child)
+		}
+	}
+}
+
+// nonEmpty returns the non-empty strings.
+func nonEmpty(values ...string) []string {
+	var out []string
+	for _, s := range values {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
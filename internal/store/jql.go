@@ -0,0 +1,366 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errInvalidJQL is JQL the local store cannot parse, which Jira may.
+var errInvalidJQL = errors.New("invalid JQL")
+
+// ErrUnsupported is JQL the local store cannot evaluate, such as sprint
+// functions or fields it does not keep.
+var ErrUnsupported = errors.New("not supported by the local store")
+
+// tokenKind classifies the tokens of a JQL query.
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	// tokWord is a bare word: a field, keyword, value or number
+	tokWord
+	tokString
+	// tokOp is =, !=, ~, !~, >, >=, < or <=
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// lex splits a JQL query into tokens.
+func lex(jql string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(jql); {
+		c := jql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")"})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokComma, ","})
+			i++
+		case c == '"' || c == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(jql) && jql[j] != c; j++ {
+				if jql[j] == '\\' && j+1 < len(jql) {
+					j++
+				}
+				b.WriteByte(jql[j])
+			}
+			if j == len(jql) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, token{tokString, b.String()})
+			i = j + 1
+		case strings.IndexByte("=!~<>", c) >= 0:
+			op := jql[i : i+1]
+			if i+1 < len(jql) {
+				switch two := jql[i : i+2]; two {
+				case "!=", "!~", ">=", "<=":
+					op = two
+				}
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected ! at %d", i)
+			}
+			tokens = append(tokens, token{tokOp, op})
+			i += len(op)
+		default:
+			j := i
+			for j < len(jql) && strings.IndexByte(" \t\n\r(),\"'=!~<>", jql[j]) < 0 {
+				j++
+			}
+			tokens = append(tokens, token{tokWord, jql[i:j]})
+			i = j
+		}
+	}
+	return append(tokens, token{kind: tokEOF}), nil
+}
+
+// expr is a parsed condition.
+type expr any
+
+type (
+	andExpr []expr
+	orExpr  []expr
+	notExpr struct{ expr expr }
+	// clause compares a field with operands: op is one of the operators,
+	// "in", "not in", "is" or "is not"
+	clause struct {
+		field    string
+		op       string
+		operands []operand
+	}
+)
+
+// operand is a value of a clause: a literal or a function call.
+type operand struct {
+	text string
+	// quoted literals are never keywords such as EMPTY
+	quoted bool
+	// fn is the lowercase name of a function, with args
+	fn   string
+	args []string
+}
+
+// empty reports whether the operand is EMPTY or NULL.
+func (o operand) empty() bool {
+	return o.fn == "" && !o.quoted && (strings.EqualFold(o.text, "empty") || strings.EqualFold(o.text, "null"))
+}
+
+// sortKey is a field of ORDER BY.
+type sortKey struct {
+	field string
+	desc  bool
+}
+
+// query is a parsed JQL query.
+type query struct {
+	where expr
+	order []sortKey
+}
+
+// parser parses the tokens of a query.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse parses a JQL query.
+func parse(jql string) (*query, error) {
+	tokens, err := lex(jql)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidJQL, err)
+	}
+	p := &parser{tokens: tokens}
+	q, err := p.query()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidJQL, err)
+	}
+	return q, nil
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword reports whether the next token is the bare word k.
+func (p *parser) keyword(k string) bool {
+	t := p.peek()
+	return t.kind == tokWord && strings.EqualFold(t.text, k)
+}
+
+func (p *parser) query() (*query, error) {
+	q := &query{}
+	if p.peek().kind != tokEOF && !p.keyword("order") {
+		where, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		q.where = where
+	}
+	if p.keyword("order") {
+		p.next()
+		if !p.keyword("by") {
+			return nil, fmt.Errorf("expected BY after ORDER")
+		}
+		p.next()
+		for {
+			t := p.next()
+			if t.kind != tokWord && t.kind != tokString {
+				return nil, fmt.Errorf("expected a field to order by, got %q", t.text)
+			}
+			key := sortKey{field: t.text}
+			switch {
+			case p.keyword("desc"):
+				key.desc = true
+				p.next()
+			case p.keyword("asc"):
+				p.next()
+			}
+			q.order = append(q.order, key)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+	return q, nil
+}
+
+func (p *parser) or() (expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	exprs := orExpr{left}
+	for p.keyword("or") || p.peek().text == "||" {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, right)
+	}
+	if len(exprs) == 1 {
+		return left, nil
+	}
+	return exprs, nil
+}
+
+func (p *parser) and() (expr, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	exprs := andExpr{left}
+	for p.keyword("and") || p.peek().text == "&&" {
+		p.next()
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, right)
+	}
+	if len(exprs) == 1 {
+		return left, nil
+	}
+	return exprs, nil
+}
+
+func (p *parser) not() (expr, error) {
+	switch {
+	case p.keyword("not"):
+		p.next()
+		e, err := p.not()
+		return notExpr{e}, err
+	case p.peek().kind == tokLParen:
+		p.next()
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokRParen {
+			return nil, fmt.Errorf("expected ), got %q", t.text)
+		}
+		return e, nil
+	}
+	return p.clause()
+}
+
+func (p *parser) clause() (expr, error) {
+	t := p.next()
+	if t.kind != tokWord && t.kind != tokString {
+		return nil, fmt.Errorf("expected a field, got %q", t.text)
+	}
+	c := clause{field: t.text}
+
+	switch op := p.next(); {
+	case op.kind == tokOp:
+		c.op = op.text
+	case op.kind == tokWord && strings.EqualFold(op.text, "in"):
+		c.op = "in"
+	case op.kind == tokWord && strings.EqualFold(op.text, "not") && p.keyword("in"):
+		p.next()
+		c.op = "not in"
+	case op.kind == tokWord && strings.EqualFold(op.text, "is"):
+		c.op = "is"
+		if p.keyword("not") {
+			p.next()
+			c.op = "is not"
+		}
+	case op.kind == tokWord && (strings.EqualFold(op.text, "was") || strings.EqualFold(op.text, "changed")):
+		return nil, fmt.Errorf("%s %s: %w", c.field, strings.ToUpper(op.text), ErrUnsupported)
+	default:
+		return nil, fmt.Errorf("expected an operator after %s, got %q", c.field, op.text)
+	}
+
+	if (c.op == "in" || c.op == "not in") && p.peek().kind != tokLParen {
+		// A function returning a list, such as openSprints()
+		o, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if o.fn == "" {
+			return nil, fmt.Errorf("expected ( after %s, got %q", strings.ToUpper(c.op), o.text)
+		}
+		c.operands = []operand{o}
+		return c, nil
+	}
+	if c.op == "in" || c.op == "not in" {
+		p.next()
+		for {
+			o, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			c.operands = append(c.operands, o)
+			if t := p.next(); t.kind == tokRParen {
+				break
+			} else if t.kind != tokComma {
+				return nil, fmt.Errorf("expected , or ), got %q", t.text)
+			}
+		}
+		return c, nil
+	}
+
+	o, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if (c.op == "is" || c.op == "is not") && !o.empty() {
+		return nil, fmt.Errorf("expected EMPTY after %s, got %q", strings.ToUpper(c.op), o.text)
+	}
+	c.operands = []operand{o}
+	return c, nil
+}
+
+func (p *parser) operand() (operand, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return operand{text: t.text, quoted: true}, nil
+	case tokWord:
+	default:
+		return operand{}, fmt.Errorf("expected a value, got %q", t.text)
+	}
+	if p.peek().kind != tokLParen {
+		return operand{text: t.text}, nil
+	}
+
+	p.next()
+	o := operand{text: t.text, fn: strings.ToLower(t.text)}
+	for p.peek().kind != tokRParen {
+		arg := p.next()
+		if arg.kind != tokWord && arg.kind != tokString {
+			return operand{}, fmt.Errorf("expected an argument of %s(), got %q", t.text, arg.text)
+		}
+		o.args = append(o.args, arg.text)
+		if p.peek().kind == tokComma {
+			p.next()
+		}
+	}
+	p.next()
+	return o, nil
+}
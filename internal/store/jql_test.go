@@ -0,0 +1,237 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"jirar/internal/jira"
+)
+
+// testNow is a Wednesday; weeks start on Sunday 2026-10-11.
+var testNow = time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+var (
+	toDo       = jira.Status{Name: "To Do", ID: "11", StatusCategory: jira.StatusCategory{Key: "new", Name: "To Do"}}
+	inProgress = jira.Status{Name: "In Progress", ID: "21", StatusCategory: jira.StatusCategory{Key: "indeterminate", Name: "In Progress"}}
+	done       = jira.Status{Name: "Done", ID: "41", StatusCategory: jira.StatusCategory{Key: "done", Name: "Done"}}
+
+	jane = jira.User{AccountID: "me-1", DisplayName: "Jane Doe", Email: "jane@example.com"}
+	bob  = jira.User{AccountID: "bob-2", DisplayName: "Bob"}
+)
+
+// testIssue is an issue with its custom fields given as JSON.
+type testIssue struct {
+	key      string
+	summary  string
+	status   jira.Status
+	priority string
+	assignee jira.User
+	created  string
+	updated  string
+	due      string
+	custom   map[string]string
+}
+
+// testIssues are the stored issues queries are evaluated over.
+var testIssues = []testIssue{
+	{
+		key: "PROJ-1", summary: "Fix login bug", status: toDo, priority: "High", assignee: jane,
+		created: "2026-10-14 09:00", updated: "2026-10-14 10:00",
+		custom: map[string]string{"labels": `["backend"]`, "customfield_10016": `3`, "watches": `{"isWatching":true}`},
+	},
+	{
+		key: "PROJ-2", summary: "Write docs", status: inProgress, priority: "Low", assignee: bob,
+		created: "2026-10-01 08:00", updated: "2026-10-12 12:00",
+		custom: map[string]string{"labels": `["docs"]`, "customfield_10016": `5`},
+	},
+	{
+		key: "PROJ-10", summary: "Login page redesign", status: done, priority: "Highest",
+		created: "2026-09-01 08:00", updated: "2026-10-14 11:30",
+		custom: map[string]string{"labels": `["backend","ui"]`, "customfield_10016": `8`, "resolution": `{"name":"Done","id":"1"}`},
+	},
+	{
+		key: "OPS-3", summary: "Rotate keys", status: toDo, priority: "Medium", assignee: jane,
+		created: "2026-10-10 15:00", updated: "2026-10-08 09:00", due: "2026-10-15",
+	},
+}
+
+// issue builds the stored issue.
+func (ti testIssue) issue(t *testing.T) jira.Issue {
+	t.Helper()
+	at := func(s string) jira.Time {
+		if s == "" {
+			return jira.Time{}
+		}
+		layout := "2006-01-02 15:04"
+		if len(s) == len(time.DateOnly) {
+			layout = time.DateOnly
+		}
+		tm, err := time.ParseInLocation(layout, s, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return jira.Time{Time: tm}
+	}
+	project, _, _ := strings.Cut(ti.key, "-")
+	issue := jira.Issue{Key: ti.key, Fields: jira.Fields{
+		Summary:   ti.summary,
+		Status:    ti.status,
+		Priority:  jira.Priority{Name: ti.priority},
+		Assignee:  ti.assignee,
+		Reporter:  jane,
+		Created:   at(ti.created),
+		Updated:   at(ti.updated),
+		DueDate:   at(ti.due),
+		Project:   jira.Project{Key: project, Name: project},
+		IssueType: jira.IssueType{Name: "Task"},
+		Custom:    map[string]json.RawMessage{},
+	}}
+	for id, raw := range ti.custom {
+		issue.Fields.Custom[id] = json.RawMessage(raw)
+	}
+	return issue
+}
+
+// testEnv evaluates queries as Jane at testNow.
+func testEnv(me string) *env {
+	return &env{
+		me:     me,
+		loc:    time.UTC,
+		now:    testNow,
+		fields: map[string]string{"story points": "customfield_10016", "customfield_10016": "customfield_10016"},
+		comments: func(key string) []jira.Comment {
+			if key != "PROJ-2" {
+				return nil
+			}
+			return []jira.Comment{{Body: jira.NewADF("Needs a section on the outbox")}}
+		},
+	}
+}
+
+// evaluate runs jql over testIssues like Client.search.
+func evaluate(t *testing.T, e *env, jql string) ([]string, error) {
+	t.Helper()
+	q, err := parse(jql)
+	if err != nil {
+		return nil, err
+	}
+	match, err := e.compile(q.where)
+	if err != nil {
+		return nil, err
+	}
+	var issues []jira.Issue
+	for _, ti := range testIssues {
+		if issue := ti.issue(t); match(&issue) {
+			issues = append(issues, issue)
+		}
+	}
+	if err := e.sortIssues(issues, q.order); err != nil {
+		return nil, err
+	}
+	keys := []string{}
+	for _, issue := range issues {
+		keys = append(keys, issue.Key)
+	}
+	return keys, nil
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name string
+		jql  string
+		want []string
+	}{
+		{"empty query lists all, newest key first", "", []string{"PROJ-10", "PROJ-2", "PROJ-1", "OPS-3"}},
+		{"equality", "project = PROJ", []string{"PROJ-10", "PROJ-2", "PROJ-1"}},
+		{"keywords are case-insensitive", "Project = proj and STATUS = done", []string{"PROJ-10"}},
+		{"AND binds tighter than OR", "project = OPS OR project = PROJ AND status = Done", []string{"PROJ-10", "OPS-3"}},
+		{"parentheses", `(project = OPS OR project = PROJ) AND status = "To Do"`, []string{"PROJ-1", "OPS-3"}},
+		{"NOT binds tighter than AND", "NOT status = Done AND priority in (High, Low)", []string{"PROJ-2", "PROJ-1"}},
+		{"NOT of a group", "NOT (status = Done OR project = OPS)", []string{"PROJ-2", "PROJ-1"}},
+		{"symbolic AND and OR", "status = Done || assignee = Bob && project = PROJ", []string{"PROJ-10", "PROJ-2"}},
+		{"NOT IN", `status not in ("To Do", Done)`, []string{"PROJ-2"}},
+		{"currentUser()", "assignee = currentUser()", []string{"PROJ-1", "OPS-3"}},
+		{"currentUser() in a list", "assignee in (currentUser(), Bob)", []string{"PROJ-2", "PROJ-1", "OPS-3"}},
+		{"empty fields match no inequality", "assignee != currentUser()", []string{"PROJ-2"}},
+		{"IS EMPTY", "assignee is EMPTY", []string{"PROJ-10"}},
+		{"IS NOT EMPTY", "due is not empty", []string{"OPS-3"}},
+		{"unresolved", "resolution = Unresolved", []string{"PROJ-2", "PROJ-1", "OPS-3"}},
+		{"status category", `statusCategory = "In Progress"`, []string{"PROJ-2"}},
+		{"watcher", "watcher = currentUser()", []string{"PROJ-1"}},
+		{"text search", "summary ~ login", []string{"PROJ-10", "PROJ-1"}},
+		{"text search needs every term", `text ~ "login bug"`, []string{"PROJ-1"}},
+		{"text search reads comments", "comment ~ outbox", []string{"PROJ-2"}},
+		{"negated text search", "summary !~ login", []string{"PROJ-2", "OPS-3"}},
+		{"labels", "labels = backend", []string{"PROJ-10", "PROJ-1"}},
+		{"custom field by name", `"Story Points" > 4`, []string{"PROJ-10", "PROJ-2"}},
+		{"custom field by cf[]", "cf[10016] <= 3", []string{"PROJ-1"}},
+		{"relative days", "updated >= -1d", []string{"PROJ-10", "PROJ-1"}},
+		{"relative hours", "updated > -1h", []string{"PROJ-10"}},
+		{"relative weeks", "created < -2w", []string{"PROJ-10"}},
+		{"startOfDay()", "created >= startOfDay()", []string{"PROJ-1"}},
+		{"startOfMonth()", "created < startOfMonth()", []string{"PROJ-10"}},
+		{"startOfWeek() with an increment", "created >= startOfWeek(-1)", []string{"PROJ-1", "OPS-3"}},
+		{"endOfDay() with an increment", "due <= endOfDay(1)", []string{"OPS-3"}},
+		{"now()", "updated < now() AND updated > -3h", []string{"PROJ-10", "PROJ-1"}},
+		{"a day matches any time in it", `created = "2026-10-14"`, []string{"PROJ-1"}},
+		{"absolute date", "created >= 2026/10/10", []string{"PROJ-1", "OPS-3"}},
+		{"order by priority", "project = PROJ ORDER BY priority DESC", []string{"PROJ-10", "PROJ-1", "PROJ-2"}},
+		{"order by date", "ORDER BY updated ASC", []string{"OPS-3", "PROJ-2", "PROJ-1", "PROJ-10"}},
+		{"order by several fields", "order by status, key asc", []string{"PROJ-10", "PROJ-2", "OPS-3", "PROJ-1"}},
+		{"keys order numerically", "ORDER BY key", []string{"OPS-3", "PROJ-1", "PROJ-2", "PROJ-10"}},
+		{"empty values order last descending", `ORDER BY "Story Points" DESC`, []string{"PROJ-10", "PROJ-2", "PROJ-1", "OPS-3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evaluate(t, testEnv(jane.AccountID), tt.jql)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.jql, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("%s = %v, want %v", tt.jql, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateUnsupported(t *testing.T) {
+	tests := []struct {
+		name string
+		jql  string
+		me   string
+		want error
+	}{
+		{"sprint functions", "sprint in openSprints()", jane.AccountID, ErrUnsupported},
+		{"list functions", "fixVersion in releasedVersions()", jane.AccountID, ErrUnsupported},
+		{"WAS", "status WAS Done", jane.AccountID, ErrUnsupported},
+		{"CHANGED", "assignee CHANGED", jane.AccountID, ErrUnsupported},
+		{"unknown fields", `"Team" = Platform`, jane.AccountID, ErrUnsupported},
+		{"ordering by unknown fields", "ORDER BY Rank", jane.AccountID, ErrUnsupported},
+		{"comparing names", "summary > a", jane.AccountID, ErrUnsupported},
+		{"watchers other than me", "watcher = bob-2", jane.AccountID, ErrUnsupported},
+		{"currentUser() before a sync", "assignee = currentUser()", "", ErrUnsupported},
+		{"missing value", "status = ", jane.AccountID, errInvalidJQL},
+		{"unbalanced parentheses", "(project = PROJ", jane.AccountID, errInvalidJQL},
+		{"unterminated string", `summary ~ "login`, jane.AccountID, errInvalidJQL},
+		{"ORDER without BY", "ORDER status", jane.AccountID, errInvalidJQL},
+		{"IS without EMPTY", "assignee is Bob", jane.AccountID, errInvalidJQL},
+		{"invalid date", "created > yesterday", jane.AccountID, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := evaluate(t, testEnv(tt.me), tt.jql)
+			switch {
+			case err == nil:
+				t.Fatalf("%s: no error", tt.jql)
+			case tt.want != nil && !errors.Is(err, tt.want):
+				t.Errorf("%s: %v, want %v", tt.jql, err, tt.want)
+			case tt.want == nil && (errors.Is(err, ErrUnsupported) || errors.Is(err, errInvalidJQL)):
+				t.Errorf("%s: %v, want an error Jira would give too", tt.jql, err)
+			}
+		})
+	}
+}
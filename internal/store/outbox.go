@@ -0,0 +1,308 @@
+package store
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+
+	"jirar/internal/jira"
+)
+
+// issuePathPattern matches the REST paths of an issue and what belongs to
+// it, capturing the issue key; changes to other paths cannot be queued.
+var issuePathPattern = regexp.MustCompile(`^/rest/api/[23]/issue(?:/([A-Za-z][A-Za-z0-9_]*-\d+)(?:/.*)?)?$`)
+
+// Op is a change made offline, queued in the outbox until Push sends it to
+// Jira as the request it would have been.
+type Op struct {
+	ID uint64 `json:"id"`
+	// Key is the issue changed; empty when creating one
+	Key string `json:"key,omitempty"`
+	// Action describes the change, e.g. transition to Done
+	Action string          `json:"action"`
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Query  url.Values      `json:"query,omitempty"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	// Updated is when the issue was last updated as stored when the change
+	// was queued; Push holds the change back when Jira's is later
+	Updated time.Time `json:"updated,omitzero"`
+	Queued  time.Time `json:"queued"`
+	// Conflict is why the last push held the change back
+	Conflict string `json:"conflict,omitempty"`
+}
+
+// request returns the request that makes the change.
+func (op *Op) request() *jira.RawRequest {
+	req := &jira.RawRequest{Method: op.Method, Path: op.Path, Query: op.Query, Header: op.Header}
+	if len(op.Body) > 0 {
+		req.Body = op.Body
+	}
+	return req
+}
+
+// change is how a queued change shows in the store before it is pushed.
+type change struct {
+	status   *jira.Status
+	assignee *jira.User
+	comment  *jira.Comment
+}
+
+// queue adds op to the outbox, setting its ID, when it was queued and the
+// update of the stored issue it is based on, and applies ch to the issue.
+func (s *Store) queue(op *Op, ch change) error {
+	op.Queued = time.Now()
+	return s.update(func(tx *bolt.Tx) error {
+		issues := tx.Bucket(bucketIssues)
+		var e entry
+		err := get(issues, op.Key, &e)
+		if err != nil && !errors.Is(err, ErrNotStored) {
+			return err
+		}
+		if err == nil {
+			op.Updated = e.Issue.Fields.Updated.Time
+			if ch.status != nil {
+				// The transitions stored lead from the old status
+				e.Issue.Fields.Status, e.Transitions = *ch.status, nil
+			}
+			if ch.assignee != nil {
+				e.Issue.Fields.Assignee = *ch.assignee
+			}
+			if err := put(issues, op.Key, e); err != nil {
+				return err
+			}
+		}
+		if ch.comment != nil {
+			var comments []jira.Comment
+			if err := get(tx.Bucket(bucketComments), op.Key, &comments); err != nil && !errors.Is(err, ErrNotStored) {
+				return err
+			}
+			if err := put(tx.Bucket(bucketComments), op.Key, append(comments, *ch.comment)); err != nil {
+				return err
+			}
+		}
+
+		outbox := tx.Bucket(bucketOutbox)
+		id, err := outbox.NextSequence()
+		if err != nil {
+			return err
+		}
+		op.ID = id
+		return putOp(outbox, *op)
+	})
+}
+
+// Outbox returns the queued changes, oldest first.
+func (s *Store) Outbox() ([]Op, error) {
+	var ops []Op
+	err := s.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketOutbox).ForEach(func(_, data []byte) error {
+			var op Op
+			if err := json.Unmarshal(data, &op); err != nil {
+				return fmt.Errorf("read queued change: %w", err)
+			}
+			ops = append(ops, op)
+			return nil
+		})
+	})
+	return ops, err
+}
+
+// Unqueue removes changes from the outbox and returns them; ErrNotStored
+// if one is not queued.
+func (s *Store) Unqueue(ids ...uint64) ([]Op, error) {
+	var ops []Op
+	err := s.update(func(tx *bolt.Tx) error {
+		outbox := tx.Bucket(bucketOutbox)
+		for _, id := range ids {
+			data := outbox.Get(opKey(id))
+			if data == nil {
+				return fmt.Errorf("change %d: %w", id, ErrNotStored)
+			}
+			var op Op
+			if err := json.Unmarshal(data, &op); err != nil {
+				return fmt.Errorf("read queued change: %w", err)
+			}
+			ops = append(ops, op)
+			if err := outbox.Delete(opKey(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return ops, err
+}
+
+// putOp stores a queued change.
+func putOp(b *bolt.Bucket, op Op) error {
+	data, err := json.Marshal(op)
+	if err != nil {
+		return fmt.Errorf("encode change %d: %w", op.ID, err)
+	}
+	return b.Put(opKey(op.ID), data)
+}
+
+// opKey orders queued changes by ID.
+func opKey(id uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, id)
+}
+
+// Rejection is a queued change Push did not make.
+type Rejection struct {
+	Op     Op     `json:"op"`
+	Reason string `json:"reason"`
+	// Kept is set for conflicts, which stay queued to be pushed with Force
+	// or discarded; changes Jira refused leave the outbox
+	Kept bool `json:"kept,omitempty"`
+}
+
+// PushResult reports a push.
+type PushResult struct {
+	Pushed   []Op        `json:"pushed"`
+	Rejected []Rejection `json:"rejected"`
+}
+
+// Push replays the outbox against Jira in the order the changes were made.
+// A change to an issue updated in Jira since it was stored is a conflict
+// and held back, unless force; so are later changes to the issue. A change
+// Jira refuses is dropped. Issues changed are refetched, undoing in the
+// store what did not reach Jira. Push stops at the first change that
+// could not be sent, leaving it and the rest queued.
+func Push(ctx context.Context, client jira.Client, st *Store, force bool, logger *logrus.Logger) (*PushResult, error) {
+	ops, err := st.Outbox()
+	if err != nil || len(ops) == 0 {
+		return &PushResult{}, err
+	}
+
+	result := &PushResult{}
+	// expected is the update of issues after changes this push made
+	expected := map[string]time.Time{}
+	var keys []string
+	defer func() {
+		if len(keys) == 0 {
+			return
+		}
+		if err := Refresh(ctx, client, st, keys...); err != nil {
+			logger.WithError(err).Warn("Could not refetch the issues changed")
+		}
+	}()
+
+	for _, op := range ops {
+		if op.Key != "" && !slices.Contains(keys, op.Key) {
+			keys = append(keys, op.Key)
+		}
+		reason, kept, err := push(ctx, client, &op, expected, force)
+		if err != nil {
+			return result, fmt.Errorf("push %s of %s: %w", op.Action, op.Key, err)
+		}
+
+		err = st.update(func(tx *bolt.Tx) error {
+			if kept {
+				op.Conflict = reason
+				return putOp(tx.Bucket(bucketOutbox), op)
+			}
+			return tx.Bucket(bucketOutbox).Delete(opKey(op.ID))
+		})
+		if err != nil {
+			return result, err
+		}
+		if reason != "" {
+			result.Rejected = append(result.Rejected, Rejection{Op: op, Reason: reason, Kept: kept})
+			continue
+		}
+		logger.WithFields(logrus.Fields{"key": op.Key, "action": op.Action}).Debug("Pushed queued change")
+		result.Pushed = append(result.Pushed, op)
+	}
+	return result, nil
+}
+
+// push sends a queued change unless it conflicts, returning why it was
+// rejected and whether it stays queued.
+func push(ctx context.Context, client jira.Client, op *Op, expected map[string]time.Time, force bool) (string, bool, error) {
+	if op.Key != "" && !force {
+		updated, reason, err := issueUpdated(ctx, client, op.Key)
+		if err != nil || reason != "" {
+			return reason, false, err
+		}
+		base := op.Updated
+		if t, ok := expected[op.Key]; ok {
+			base = t
+		}
+		if !base.IsZero() && updated.After(base) {
+			return fmt.Sprintf("%s was updated in Jira %s, after the change was queued", op.Key, updated.Local().Format(time.DateTime)), true, nil
+		}
+	}
+
+	resp, err := client.Do(ctx, op.request())
+	if err != nil {
+		return "", false, err
+	}
+	if resp.StatusCode >= 500 {
+		return "", false, fmt.Errorf("HTTP %s", resp.Status)
+	}
+	if resp.StatusCode >= 300 {
+		return rejectReason(resp), false, nil
+	}
+
+	if op.Key != "" {
+		// Later changes are based on this one
+		updated, _, err := issueUpdated(ctx, client, op.Key)
+		if err == nil && !updated.IsZero() {
+			expected[op.Key] = updated
+		}
+	}
+	return "", false, nil
+}
+
+// issueUpdated returns when an issue was last updated in Jira, or why it
+// cannot be changed.
+func issueUpdated(ctx context.Context, client jira.Client, key string) (time.Time, string, error) {
+	resp, err := client.Do(ctx, &jira.RawRequest{
+		Method: http.MethodGet,
+		Path:   "/rest/api/3/issue/" + key,
+		Query:  url.Values{"fields": {"updated"}},
+	})
+	switch {
+	case err != nil:
+		return time.Time{}, "", err
+	case resp.StatusCode >= 500:
+		return time.Time{}, "", fmt.Errorf("HTTP %s", resp.Status)
+	case resp.StatusCode >= 300:
+		return time.Time{}, rejectReason(resp), nil
+	}
+	var issue jira.Issue
+	if err := json.Unmarshal(resp.Body, &issue); err != nil {
+		return time.Time{}, "", fmt.Errorf("parse %s: %w", key, err)
+	}
+	return issue.Fields.Updated.Time, "", nil
+}
+
+// rejectReason returns the error messages of a Jira response.
+func rejectReason(resp *jira.RawResponse) string {
+	var body struct {
+		ErrorMessages []string          `json:"errorMessages"`
+		Errors        map[string]string `json:"errors"`
+	}
+	json.Unmarshal(resp.Body, &body)
+	reasons := body.ErrorMessages
+	for _, field := range slices.Sorted(maps.Keys(body.Errors)) {
+		reasons = append(reasons, field+": "+body.Errors[field])
+	}
+	if len(reasons) == 0 {
+		return "Jira answered " + resp.Status
+	}
+	return strings.Join(reasons, "; ")
+}
@@ -0,0 +1,644 @@
+// Package store keeps a local copy of Jira for reads that do not wait for
+// the network: the issues of configured JQL scopes with their comments,
+// and the users, projects, statuses and fields they refer to. It is a
+// bbolt database per profile that Sync brings up to date incrementally;
+// Client answers jira.Client from it, evaluating JQL locally, and queues
+// changes made offline in an outbox that Push replays.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	bolterrors "go.etcd.io/bbolt/errors"
+
+	"jirar/internal/jira"
+)
+
+// Buckets of the database. Issues, comments and scopes are keyed by issue
+// key and JQL, users by account ID, projects by key, the outbox by
+// sequence, the rest by ID.
+var (
+	bucketIssues   = []byte("issues")
+	bucketComments = []byte("comments")
+	bucketUsers    = []byte("users")
+	bucketProjects = []byte("projects")
+	bucketStatuses = []byte("statuses")
+	bucketFields   = []byte("fields")
+	bucketScopes   = []byte("scopes")
+	bucketMeta     = []byte("meta")
+	bucketOutbox   = []byte("outbox")
+
+	buckets = [][]byte{
+		bucketIssues, bucketComments, bucketUsers, bucketProjects,
+		bucketStatuses, bucketFields, bucketScopes, bucketMeta, bucketOutbox,
+	}
+)
+
+// Keys of the meta bucket.
+var (
+	metaDomain   = []byte("domain")
+	metaMyself   = []byte("myself")
+	metaMetadata = []byte("metadata")
+)
+
+// ErrNotStored is returned for what the local store does not hold.
+var ErrNotStored = errors.New("not in the local store")
+
+// lockTimeout is how long Open waits for another jirar to close the store.
+const lockTimeout = time.Second
+
+// Store is the local store of a profile.
+type Store struct {
+	// mu guards db, which is reopened when a read-only store is written
+	mu       sync.RWMutex
+	db       *bolt.DB
+	path     string
+	readOnly bool
+}
+
+// entry is a stored issue, the scopes it was synced for and the
+// transitions it had then.
+type entry struct {
+	Issue       jira.Issue        `json:"issue"`
+	Scopes      []string          `json:"scopes,omitempty"`
+	Transitions []jira.Transition `json:"transitions,omitempty"`
+}
+
+// Scope is the sync state of a JQL scope.
+type Scope struct {
+	JQL string `json:"jql"`
+	// Synced is when the scope was last brought up to date
+	Synced time.Time `json:"synced"`
+	// Reconciled is when the scope was last listed in full, dropping the
+	// issues that left it
+	Reconciled time.Time `json:"reconciled"`
+}
+
+// Field is the metadata of an issue field, as listed by Jira.
+type Field struct {
+	ID     string `json:"id"`
+	Key    string `json:"key,omitempty"`
+	Name   string `json:"name"`
+	Custom bool   `json:"custom"`
+	Schema struct {
+		Type   string `json:"type,omitempty"`
+		Items  string `json:"items,omitempty"`
+		System string `json:"system,omitempty"`
+	} `json:"schema"`
+}
+
+// Info summarizes a store.
+type Info struct {
+	Path     string `json:"path"`
+	Domain   string `json:"domain"`
+	Size     int64  `json:"size"`
+	Issues   int    `json:"issues"`
+	Comments int    `json:"comments"`
+	Users    int    `json:"users"`
+	Projects int    `json:"projects"`
+	Statuses int    `json:"statuses"`
+	Fields   int    `json:"fields"`
+	// Queued counts the changes in the outbox
+	Queued int     `json:"queued"`
+	Scopes []Scope `json:"scopes"`
+	// Metadata is when users, projects, statuses and fields were fetched
+	Metadata time.Time `json:"metadata,omitzero"`
+}
+
+// Synced returns the oldest sync of the scopes; zero if none was synced.
+func (i *Info) Synced() time.Time {
+	var oldest time.Time
+	for _, s := range i.Scopes {
+		if oldest.IsZero() || s.Synced.Before(oldest) {
+			oldest = s.Synced
+		}
+	}
+	return oldest
+}
+
+// DefaultPath returns the store of a profile under the user cache
+// directory.
+func DefaultPath(profile string) string {
+	if profile == "" {
+		profile = "default"
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = filepath.Join(os.TempDir(), "jirar-cache")
+	}
+	return filepath.Join(dir, "jirar", profile, "store.db")
+}
+
+// Open opens the store at path for writing, creating it if needed. Only
+// one jirar writes a store at a time, and none reads it meanwhile.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create cache directory: %w", err)
+	}
+	db, err := openDB(path, false)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range buckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open local store: %w", err)
+	}
+	return &Store{db: db, path: path}, nil
+}
+
+// OpenReadOnly opens the store at path for reading, which any number of
+// jirar do at once. The first write reopens it for writing, waiting for
+// the readers like Open. A store that does not exist yet is created.
+func OpenReadOnly(path string) (*Store, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return Open(path)
+	}
+	db, err := openDB(path, true)
+	if err != nil {
+		return nil, err
+	}
+	complete := true
+	db.View(func(tx *bolt.Tx) error {
+		for _, name := range buckets {
+			complete = complete && tx.Bucket(name) != nil
+		}
+		return nil
+	})
+	if !complete {
+		// Written by an older jirar; Open adds the buckets
+		db.Close()
+		return Open(path)
+	}
+	return &Store{db: db, path: path, readOnly: true}, nil
+}
+
+// openDB opens the database at path, waiting lockTimeout for a writer, or
+// for readers when writing.
+func openDB(path string, readOnly bool) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: lockTimeout, ReadOnly: readOnly})
+	if errors.Is(err, bolterrors.ErrTimeout) {
+		return nil, fmt.Errorf("the local store %s is in use by another jirar", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open local store: %w", err)
+	}
+	return db, nil
+}
+
+// view reads the store in a transaction.
+func (s *Store) view(f func(*bolt.Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.db.View(f)
+}
+
+// update writes the store in a transaction, reopening it for writing first
+// if it was opened read-only.
+func (s *Store) update(f func(*bolt.Tx) error) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.db.Update(f)
+}
+
+// writable reopens a read-only store for writing. When another jirar holds
+// it, the store stays open for reading.
+func (s *Store) writable() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.readOnly {
+		return nil
+	}
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("close local store: %w", err)
+	}
+	db, err := openDB(s.path, false)
+	if err != nil {
+		if reopened, rerr := openDB(s.path, true); rerr == nil {
+			s.db = reopened
+		}
+		return err
+	}
+	s.db, s.readOnly = db, false
+	return nil
+}
+
+// Close closes the store.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Close()
+}
+
+// Path returns the file of the store.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Domain returns the Jira site the store holds; empty for a new store.
+func (s *Store) Domain() (string, error) {
+	var domain string
+	err := s.view(func(tx *bolt.Tx) error {
+		domain = string(tx.Bucket(bucketMeta).Get(metaDomain))
+		return nil
+	})
+	return domain, err
+}
+
+// Reset empties the store and records the Jira site it holds. It fails
+// while changes are queued, as they are for the site held.
+func (s *Store) Reset(domain string) error {
+	return s.update(func(tx *bolt.Tx) error {
+		if n := tx.Bucket(bucketOutbox).Stats().KeyN; n > 0 {
+			held := tx.Bucket(bucketMeta).Get(metaDomain)
+			return fmt.Errorf("the local store holds %d changes queued for %s (push them with 'jirar sync --push' first)", n, held)
+		}
+		for _, name := range buckets {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(bucketMeta).Put(metaDomain, []byte(domain))
+	})
+}
+
+// Issue returns a stored issue.
+func (s *Store) Issue(key string) (*jira.Issue, error) {
+	var e entry
+	err := s.view(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(bucketIssues), strings.ToUpper(key), &e)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("issue %s: %w", key, err)
+	}
+	return &e.Issue, nil
+}
+
+// ForEachIssue calls f with every stored issue in key order until it
+// returns an error.
+func (s *Store) ForEachIssue(f func(*jira.Issue) error) error {
+	return s.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketIssues).ForEach(func(_, data []byte) error {
+			var e entry
+			if err := json.Unmarshal(data, &e); err != nil {
+				return fmt.Errorf("read stored issue: %w", err)
+			}
+			return f(&e.Issue)
+		})
+	})
+}
+
+// Transitions returns the transitions an issue had when it was synced.
+func (s *Store) Transitions(key string) ([]jira.Transition, error) {
+	var e entry
+	err := s.view(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(bucketIssues), strings.ToUpper(key), &e)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("transitions of %s: %w", key, err)
+	}
+	return e.Transitions, nil
+}
+
+// Comments returns the stored comments of an issue, oldest first.
+func (s *Store) Comments(key string) ([]jira.Comment, error) {
+	var comments []jira.Comment
+	err := s.view(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(bucketComments), strings.ToUpper(key), &comments)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("comments of %s: %w", key, err)
+	}
+	return comments, nil
+}
+
+// PutIssues stores issues synced for scope with their comments, keyed by
+// issue; issues without comments or transitions keep those stored. The users, projects
+// and statuses the issues refer to are stored too, unless already known.
+// An empty scope only updates issues already stored.
+func (s *Store) PutIssues(scope string, issues []jira.Issue, comments map[string][]jira.Comment) error {
+	return s.update(func(tx *bolt.Tx) error {
+		for _, issue := range issues {
+			var e entry
+			err := get(tx.Bucket(bucketIssues), issue.Key, &e)
+			switch {
+			case errors.Is(err, ErrNotStored) && scope == "":
+				continue
+			case err != nil && !errors.Is(err, ErrNotStored):
+				return err
+			}
+			e.Issue = issue
+			if issue.Transitions != nil {
+				e.Transitions, e.Issue.Transitions = issue.Transitions, nil
+			}
+			if scope != "" && !slices.Contains(e.Scopes, scope) {
+				e.Scopes = append(e.Scopes, scope)
+			}
+			if err := put(tx.Bucket(bucketIssues), issue.Key, e); err != nil {
+				return err
+			}
+
+			f := issue.Fields
+			users := []jira.User{f.Assignee, f.Reporter}
+			if list, ok := comments[issue.Key]; ok {
+				if err := put(tx.Bucket(bucketComments), issue.Key, list); err != nil {
+					return err
+				}
+				for _, c := range list {
+					users = append(users, c.Author)
+				}
+			}
+			for _, u := range users {
+				if err := putNew(tx.Bucket(bucketUsers), u.AccountID, u); err != nil {
+					return err
+				}
+			}
+			if err := putNew(tx.Bucket(bucketProjects), f.Project.Key, f.Project); err != nil {
+				return err
+			}
+			if err := putNew(tx.Bucket(bucketStatuses), f.Status.ID, f.Status); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Reconcile drops scope from the stored issues not among keys, deleting
+// those left in no scope, and returns how many were deleted.
+func (s *Store) Reconcile(scope string, keys map[string]bool) (int, error) {
+	return s.dropScopes(func(e *entry) bool { return slices.Contains(e.Scopes, scope) && !keys[e.Issue.Key] }, scope)
+}
+
+// Prune forgets the scopes not in scopes, deleting the issues left in
+// none, and returns how many were deleted.
+func (s *Store) Prune(scopes []string) (int, error) {
+	var gone []string
+	err := s.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketScopes).ForEach(func(k, _ []byte) error {
+			if !slices.Contains(scopes, string(k)) {
+				gone = append(gone, string(k))
+			}
+			return nil
+		})
+	})
+	if err != nil || len(gone) == 0 {
+		return 0, err
+	}
+
+	err = s.update(func(tx *bolt.Tx) error {
+		for _, jql := range gone {
+			if err := tx.Bucket(bucketScopes).Delete([]byte(jql)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return s.dropScopes(func(e *entry) bool {
+		return slices.ContainsFunc(e.Scopes, func(s string) bool { return slices.Contains(gone, s) })
+	}, gone...)
+}
+
+// dropScopes removes scopes from the issues matching f and deletes the
+// issues left in no scope.
+func (s *Store) dropScopes(f func(*entry) bool, scopes ...string) (int, error) {
+	deleted := 0
+	err := s.update(func(tx *bolt.Tx) error {
+		issues := tx.Bucket(bucketIssues)
+		var changed []entry
+		err := issues.ForEach(func(_, data []byte) error {
+			var e entry
+			if err := json.Unmarshal(data, &e); err != nil {
+				return fmt.Errorf("read stored issue: %w", err)
+			}
+			if f(&e) {
+				changed = append(changed, e)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Buckets must not change while iterated
+		for _, e := range changed {
+			e.Scopes = slices.DeleteFunc(e.Scopes, func(s string) bool { return slices.Contains(scopes, s) })
+			if len(e.Scopes) > 0 {
+				if err := put(issues, e.Issue.Key, e); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := issues.Delete([]byte(e.Issue.Key)); err != nil {
+				return err
+			}
+			if err := tx.Bucket(bucketComments).Delete([]byte(e.Issue.Key)); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// Scope returns the sync state of a JQL scope; zero if it was never
+// synced.
+func (s *Store) Scope(jql string) (Scope, error) {
+	scope := Scope{JQL: jql}
+	err := s.view(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(bucketScopes), jql, &scope)
+	})
+	if errors.Is(err, ErrNotStored) {
+		err = nil
+	}
+	return scope, err
+}
+
+// PutScope records the sync state of a scope.
+func (s *Store) PutScope(scope Scope) error {
+	return s.update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(bucketScopes), scope.JQL, scope)
+	})
+}
+
+// Myself returns the stored current user.
+func (s *Store) Myself() (*jira.CurrentUser, error) {
+	var user jira.CurrentUser
+	err := s.view(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(bucketMeta), string(metaMyself), &user)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("current user: %w", err)
+	}
+	return &user, nil
+}
+
+// Metadata is what PutMetadata stores besides issues.
+type Metadata struct {
+	Myself   *jira.CurrentUser
+	Projects []jira.Project
+	Statuses []jira.Status
+	Fields   []Field
+}
+
+// PutMetadata replaces the stored current user, projects, statuses and
+// fields, and records when they were fetched.
+func (s *Store) PutMetadata(m Metadata, fetched time.Time) error {
+	return s.update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(bucketMeta)
+		if err := put(meta, string(metaMyself), m.Myself); err != nil {
+			return err
+		}
+		user := jira.User{AccountID: m.Myself.AccountID, Name: m.Myself.Name, DisplayName: m.Myself.DisplayName, Email: m.Myself.Email, Active: m.Myself.Active}
+		if err := put(tx.Bucket(bucketUsers), user.AccountID, user); err != nil {
+			return err
+		}
+		for _, p := range m.Projects {
+			if err := put(tx.Bucket(bucketProjects), p.Key, p); err != nil {
+				return err
+			}
+		}
+		for _, st := range m.Statuses {
+			if err := put(tx.Bucket(bucketStatuses), st.ID, st); err != nil {
+				return err
+			}
+		}
+		for _, f := range m.Fields {
+			if err := put(tx.Bucket(bucketFields), f.ID, f); err != nil {
+				return err
+			}
+		}
+		return put(meta, string(metaMetadata), fetched)
+	})
+}
+
+// Users returns the stored users.
+func (s *Store) Users() ([]jira.User, error) {
+	return all[jira.User](s, bucketUsers)
+}
+
+// Projects returns the stored projects.
+func (s *Store) Projects() ([]jira.Project, error) {
+	return all[jira.Project](s, bucketProjects)
+}
+
+// Statuses returns the stored statuses.
+func (s *Store) Statuses() ([]jira.Status, error) {
+	return all[jira.Status](s, bucketStatuses)
+}
+
+// Fields returns the stored field metadata.
+func (s *Store) Fields() ([]Field, error) {
+	return all[Field](s, bucketFields)
+}
+
+// Info summarizes the store.
+func (s *Store) Info() (*Info, error) {
+	info := &Info{Path: s.path}
+	err := s.view(func(tx *bolt.Tx) error {
+		info.Size = tx.Size()
+		info.Domain = string(tx.Bucket(bucketMeta).Get(metaDomain))
+		info.Issues = tx.Bucket(bucketIssues).Stats().KeyN
+		err := tx.Bucket(bucketComments).ForEach(func(k, data []byte) error {
+			var comments []json.RawMessage
+			if err := json.Unmarshal(data, &comments); err != nil {
+				return fmt.Errorf("read comments of %s: %w", k, err)
+			}
+			info.Comments += len(comments)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		info.Users = tx.Bucket(bucketUsers).Stats().KeyN
+		info.Projects = tx.Bucket(bucketProjects).Stats().KeyN
+		info.Statuses = tx.Bucket(bucketStatuses).Stats().KeyN
+		info.Fields = tx.Bucket(bucketFields).Stats().KeyN
+		info.Queued = tx.Bucket(bucketOutbox).Stats().KeyN
+		if err := get(tx.Bucket(bucketMeta), string(metaMetadata), &info.Metadata); err != nil && !errors.Is(err, ErrNotStored) {
+			return err
+		}
+		return tx.Bucket(bucketScopes).ForEach(func(_, data []byte) error {
+			var scope Scope
+			if err := json.Unmarshal(data, &scope); err != nil {
+				return fmt.Errorf("read scope: %w", err)
+			}
+			info.Scopes = append(info.Scopes, scope)
+			return nil
+		})
+	})
+	return info, err
+}
+
+// get decodes the value of key in b into v; ErrNotStored if missing.
+func get(b *bolt.Bucket, key string, v any) error {
+	data := b.Get([]byte(key))
+	if data == nil {
+		return ErrNotStored
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("read %s: %w", key, err)
+	}
+	return nil
+}
+
+// put stores v as the value of key in b.
+func put(b *bolt.Bucket, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", key, err)
+	}
+	return b.Put([]byte(key), data)
+}
+
+// putNew stores v under key unless key is empty or already stored, as
+// issues refer to users, projects and statuses with fewer details than
+// Jira lists them.
+func putNew(b *bolt.Bucket, key string, v any) error {
+	if key == "" || b.Get([]byte(key)) != nil {
+		return nil
+	}
+	return put(b, key, v)
+}
+
+// all returns the values of a bucket.
+func all[T any](s *Store, bucket []byte) ([]T, error) {
+	var values []T
+	err := s.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(k, data []byte) error {
+			var v T
+			if err := json.Unmarshal(data, &v); err != nil {
+				return fmt.Errorf("read %s %s: %w", bucket, k, err)
+			}
+			values = append(values, v)
+			return nil
+		})
+	})
+	return values, err
+}
@@ -0,0 +1,287 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"jirar/internal/jira"
+)
+
+// pageSize is the number of issues requested per search page.
+const pageSize = 100
+
+// SyncFields are the issue fields kept: all navigable ones, so local JQL
+// sees custom fields, and the comments.
+var SyncFields = []string{"*navigable", "comment"}
+
+// syncExpand is expanded in synced issues: the transitions, so issues can
+// be moved offline.
+var syncExpand = []string{"transitions"}
+
+// orderByPattern matches the ORDER BY of a scope, which syncs drop.
+var orderByPattern = regexp.MustCompile(`(?is)\s*\border\s+by\b.*$`)
+
+// SyncOptions configure Sync.
+type SyncOptions struct {
+	// Domain is the Jira site synced; a store of another site starts over
+	Domain string
+	// Scopes are the JQL queries whose issues are kept
+	Scopes []string
+	// Reconcile is how often a scope is listed in full to drop the issues
+	// deleted or moved out of it, and the metadata is refetched
+	Reconcile time.Duration
+	// Full reconciles every scope now
+	Full bool
+	// Prune forgets the issues of scopes not in Scopes
+	Prune bool
+	// Push replays the outbox first; Force pushes conflicting changes
+	Push   bool
+	Force  bool
+	Logger *logrus.Logger
+}
+
+// ScopeResult reports the sync of a scope.
+type ScopeResult struct {
+	JQL string `json:"jql"`
+	// Initial is the first sync of the scope, which fetches all its issues
+	Initial bool `json:"initial,omitempty"`
+	// Reconciled is set when the scope was listed in full
+	Reconciled bool `json:"reconciled,omitempty"`
+	Fetched    int  `json:"fetched"`
+	Removed    int  `json:"removed"`
+}
+
+// SyncResult reports a sync.
+type SyncResult struct {
+	// Push reports the changes pushed, with Push
+	Push   *PushResult   `json:"push,omitempty"`
+	Scopes []ScopeResult `json:"scopes"`
+	// Metadata is set when users, projects, statuses and fields were
+	// refetched
+	Metadata bool `json:"metadata,omitempty"`
+	// Pruned counts the issues of dropped scopes that were deleted
+	Pruned int `json:"pruned,omitempty"`
+	// Queued counts the changes left in the outbox
+	Queued int `json:"queued"`
+}
+
+// Sync brings the store up to date with Jira, pushing the outbox first
+// with Push. A scope synced before only
+// fetches the issues updated since, with a minute of overlap; every
+// Reconcile period it lists the keys of all its issues to drop those
+// deleted or moved out of it.
+func Sync(ctx context.Context, client jira.Client, st *Store, opts SyncOptions) (*SyncResult, error) {
+	domain, err := st.Domain()
+	if err != nil {
+		return nil, err
+	}
+	if domain != opts.Domain {
+		if domain != "" {
+			opts.Logger.WithFields(logrus.Fields{"was": domain, "now": opts.Domain}).Info("Jira site changed; starting the local store over")
+		}
+		if err := st.Reset(opts.Domain); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &SyncResult{}
+	if opts.Push {
+		if result.Push, err = Push(ctx, client, st, opts.Force, opts.Logger); err != nil {
+			return nil, err
+		}
+	}
+	info, err := st.Info()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Full || time.Since(info.Metadata) > opts.Reconcile {
+		if err := syncMetadata(ctx, client, st); err != nil {
+			return nil, err
+		}
+		result.Metadata = true
+	}
+	if opts.Prune {
+		if result.Pruned, err = st.Prune(opts.Scopes); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, jql := range opts.Scopes {
+		r, err := syncScope(ctx, client, st, jql, opts)
+		if err != nil {
+			return nil, fmt.Errorf("sync %q: %w", jql, err)
+		}
+		opts.Logger.WithFields(logrus.Fields{"scope": jql, "fetched": r.Fetched, "removed": r.Removed}).Debug("Synced scope")
+		result.Scopes = append(result.Scopes, r)
+	}
+	if info, err = st.Info(); err != nil {
+		return nil, err
+	}
+	result.Queued = info.Queued
+	return result, nil
+}
+
+// syncScope brings the issues of a scope up to date.
+func syncScope(ctx context.Context, client jira.Client, st *Store, jql string, opts SyncOptions) (ScopeResult, error) {
+	result := ScopeResult{JQL: jql}
+	scope, err := st.Scope(jql)
+	if err != nil {
+		return result, err
+	}
+	filter := strings.TrimSpace(orderByPattern.ReplaceAllString(jql, ""))
+	start := time.Now()
+
+	query := fmt.Sprintf("(%s) ORDER BY updated ASC", filter)
+	switch {
+	case scope.Synced.IsZero():
+		result.Initial, result.Reconciled = true, true
+	case opts.Full || start.Sub(scope.Reconciled) > opts.Reconcile:
+		keys, err := listKeys(ctx, client, filter)
+		if err != nil {
+			return result, err
+		}
+		if result.Removed, err = st.Reconcile(jql, keys); err != nil {
+			return result, err
+		}
+		result.Reconciled = true
+		fallthrough
+	default:
+		// Relative dates avoid the timezone of the Jira account
+		minutes := int(math.Ceil(start.Sub(scope.Synced).Minutes())) + 1
+		query = fmt.Sprintf(`(%s) AND updated >= "-%dm" ORDER BY updated ASC`, filter, minutes)
+	}
+
+	issues, comments, err := fetchIssues(ctx, client, query)
+	if err != nil {
+		return result, err
+	}
+	if err := st.PutIssues(jql, issues, comments); err != nil {
+		return result, err
+	}
+	result.Fetched = len(issues)
+	if result.Initial {
+		keys := make(map[string]bool, len(issues))
+		for _, issue := range issues {
+			keys[issue.Key] = true
+		}
+		if result.Removed, err = st.Reconcile(jql, keys); err != nil {
+			return result, err
+		}
+	}
+
+	scope.Synced = start
+	if result.Reconciled {
+		scope.Reconciled = start
+	}
+	return result, st.PutScope(scope)
+}
+
+// fetchIssues runs jql through all result pages, returning the issues
+// and their comments by key. Comments come with the issues, and are
+// fetched separately only for issues with more than a search returns.
+func fetchIssues(ctx context.Context, client jira.Client, jql string) ([]jira.Issue, map[string][]jira.Comment, error) {
+	var issues []jira.Issue
+	for {
+		res, err := client.SearchIssues(ctx, jql, jira.WithLimit(pageSize), jira.WithStartAt(len(issues)), jira.WithFields(SyncFields...), jira.WithExpand(syncExpand...))
+		if err != nil {
+			return nil, nil, err
+		}
+		issues = append(issues, res.Issues...)
+		if len(res.Issues) == 0 || len(issues) >= res.Total {
+			break
+		}
+	}
+
+	comments := map[string][]jira.Comment{}
+	for i := range issues {
+		raw, ok := issues[i].Fields.Custom["comment"]
+		if !ok {
+			continue
+		}
+		delete(issues[i].Fields.Custom, "comment")
+		var page jira.CommentPage
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return nil, nil, fmt.Errorf("parse comments of %s: %w", issues[i].Key, err)
+		}
+		if len(page.Comments) < page.Total {
+			all, err := client.GetComments(ctx, issues[i].Key)
+			if err != nil {
+				return nil, nil, err
+			}
+			page.Comments = all
+		}
+		comments[issues[i].Key] = page.Comments
+	}
+	return issues, comments, nil
+}
+
+// listKeys returns the keys of all issues matching filter.
+func listKeys(ctx context.Context, client jira.Client, filter string) (map[string]bool, error) {
+	keys := map[string]bool{}
+	for {
+		res, err := client.SearchIssues(ctx, filter, jira.WithLimit(pageSize), jira.WithStartAt(len(keys)), jira.WithFields("updated"))
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range res.Issues {
+			keys[issue.Key] = true
+		}
+		if len(res.Issues) == 0 || len(keys) >= res.Total {
+			return keys, nil
+		}
+	}
+}
+
+// syncMetadata refetches the current user, projects, statuses and fields.
+func syncMetadata(ctx context.Context, client jira.Client, st *Store) error {
+	var (
+		m   Metadata
+		err error
+	)
+	if m.Myself, err = client.GetCurrentUser(ctx); err != nil {
+		return err
+	}
+	if m.Projects, err = client.ListProjects(ctx); err != nil {
+		return err
+	}
+	if err := getJSON(ctx, client, "/rest/api/3/status", &m.Statuses); err != nil {
+		return err
+	}
+	if err := getJSON(ctx, client, "/rest/api/3/field", &m.Fields); err != nil {
+		return err
+	}
+	return st.PutMetadata(m, time.Now())
+}
+
+// getJSON fetches a Jira resource the client has no method for.
+func getJSON(ctx context.Context, client jira.Client, path string, out any) error {
+	resp, err := client.Do(ctx, &jira.RawRequest{Method: http.MethodGet, Path: path})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s failed with status %d", path, resp.StatusCode)
+	}
+	if err := json.Unmarshal(resp.Body, out); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// Refresh refetches stored issues by key after they were changed, so the
+// store shows the change before the next sync.
+func Refresh(ctx context.Context, client jira.Client, st *Store, keys ...string) error {
+	issues, comments, err := fetchIssues(ctx, client, fmt.Sprintf("key in (%s)", strings.Join(keys, ", ")))
+	if err != nil {
+		return err
+	}
+	return st.PutIssues("", issues, comments)
+}
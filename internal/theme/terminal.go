@@ -0,0 +1,84 @@
+package theme
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"jirar/internal/jira"
+	"jirar/internal/output"
+)
+
+// Time formats accepted by --time-format besides Go layouts.
+const (
+	TimeRelative = "relative"
+	TimeAbsolute = "absolute"
+	TimeISO      = "iso"
+)
+
+// timeLayouts are the layouts of the named time formats.
+var timeLayouts = map[string]string{
+	TimeAbsolute: "2006-01-02 15:04",
+	TimeISO:      time.RFC3339,
+	"date":       "2006-01-02",
+}
+
+// Link renders text as an OSC 8 hyperlink to url when hyperlinks are on.
+func (t *Theme) Link(url, text string) string {
+	if !t.hyperlinks || url == "" {
+		return text
+	}
+	return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// Time renders a timestamp in the theme's format: relative to now, or in
+// the theme's timezone with a named format or Go layout.
+func (t *Theme) Time(ts jira.Time) string {
+	if ts.IsZero() {
+		return ""
+	}
+
+	switch t.timeFormat {
+	case "", TimeRelative:
+		return output.TimeAgo(ts.Time, time.Now())
+	}
+
+	layout, ok := timeLayouts[t.timeFormat]
+	if !ok {
+		layout = t.timeFormat
+	}
+	loc := t.location
+	if loc == nil {
+		loc = time.Local
+	}
+	return ts.In(loc).Format(layout)
+}
+
+// SupportsHyperlinks guesses from the environment whether the terminal
+// renders OSC 8 hyperlinks. FORCE_HYPERLINK=1 or 0 overrides the guess.
+func SupportsHyperlinks(getenv func(string) string) bool {
+	if force := getenv("FORCE_HYPERLINK"); force != "" {
+		return force != "0"
+	}
+
+	switch getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper", "Tabby":
+		return true
+	}
+	for _, env := range []string{"WT_SESSION", "KITTY_WINDOW_ID", "KONSOLE_VERSION", "DOMTERM"} {
+		if getenv(env) != "" {
+			return true
+		}
+	}
+	if v, err := strconv.Atoi(getenv("VTE_VERSION")); err == nil && v >= 5000 {
+		return true
+	}
+
+	term := getenv("TERM")
+	for _, name := range []string{"kitty", "alacritty", "foot", "ghostty", "wezterm"} {
+		if strings.Contains(term, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+// Package theme styles statuses and priorities for terminal output.
+package theme
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"jirar/internal/config"
+	"jirar/internal/jira"
+)
+
+// Status category keys as returned by Jira.
+const (
+	CategoryNew           = "new"
+	CategoryIndeterminate = "indeterminate"
+	CategoryDone          = "done"
+)
+
+// Style is the icon and color of a status or priority. Colors are names
+// such as "red" or "bold green", or 256-color numbers.
+type Style struct {
+	Icon  string
+	Color string
+}
+
+// overlay replaces the fields of s that o sets.
+func (s Style) overlay(o Style) Style {
+	if o.Icon != "" {
+		s.Icon = o.Icon
+	}
+	if o.Color != "" {
+		s.Color = o.Color
+	}
+	return s
+}
+
+// The default theme follows the status indicators in docs/COMMANDS.md.
+var (
+	defaultCategories = map[string]Style{
+		CategoryNew:           {Icon: "📋", Color: "blue"},
+		CategoryIndeterminate: {Icon: "🔥", Color: "yellow"},
+		CategoryDone:          {Icon: "✅", Color: "green"},
+	}
+	defaultStatuses = map[string]Style{
+		"blocked":     {Icon: "⏸️", Color: "red"},
+		"on hold":     {Icon: "⏸️", Color: "red"},
+		"review":      {Icon: "👀", Color: "magenta"},
+		"in review":   {Icon: "👀", Color: "magenta"},
+		"code review": {Icon: "👀", Color: "magenta"},
+		"resolved":    {Icon: "✅", Color: "green"},
+	}
+	defaultPriorities = map[string]Style{
+		"highest": {Color: "bold red"},
+		"blocker": {Color: "bold red"},
+		"high":    {Color: "red"},
+		"medium":  {Color: "yellow"},
+		"low":     {Color: "cyan"},
+		"lowest":  {Color: "gray"},
+	}
+)
+
+// colorCodes maps color names to SGR parameters.
+var colorCodes = map[string]string{
+	"bold":      "1",
+	"dim":       "2",
+	"italic":    "3",
+	"underline": "4",
+	"black":     "30",
+	"red":       "31",
+	"green":     "32",
+	"yellow":    "33",
+	"blue":      "34",
+	"magenta":   "35",
+	"cyan":      "36",
+	"white":     "37",
+	"gray":      "90",
+	"grey":      "90",
+}
+
+// Options are the terminal capabilities and time settings of a theme.
+type Options struct {
+	// Colors enables ANSI colors
+	Colors bool
+	// Hyperlinks enables OSC 8 hyperlinks
+	Hyperlinks bool
+	// TimeFormat is "relative", "absolute", "iso" or a Go time layout
+	TimeFormat string
+	// Location is the timezone absolute times are shown in
+	Location *time.Location
+}
+
+// Theme renders statuses and priorities with icons and colors, links and
+// timestamps.
+type Theme struct {
+	colors     bool
+	icons      bool
+	compact    bool
+	hyperlinks bool
+	timeFormat string
+	location   *time.Location
+
+	categories map[string]Style
+	statuses   map[string]Style
+	priorities map[string]Style
+}
+
+// New builds the theme from the UI configuration on top of the defaults.
+// Callers decide the capabilities in opts from ui.colors, NO_COLOR,
+// --no-color and whether output is a terminal.
+func New(cfg config.UIConfig, opts Options) *Theme {
+	return &Theme{
+		colors:     opts.Colors,
+		icons:      cfg.Icons,
+		compact:    cfg.Compact,
+		hyperlinks: opts.Hyperlinks,
+		timeFormat: opts.TimeFormat,
+		location:   opts.Location,
+		categories: merge(defaultCategories, cfg.Theme.Categories),
+		statuses:   merge(defaultStatuses, cfg.Theme.Statuses),
+		priorities: merge(defaultPriorities, cfg.Theme.Priorities),
+	}
+}
+
+// Plain returns a theme without colors, icons or links that prints times
+// in the given format.
+func Plain(opts Options) *Theme {
+	return &Theme{timeFormat: opts.TimeFormat, location: opts.Location}
+}
+
+// merge overlays configured styles, keyed case-insensitively, on defaults.
+func merge(defaults map[string]Style, overrides map[string]config.StyleConfig) map[string]Style {
+	styles := make(map[string]Style, len(defaults)+len(overrides))
+	for name, s := range defaults {
+		styles[name] = s
+	}
+	for name, o := range overrides {
+		name = strings.ToLower(name)
+		styles[name] = styles[name].overlay(Style{Icon: o.Icon, Color: o.Color})
+	}
+	return styles
+}
+
+// Colors reports whether the theme emits ANSI colors.
+func (t *Theme) Colors() bool {
+	return t.colors
+}
+
+// Icons reports whether the theme shows icons.
+func (t *Theme) Icons() bool {
+	return t.icons
+}
+
+// Hyperlinks reports whether the theme emits OSC 8 hyperlinks.
+func (t *Theme) Hyperlinks() bool {
+	return t.hyperlinks
+}
+
+// Compact reports whether tables are drawn without borders.
+func (t *Theme) Compact() bool {
+	return t.compact
+}
+
+// StatusStyle resolves the style of a status: the style of its category,
+// overridden by a style for its name.
+func (t *Theme) StatusStyle(s jira.Status) Style {
+	style := t.categories[strings.ToLower(s.StatusCategory.Key)]
+	if named, ok := t.statuses[strings.ToLower(s.Name)]; ok {
+		style = style.overlay(named)
+	}
+	return style
+}
+
+// Status renders a status name with its icon and color.
+func (t *Theme) Status(s jira.Status) string {
+	return t.render(t.StatusStyle(s), s.Name)
+}
+
+// Priority renders a priority name with its icon and color.
+func (t *Theme) Priority(p jira.Priority) string {
+	return t.render(t.priorities[strings.ToLower(p.Name)], p.Name)
+}
+
+// render applies a style to text.
+func (t *Theme) render(style Style, text string) string {
+	if text == "" {
+		return ""
+	}
+	text = t.Paint(style.Color, text)
+	if t.icons && style.Icon != "" {
+		text = style.Icon + " " + text
+	}
+	return text
+}
+
+// Paint wraps text in the ANSI sequence for color when colors are on.
+func (t *Theme) Paint(color, text string) string {
+	if !t.colors || color == "" || text == "" {
+		return text
+	}
+
+	var codes []string
+	for _, name := range strings.Fields(strings.ToLower(color)) {
+		if code, ok := colorCodes[name]; ok {
+			codes = append(codes, code)
+		} else if n, err := strconv.Atoi(name); err == nil && n >= 0 && n < 256 {
+			codes = append(codes, "38;5;"+name)
+		}
+	}
+	if len(codes) == 0 {
+		return text
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m" + text + "\x1b[0m"
+}
@@ -0,0 +1,394 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"jirar/internal/board"
+	"jirar/internal/browser"
+	"jirar/internal/clipboard"
+	"jirar/internal/jira"
+	"jirar/internal/output"
+)
+
+// BoardOptions configure the interactive board.
+type BoardOptions struct {
+	Client jira.Client
+	// Domain is the Jira base URL used for browser links
+	Domain string
+	Colors bool
+	// Load fetches the board; it is called again after moves and on refresh
+	Load func(ctx context.Context) (*board.Board, error)
+}
+
+// RunBoard shows a board and lets the user move cards between columns,
+// which transitions their issues. It blocks until the user quits or ctx
+// is cancelled.
+func RunBoard(ctx context.Context, opts BoardOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p := tea.NewProgram(newBoardModel(ctx, opts), tea.WithAltScreen(), tea.WithContext(ctx))
+	_, err := p.Run()
+	if errors.Is(err, tea.ErrProgramKilled) && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// boardKeyMap holds the bindings of the board.
+type boardKeyMap struct {
+	Left      key.Binding
+	Right     key.Binding
+	Up        key.Binding
+	Down      key.Binding
+	MoveLeft  key.Binding
+	MoveRight key.Binding
+	Open      key.Binding
+	Copy      key.Binding
+	Refresh   key.Binding
+	Quit      key.Binding
+}
+
+// defaultBoardKeyMap returns the default board bindings.
+func defaultBoardKeyMap() boardKeyMap {
+	return boardKeyMap{
+		Left:      key.NewBinding(key.WithKeys("left", "h"), key.WithHelp("←/h", "column")),
+		Right:     key.NewBinding(key.WithKeys("right", "l"), key.WithHelp("→/l", "column")),
+		Up:        key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "card")),
+		Down:      key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "card")),
+		MoveLeft:  key.NewBinding(key.WithKeys("H", "<", "shift+left"), key.WithHelp("H/<", "move left")),
+		MoveRight: key.NewBinding(key.WithKeys("L", ">", "shift+right"), key.WithHelp("L/>", "move right")),
+		Open:      key.NewBinding(key.WithKeys("o", "enter"), key.WithHelp("o", "open")),
+		Copy:      key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "copy key")),
+		Refresh:   key.NewBinding(key.WithKeys("r", "ctrl+r"), key.WithHelp("r", "refresh")),
+		Quit:      key.NewBinding(key.WithKeys("q", "esc", "ctrl+c"), key.WithHelp("q", "quit")),
+	}
+}
+
+// help renders the key hints.
+func (k boardKeyMap) help() string {
+	var parts []string
+	for _, b := range []key.Binding{k.Left, k.Down, k.MoveLeft, k.MoveRight, k.Open, k.Copy, k.Refresh, k.Quit} {
+		parts = append(parts, b.Help().Key+" "+b.Help().Desc)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// Messages of the board.
+type (
+	boardMsg struct {
+		board *board.Board
+		err   error
+	}
+	movedMsg struct {
+		text string
+		err  error
+	}
+)
+
+// boardModel is the Bubble Tea model of the board.
+type boardModel struct {
+	ctx  context.Context
+	opts BoardOptions
+	keys boardKeyMap
+
+	board   *board.Board
+	viewer  viewport.Model
+	spinner spinner.Model
+	width   int
+	height  int
+	busy    int
+
+	// column and selected locate the cursor; selected is "" on an empty
+	// column
+	column   int
+	selected string
+
+	status    string
+	statusErr bool
+	statusSeq int
+}
+
+// newBoardModel creates the model; the board loads on Init.
+func newBoardModel(ctx context.Context, opts BoardOptions) *boardModel {
+	return &boardModel{
+		ctx:     ctx,
+		opts:    opts,
+		keys:    defaultBoardKeyMap(),
+		viewer:  viewport.New(0, 0),
+		spinner: spinner.New(spinner.WithSpinner(spinner.Dot)),
+	}
+}
+
+// Init implements tea.Model.
+func (m *boardModel) Init() tea.Cmd {
+	return m.load()
+}
+
+// Update implements tea.Model.
+func (m *boardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.viewer.Width, m.viewer.Height = msg.Width, max(msg.Height-3, 1)
+		m.redraw()
+		return m, nil
+
+	case tea.KeyMsg:
+		return m, m.handleKey(msg)
+
+	case spinner.TickMsg:
+		if m.busy == 0 {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case boardMsg:
+		m.busy--
+		if msg.err != nil {
+			return m, m.notify(msg.err.Error(), true)
+		}
+		first := m.board == nil
+		m.board = msg.board
+		if c, _, ok := m.board.Find(m.selected); ok {
+			m.column = c
+		} else if first {
+			// Start on the first column with cards
+			for c, col := range m.board.Columns {
+				if len(col.Cards) > 0 {
+					m.column = c
+					break
+				}
+			}
+			m.selectIndex(0)
+		} else {
+			m.column = min(m.column, max(len(m.board.Columns)-1, 0))
+			m.selectIndex(0)
+		}
+		m.redraw()
+		return m, nil
+
+	case movedMsg:
+		m.busy--
+		if msg.err != nil {
+			return m, m.notify(msg.err.Error(), true)
+		}
+		return m, tea.Batch(m.notify(msg.text, false), m.load())
+
+	case clearStatusMsg:
+		if msg.seq == m.statusSeq {
+			m.status = ""
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.viewer, cmd = m.viewer.Update(msg)
+	return m, cmd
+}
+
+// handleKey moves the cursor or acts on the selected card.
+func (m *boardModel) handleKey(msg tea.KeyMsg) tea.Cmd {
+	if key.Matches(msg, m.keys.Quit) {
+		return tea.Quit
+	}
+	if key.Matches(msg, m.keys.Refresh) {
+		return m.load()
+	}
+	if m.board == nil || len(m.board.Columns) == 0 {
+		return nil
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Left):
+		m.moveCursor(-1, 0)
+	case key.Matches(msg, m.keys.Right):
+		m.moveCursor(1, 0)
+	case key.Matches(msg, m.keys.Up):
+		m.moveCursor(0, -1)
+	case key.Matches(msg, m.keys.Down):
+		m.moveCursor(0, 1)
+	case key.Matches(msg, m.keys.MoveLeft):
+		return m.moveCard(m.column - 1)
+	case key.Matches(msg, m.keys.MoveRight):
+		return m.moveCard(m.column + 1)
+	case key.Matches(msg, m.keys.Open):
+		if m.selected == "" {
+			return nil
+		}
+		if err := browser.Open(output.BrowseURL(m.opts.Domain, m.selected)); err != nil {
+			return m.notify(err.Error(), true)
+		}
+		return m.notify("Opened "+m.selected, false)
+	case key.Matches(msg, m.keys.Copy):
+		if m.selected == "" {
+			return nil
+		}
+		if err := clipboard.Copy(os.Stdout, m.selected); err != nil {
+			return m.notify(err.Error(), true)
+		}
+		return m.notify("Copied "+m.selected, false)
+	default:
+		var cmd tea.Cmd
+		m.viewer, cmd = m.viewer.Update(msg)
+		return cmd
+	}
+	m.redraw()
+	return nil
+}
+
+// index returns the position of the selected card in its column.
+func (m *boardModel) index() int {
+	for i, card := range m.board.Ordered(m.column) {
+		if card.Key == m.selected {
+			return i
+		}
+	}
+	return 0
+}
+
+// selectIndex selects the card at index i of the current column, clamped
+// to the cards it has.
+func (m *boardModel) selectIndex(i int) {
+	m.selected = ""
+	if m.board == nil || len(m.board.Columns) == 0 {
+		return
+	}
+	cards := m.board.Ordered(m.column)
+	if len(cards) > 0 {
+		m.selected = cards[max(min(i, len(cards)-1), 0)].Key
+	}
+}
+
+// moveCursor moves the cursor by columns and cards.
+func (m *boardModel) moveCursor(dcol, dcard int) {
+	i := m.index()
+	if dcol != 0 {
+		m.column = max(min(m.column+dcol, len(m.board.Columns)-1), 0)
+	}
+	m.selectIndex(i + dcard)
+}
+
+// moveCard transitions the selected issue into the column at index to.
+func (m *boardModel) moveCard(to int) tea.Cmd {
+	if m.selected == "" || to < 0 || to >= len(m.board.Columns) {
+		return nil
+	}
+	ctx, client, b, key := m.ctx, m.opts.Client, m.board, m.selected
+	column := b.Columns[to].Name
+
+	move := func() tea.Msg {
+		transitions, err := client.GetTransitions(ctx, key)
+		if err != nil {
+			return movedMsg{err: err}
+		}
+		t, err := b.Transition(transitions, to)
+		if err != nil {
+			return movedMsg{err: fmt.Errorf("cannot move %s: %w", key, err)}
+		}
+		if err := client.TransitionIssue(ctx, key, t.ID); err != nil {
+			return movedMsg{err: err}
+		}
+		return movedMsg{text: fmt.Sprintf("Moved %s to %s (%s)", key, column, t.Name)}
+	}
+	m.status, m.statusErr = fmt.Sprintf("Moving %s to %s…", key, column), false
+	return tea.Batch(m.startBusy(), move)
+}
+
+// load fetches the board.
+func (m *boardModel) load() tea.Cmd {
+	ctx, load := m.ctx, m.opts.Load
+	fetch := func() tea.Msg {
+		b, err := load(ctx)
+		return boardMsg{board: b, err: err}
+	}
+	return tea.Batch(m.startBusy(), fetch)
+}
+
+// startBusy counts a request and starts the spinner if it was idle.
+func (m *boardModel) startBusy() tea.Cmd {
+	m.busy++
+	if m.busy == 1 {
+		return m.spinner.Tick
+	}
+	return nil
+}
+
+// notify shows a status message for a while.
+func (m *boardModel) notify(text string, isErr bool) tea.Cmd {
+	m.statusSeq++
+	m.status, m.statusErr = text, isErr
+	seq := m.statusSeq
+	return tea.Tick(statusLifetime, func(time.Time) tea.Msg { return clearStatusMsg{seq: seq} })
+}
+
+// redraw renders the board into the viewer and scrolls the selected card
+// into view.
+func (m *boardModel) redraw() {
+	if m.board == nil || m.width == 0 {
+		return
+	}
+	drawing, line := board.Render(m.board, board.Options{
+		Width:    m.width,
+		Colors:   m.opts.Colors,
+		Cursor:   true,
+		Column:   m.column,
+		Selected: m.selected,
+	})
+	m.viewer.SetContent(drawing)
+
+	switch {
+	case line < 0:
+	case line < m.viewer.YOffset+2:
+		// Keep the column headers or the swimlane title in view
+		m.viewer.SetYOffset(max(line-2, 0))
+	case line+board.CardHeight > m.viewer.YOffset+m.viewer.Height:
+		m.viewer.SetYOffset(line + board.CardHeight - m.viewer.Height)
+	}
+}
+
+// View implements tea.Model.
+func (m *boardModel) View() string {
+	if m.width == 0 {
+		return ""
+	}
+
+	title := "Loading board…"
+	if m.board != nil {
+		title = m.board.Name
+	}
+	header := lipgloss.NewStyle().Bold(true).Render(title)
+
+	var status strings.Builder
+	if m.busy > 0 {
+		status.WriteString(m.spinner.View() + " ")
+	}
+	switch {
+	case m.status != "" && m.statusErr:
+		status.WriteString(errorStyle.Render(m.status))
+	case m.status != "":
+		status.WriteString(m.status)
+	default:
+		status.WriteString(hintStyle.Render(m.keys.help()))
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		header,
+		m.viewer.View(),
+		"",
+		lipgloss.NewStyle().MaxWidth(m.width).Render(status.String()),
+	)
+}
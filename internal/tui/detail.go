@@ -0,0 +1,110 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+
+	"jirar/internal/jira"
+	"jirar/internal/theme"
+)
+
+// detail is a loaded issue with its comments.
+type detail struct {
+	issue    *jira.Issue
+	comments []jira.Comment
+}
+
+// markdown renders the issue and its comments as a Markdown document.
+func (d *detail) markdown(th *theme.Theme) string {
+	f := d.issue.Fields
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s %s\n\n", d.issue.Key, f.Summary)
+
+	status := f.Status.Name
+	if icon := th.StatusStyle(f.Status).Icon; th.Icons() && icon != "" {
+		status = icon + " " + status
+	}
+	meta := []string{field("Status", status), field("Priority", f.Priority.Name), field("Type", f.IssueType.Name)}
+	fmt.Fprintf(&b, "%s\n\n", join(meta))
+
+	assignee := f.Assignee.DisplayName
+	if assignee == "" {
+		assignee = "Unassigned"
+	}
+	fmt.Fprintf(&b, "%s\n\n", join([]string{field("Assignee", assignee), field("Reporter", f.Reporter.DisplayName)}))
+	fmt.Fprintf(&b, "%s\n\n", join([]string{field("Created", timeText(th, f.Created)), field("Updated", timeText(th, f.Updated))}))
+
+	b.WriteString("---\n\n")
+	if f.Description != nil && len(f.Description.Content) > 0 {
+		b.WriteString(f.Description.Markdown())
+	} else {
+		b.WriteString("_No description_\n")
+	}
+
+	if len(d.comments) > 0 {
+		fmt.Fprintf(&b, "\n## Comments (%d)\n\n", len(d.comments))
+		for _, c := range d.comments {
+			fmt.Fprintf(&b, "**%s** · %s\n\n%s\n", c.Author.DisplayName, timeText(th, c.Created), c.Body.Markdown())
+		}
+	}
+	return b.String()
+}
+
+// field formats a labelled value, or returns "" when it is empty.
+func field(label, value string) string {
+	if value == "" {
+		return ""
+	}
+	return "**" + label + ":** " + value
+}
+
+// join joins the non-empty parts of a metadata line.
+func join(parts []string) string {
+	var kept []string
+	for _, p := range parts {
+		if p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, " · ")
+}
+
+// timeText formats t with the theme, or returns "" when it is zero.
+func timeText(th *theme.Theme, t jira.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return th.Time(t)
+}
+
+// renderer renders Markdown for the detail pane at a given width.
+type renderer struct {
+	style string
+	width int
+	term  *glamour.TermRenderer
+}
+
+// render renders doc wrapped to width, falling back to the Markdown source
+// when the renderer fails.
+func (r *renderer) render(doc string, width int) string {
+	if r.term == nil || r.width != width {
+		term, err := glamour.NewTermRenderer(
+			glamour.WithStandardStyle(r.style),
+			glamour.WithWordWrap(width),
+			glamour.WithPreservedNewLines(),
+		)
+		if err != nil {
+			return doc
+		}
+		r.term, r.width = term, width
+	}
+
+	out, err := r.term.Render(doc)
+	if err != nil {
+		return doc
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+package tui
+
+import (
+	"strings"
+
+	"jirar/internal/jira"
+	"jirar/internal/theme"
+)
+
+// issueItem is an issue in the list pane.
+type issueItem struct {
+	issue jira.Issue
+	desc  string
+}
+
+// newIssueItem builds a list item, styling the status with th.
+func newIssueItem(issue jira.Issue, th *theme.Theme) issueItem {
+	f := issue.Fields
+	parts := []string{th.Status(f.Status)}
+	if f.Assignee.DisplayName != "" {
+		parts = append(parts, f.Assignee.DisplayName)
+	} else {
+		parts = append(parts, "Unassigned")
+	}
+	if !f.Updated.IsZero() {
+		parts = append(parts, th.Time(f.Updated))
+	}
+	return issueItem{issue: issue, desc: strings.Join(parts, " · ")}
+}
+
+// FilterValue implements list.Item; filtering matches key and summary.
+func (i issueItem) FilterValue() string {
+	return i.issue.Key + " " + i.issue.Fields.Summary
+}
+
+// Title implements list.DefaultItem.
+func (i issueItem) Title() string {
+	return i.issue.Key + " " + i.issue.Fields.Summary
+}
+
+// Description implements list.DefaultItem.
+func (i issueItem) Description() string {
+	return i.desc
+}
+
+// choice is an option of a chooser, such as a transition or a user.
+type choice struct {
+	id    string
+	title string
+	desc  string
+}
+
+// FilterValue implements list.Item.
+func (c choice) FilterValue() string {
+	return c.title + " " + c.desc
+}
+
+// Title implements list.DefaultItem.
+func (c choice) Title() string {
+	return c.title
+}
+
+// Description implements list.DefaultItem.
+func (c choice) Description() string {
+	return c.desc
+}
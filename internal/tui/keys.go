@@ -0,0 +1,41 @@
+package tui
+
+import "github.com/charmbracelet/bubbles/key"
+
+// keyMap holds the issue actions of the browser. List navigation and
+// filtering use the bindings of the list component.
+type keyMap struct {
+	Open       key.Binding
+	Transition key.Binding
+	Assign     key.Binding
+	Comment    key.Binding
+	Copy       key.Binding
+	Refresh    key.Binding
+	Focus      key.Binding
+	Back       key.Binding
+	Quit       key.Binding
+	Submit     key.Binding
+	Choose     key.Binding
+}
+
+// defaultKeyMap returns the default bindings.
+func defaultKeyMap() keyMap {
+	return keyMap{
+		Open:       key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "open")),
+		Transition: key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "transition")),
+		Assign:     key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "assign")),
+		Comment:    key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "comment")),
+		Copy:       key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "copy key")),
+		Refresh:    key.NewBinding(key.WithKeys("r", "ctrl+r"), key.WithHelp("r", "refresh")),
+		Focus:      key.NewBinding(key.WithKeys("tab", "enter"), key.WithHelp("tab", "details")),
+		Back:       key.NewBinding(key.WithKeys("esc", "tab", "shift+tab"), key.WithHelp("esc", "back")),
+		Quit:       key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+		Submit:     key.NewBinding(key.WithKeys("ctrl+s"), key.WithHelp("ctrl+s", "send")),
+		Choose:     key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "choose")),
+	}
+}
+
+// actions are the bindings shown in the list help.
+func (k keyMap) actions() []key.Binding {
+	return []key.Binding{k.Focus, k.Open, k.Transition, k.Assign, k.Comment, k.Copy, k.Refresh}
+}
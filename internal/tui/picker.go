@@ -0,0 +1,429 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	"github.com/sahilm/fuzzy"
+
+	"jirar/internal/jira"
+	"jirar/internal/theme"
+)
+
+// ErrNoPick is returned by Pick when the user cancels.
+var ErrNoPick = errors.New("no issue picked")
+
+// typedKeyPattern matches an issue key typed into the picker.
+var typedKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]+-[0-9]+$`)
+
+// PickOptions configure the issue picker.
+type PickOptions struct {
+	// Issues are offered right away, e.g. from a cache
+	Issues []jira.Issue
+	// Refresh, when set, fetches fresher issues in the background; they
+	// replace Issues when they arrive
+	Refresh func(ctx context.Context) ([]jira.Issue, error)
+	// Theme styles statuses and times in the preview
+	Theme *theme.Theme
+	// Prompt is shown in front of the query
+	Prompt string
+}
+
+// Pick lets the user choose an issue by fuzzy matching its key and
+// summary, with a preview of the highlighted issue. A key typed in full
+// is accepted even when it is not offered. The picker draws on stderr so
+// it also works inside command substitution.
+func Pick(ctx context.Context, opts PickOptions) (jira.Issue, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	m := newPickerModel(ctx, opts)
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx), tea.WithOutput(os.Stderr))
+	if _, err := p.Run(); err != nil {
+		if errors.Is(err, tea.ErrProgramKilled) && ctx.Err() != nil {
+			return jira.Issue{}, ctx.Err()
+		}
+		return jira.Issue{}, err
+	}
+	if m.picked == nil {
+		return jira.Issue{}, ErrNoPick
+	}
+	return *m.picked, nil
+}
+
+// pickerKeyMap holds the bindings of the picker. Other keys edit the query.
+type pickerKeyMap struct {
+	Up     key.Binding
+	Down   key.Binding
+	Choose key.Binding
+	Cancel key.Binding
+}
+
+// defaultPickerKeyMap returns the default picker bindings.
+func defaultPickerKeyMap() pickerKeyMap {
+	return pickerKeyMap{
+		Up:     key.NewBinding(key.WithKeys("up", "ctrl+p", "ctrl+k"), key.WithHelp("↑", "up")),
+		Down:   key.NewBinding(key.WithKeys("down", "ctrl+n", "ctrl+j"), key.WithHelp("↓", "down")),
+		Choose: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "choose")),
+		Cancel: key.NewBinding(key.WithKeys("esc", "ctrl+c"), key.WithHelp("esc", "cancel")),
+	}
+}
+
+// pickerIssuesMsg delivers the refreshed issues.
+type pickerIssuesMsg struct {
+	issues []jira.Issue
+	err    error
+}
+
+// issueSource adapts issues to fuzzy.Source, matching "KEY summary".
+type issueSource []jira.Issue
+
+// String implements fuzzy.Source.
+func (s issueSource) String(i int) string {
+	return s[i].Key + " " + s[i].Fields.Summary
+}
+
+// Len implements fuzzy.Source.
+func (s issueSource) Len() int {
+	return len(s)
+}
+
+// pickerModel is the Bubble Tea model of the picker.
+type pickerModel struct {
+	ctx  context.Context
+	opts PickOptions
+	keys pickerKeyMap
+
+	input   textinput.Model
+	spinner spinner.Model
+	styles  pickerStyles
+	width   int
+	height  int
+
+	issues  []jira.Issue
+	matches fuzzy.Matches
+	cursor  int
+	offset  int
+
+	refreshing bool
+	err        error
+	picked     *jira.Issue
+}
+
+// pickerStyles are the styles of the picker, rendered for stderr.
+type pickerStyles struct {
+	selected lipgloss.Style
+	match    lipgloss.Style
+	hint     lipgloss.Style
+	error    lipgloss.Style
+	key      lipgloss.Style
+	pane     lipgloss.Style
+}
+
+// newPickerStyles builds the styles for the terminal on stderr.
+func newPickerStyles() pickerStyles {
+	r := lipgloss.NewRenderer(os.Stderr)
+	return pickerStyles{
+		selected: r.NewStyle().Bold(true).Foreground(lipgloss.Color("5")),
+		match:    r.NewStyle().Underline(true).Foreground(lipgloss.Color("3")),
+		hint:     r.NewStyle().Foreground(lipgloss.Color("8")),
+		error:    r.NewStyle().Foreground(lipgloss.Color("1")),
+		key:      r.NewStyle().Bold(true),
+		pane: r.NewStyle().
+			Border(lipgloss.NormalBorder(), false, false, false, true).
+			BorderForeground(lipgloss.Color("8")).
+			PaddingLeft(1),
+	}
+}
+
+// newPickerModel creates the model with the cached issues.
+func newPickerModel(ctx context.Context, opts PickOptions) *pickerModel {
+	input := textinput.New()
+	input.Prompt = "> "
+	if opts.Prompt != "" {
+		input.Prompt = opts.Prompt + " > "
+	}
+	input.Placeholder = "key or summary"
+	input.Focus()
+
+	m := &pickerModel{
+		ctx:        ctx,
+		opts:       opts,
+		keys:       defaultPickerKeyMap(),
+		input:      input,
+		spinner:    spinner.New(spinner.WithSpinner(spinner.Dot)),
+		styles:     newPickerStyles(),
+		issues:     opts.Issues,
+		refreshing: opts.Refresh != nil,
+	}
+	m.filter()
+	return m
+}
+
+// Init implements tea.Model.
+func (m *pickerModel) Init() tea.Cmd {
+	cmds := []tea.Cmd{textinput.Blink}
+	if m.opts.Refresh != nil {
+		ctx, refresh := m.ctx, m.opts.Refresh
+		cmds = append(cmds, m.spinner.Tick, func() tea.Msg {
+			issues, err := refresh(ctx)
+			return pickerIssuesMsg{issues: issues, err: err}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// Update implements tea.Model.
+func (m *pickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.input.Width = max(msg.Width-len(m.input.Prompt)-1, 1)
+		m.scroll()
+		return m, nil
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.Cancel):
+			return m, tea.Quit
+		case key.Matches(msg, m.keys.Choose):
+			if issue, ok := m.choice(); ok {
+				m.picked = &issue
+				return m, tea.Quit
+			}
+			return m, nil
+		case key.Matches(msg, m.keys.Up):
+			m.move(-1)
+			return m, nil
+		case key.Matches(msg, m.keys.Down):
+			m.move(1)
+			return m, nil
+		}
+		query := m.input.Value()
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		if m.input.Value() != query {
+			m.cursor, m.offset = 0, 0
+			m.filter()
+		}
+		return m, cmd
+
+	case pickerIssuesMsg:
+		m.refreshing = false
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		current := m.current()
+		m.issues = msg.issues
+		m.filter()
+		for i, match := range m.matches {
+			if m.issues[match.Index].Key == current {
+				m.cursor = i
+			}
+		}
+		m.scroll()
+		return m, nil
+
+	case spinner.TickMsg:
+		if !m.refreshing {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// filter matches the issues against the query. An empty query keeps the
+// issues in cache order.
+func (m *pickerModel) filter() {
+	query := strings.TrimSpace(m.input.Value())
+	if query == "" {
+		m.matches = make(fuzzy.Matches, len(m.issues))
+		for i := range m.issues {
+			m.matches[i] = fuzzy.Match{Str: issueSource(m.issues).String(i), Index: i}
+		}
+	} else {
+		m.matches = fuzzy.FindFrom(query, issueSource(m.issues))
+	}
+	m.cursor = min(m.cursor, max(len(m.matches)-1, 0))
+}
+
+// current returns the key of the highlighted issue, or "".
+func (m *pickerModel) current() string {
+	if m.cursor < len(m.matches) {
+		return m.issues[m.matches[m.cursor].Index].Key
+	}
+	return ""
+}
+
+// choice returns the highlighted issue, or the key typed in full when
+// nothing matches it.
+func (m *pickerModel) choice() (jira.Issue, bool) {
+	query := strings.TrimSpace(m.input.Value())
+	if typedKeyPattern.MatchString(query) {
+		typed := strings.ToUpper(query)
+		for _, match := range m.matches {
+			if m.issues[match.Index].Key == typed {
+				return m.issues[match.Index], true
+			}
+		}
+		if len(m.matches) == 0 {
+			return jira.Issue{Key: typed}, true
+		}
+	}
+	if m.cursor < len(m.matches) {
+		return m.issues[m.matches[m.cursor].Index], true
+	}
+	return jira.Issue{}, false
+}
+
+// move moves the cursor by delta, keeping it in the list.
+func (m *pickerModel) move(delta int) {
+	m.cursor = max(min(m.cursor+delta, len(m.matches)-1), 0)
+	m.scroll()
+}
+
+// rows returns the number of result lines that fit.
+func (m *pickerModel) rows() int {
+	return max(m.height-3, 1)
+}
+
+// scroll keeps the cursor within the visible rows.
+func (m *pickerModel) scroll() {
+	switch rows := m.rows(); {
+	case m.cursor < m.offset:
+		m.offset = m.cursor
+	case m.cursor >= m.offset+rows:
+		m.offset = m.cursor - rows + 1
+	}
+}
+
+// paneWidths splits the window between the results and the preview.
+func (m *pickerModel) paneWidths() (int, int) {
+	if m.width < 60 {
+		return m.width, 0
+	}
+	left := m.width / 2
+	return left, m.width - left - m.styles.pane.GetHorizontalFrameSize()
+}
+
+// View implements tea.Model.
+func (m *pickerModel) View() string {
+	if m.width == 0 {
+		return ""
+	}
+
+	left, right := m.paneWidths()
+	lines := make([]string, 0, m.rows())
+	for i := m.offset; i < len(m.matches) && i < m.offset+m.rows(); i++ {
+		lines = append(lines, m.resultLine(m.matches[i], i == m.cursor, left))
+	}
+	if len(m.matches) == 0 {
+		hint := "No matching issues"
+		if typedKeyPattern.MatchString(strings.TrimSpace(m.input.Value())) {
+			hint = "enter opens " + strings.ToUpper(strings.TrimSpace(m.input.Value()))
+		}
+		lines = append(lines, m.styles.hint.Render(hint))
+	}
+
+	body := lipgloss.NewStyle().Width(left).Height(m.rows()).Render(strings.Join(lines, "\n"))
+	if right > 0 && m.cursor < len(m.matches) {
+		preview := m.preview(m.issues[m.matches[m.cursor].Index], right)
+		body = lipgloss.JoinHorizontal(lipgloss.Top, body, m.styles.pane.Height(m.rows()).Render(preview))
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, m.input.View(), "", body, m.statusLine())
+}
+
+// resultLine renders a match with its matched characters highlighted.
+func (m *pickerModel) resultLine(match fuzzy.Match, selected bool, width int) string {
+	prefix := "  "
+	if selected {
+		prefix = "▌ "
+	}
+	text := runewidth.Truncate(match.Str, max(width-len(prefix), 1), "…")
+
+	matched := make(map[int]bool, len(match.MatchedIndexes))
+	for _, i := range match.MatchedIndexes {
+		matched[i] = true
+	}
+	var b strings.Builder
+	for i, r := range text {
+		switch {
+		case matched[i]:
+			b.WriteString(m.styles.match.Render(string(r)))
+		case selected:
+			b.WriteString(m.styles.selected.Render(string(r)))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if selected {
+		return m.styles.selected.Render(prefix) + b.String()
+	}
+	return prefix + b.String()
+}
+
+// preview renders the fields of an issue.
+func (m *pickerModel) preview(issue jira.Issue, width int) string {
+	f := issue.Fields
+	th := m.opts.Theme
+	var lines []string
+	add := func(label, value string) {
+		if value != "" {
+			lines = append(lines, m.styles.hint.Render(label+": ")+value)
+		}
+	}
+
+	lines = append(lines, m.styles.key.Render(issue.Key))
+	if f.Summary != "" {
+		lines = append(lines, lipgloss.NewStyle().Width(width).Render(f.Summary))
+	}
+	lines = append(lines, "")
+	if f.Status.Name != "" {
+		add("Status", th.Status(f.Status))
+	}
+	add("Type", f.IssueType.Name)
+	add("Priority", f.Priority.Name)
+	if f.Summary != "" {
+		assignee := f.Assignee.DisplayName
+		if assignee == "" {
+			assignee = "Unassigned"
+		}
+		add("Assignee", assignee)
+	}
+	add("Updated", timeText(th, f.Updated))
+	add("Project", f.Project.Name)
+	return strings.Join(lines, "\n")
+}
+
+// statusLine renders the match count, the refresh state and key hints.
+func (m *pickerModel) statusLine() string {
+	var b strings.Builder
+	b.WriteString(m.styles.hint.Render(fmt.Sprintf("%d/%d · ", len(m.matches), len(m.issues))))
+	switch {
+	case m.refreshing:
+		b.WriteString(m.spinner.View() + " refreshing")
+	case m.err != nil:
+		b.WriteString(m.styles.error.Render(m.err.Error()))
+	default:
+		b.WriteString(m.styles.hint.Render("↑/↓ move · enter choose · esc cancel"))
+	}
+	return lipgloss.NewStyle().MaxWidth(m.width).Render(b.String())
+}
@@ -0,0 +1,707 @@
+// Package tui implements the interactive terminal interface of jirar ui:
+// an issue list with fuzzy filtering next to the rendered issue, and keys
+// to act on the selected issue.
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"jirar/internal/browser"
+	"jirar/internal/clipboard"
+	"jirar/internal/jira"
+	"jirar/internal/output"
+	"jirar/internal/theme"
+)
+
+// Options configure the issue browser.
+type Options struct {
+	Client jira.Client
+	// Domain is the Jira base URL used for browser links
+	Domain string
+	// Title is shown above the issue list, e.g. the view name
+	Title string
+	JQL   string
+	Limit int
+	// Theme styles statuses and times; hyperlinks should be off
+	Theme *theme.Theme
+	// Style is the glamour style of the detail pane, e.g. dark or notty
+	Style string
+	// Visited, when set, is called with each issue whose details load
+	Visited func(jira.Issue)
+}
+
+// Run starts the issue browser and blocks until the user quits or ctx is
+// cancelled. Requests in flight are cancelled when it returns.
+func Run(ctx context.Context, opts Options) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p := tea.NewProgram(newModel(ctx, opts), tea.WithAltScreen(), tea.WithContext(ctx))
+	_, err := p.Run()
+	if errors.Is(err, tea.ErrProgramKilled) && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// detailDelay debounces detail loads while moving through the list.
+const detailDelay = 150 * time.Millisecond
+
+// statusLifetime is how long status messages stay visible.
+const statusLifetime = 4 * time.Second
+
+// mode is what the right-hand pane shows.
+type mode int
+
+const (
+	modeDetail mode = iota
+	modeChoose
+	modeComment
+)
+
+// Messages delivered by asynchronous commands.
+type (
+	issuesMsg struct {
+		issues []jira.Issue
+		err    error
+	}
+	detailMsg struct {
+		key    string
+		detail *detail
+		err    error
+	}
+	detailTickMsg struct{ seq int }
+	choicesMsg    struct {
+		title   string
+		choices []choice
+		apply   func(choice) tea.Cmd
+		err     error
+	}
+	doneMsg struct {
+		key  string
+		text string
+		err  error
+	}
+	clearStatusMsg struct{ seq int }
+)
+
+// model is the Bubble Tea model of the browser.
+type model struct {
+	ctx  context.Context
+	opts Options
+	keys keyMap
+
+	list    list.Model
+	viewer  viewport.Model
+	chooser list.Model
+	input   textarea.Model
+	spinner spinner.Model
+	render  renderer
+
+	mode        mode
+	detailFocus bool
+	width       int
+	height      int
+
+	// busy counts requests in flight; the spinner runs while it is non-zero
+	busy int
+	// apply handles the choice made in the chooser
+	apply func(choice) tea.Cmd
+
+	details      map[string]*detail
+	shown        string
+	detailSeq    int
+	cancelDetail context.CancelFunc
+	// reselect is the key to select after the list reloads
+	reselect string
+
+	status    string
+	statusErr bool
+	statusSeq int
+}
+
+// newModel creates the model; the issue list loads on Init.
+func newModel(ctx context.Context, opts Options) *model {
+	keys := defaultKeyMap()
+
+	issues := list.New(nil, list.NewDefaultDelegate(), 0, 0)
+	issues.Title = opts.Title
+	issues.SetStatusBarItemName("issue", "issues")
+	issues.AdditionalShortHelpKeys = keys.actions
+	issues.AdditionalFullHelpKeys = keys.actions
+	issues.KeyMap.Quit = keys.Quit
+	if !opts.Theme.Colors() {
+		issues.Styles.Title = issues.Styles.Title.UnsetBackground().Bold(true)
+	}
+
+	chooser := list.New(nil, list.NewDefaultDelegate(), 0, 0)
+	chooser.SetShowHelp(false)
+	chooser.SetStatusBarItemName("option", "options")
+	chooser.KeyMap.Quit = key.NewBinding()
+
+	input := textarea.New()
+	input.Placeholder = "Write a comment…"
+	input.ShowLineNumbers = false
+	input.CharLimit = 0
+
+	spin := spinner.New(spinner.WithSpinner(spinner.Dot))
+
+	return &model{
+		ctx:     ctx,
+		opts:    opts,
+		keys:    keys,
+		list:    issues,
+		viewer:  viewport.New(0, 0),
+		chooser: chooser,
+		input:   input,
+		spinner: spin,
+		render:  renderer{style: opts.Style},
+		details: map[string]*detail{},
+	}
+}
+
+// Init implements tea.Model.
+func (m *model) Init() tea.Cmd {
+	return m.loadIssues()
+}
+
+// Update implements tea.Model.
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.layout()
+		m.showDetail()
+		return m, nil
+
+	case tea.KeyMsg:
+		return m, m.handleKey(msg)
+
+	case spinner.TickMsg:
+		if m.busy == 0 {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case issuesMsg:
+		m.busy--
+		if msg.err != nil {
+			return m, m.fail(msg.err)
+		}
+		items := make([]list.Item, len(msg.issues))
+		for i, issue := range msg.issues {
+			items[i] = newIssueItem(issue, m.opts.Theme)
+		}
+		cmd := m.list.SetItems(items)
+		if m.reselect != "" {
+			for i, item := range m.list.VisibleItems() {
+				if item.(issueItem).issue.Key == m.reselect {
+					m.list.Select(i)
+				}
+			}
+			m.reselect = ""
+		}
+		return m, tea.Batch(cmd, m.selectionChanged())
+
+	case detailTickMsg:
+		if msg.seq != m.detailSeq {
+			return m, nil
+		}
+		return m, m.loadDetail(m.selectedKey())
+
+	case detailMsg:
+		m.busy--
+		if msg.err != nil {
+			if errors.Is(msg.err, context.Canceled) {
+				return m, nil
+			}
+			return m, m.fail(msg.err)
+		}
+		m.details[msg.key] = msg.detail
+		if m.opts.Visited != nil {
+			m.opts.Visited(*msg.detail.issue)
+		}
+		if msg.key == m.selectedKey() {
+			m.shown = ""
+			m.showDetail()
+		}
+		return m, nil
+
+	case choicesMsg:
+		m.busy--
+		if msg.err != nil {
+			return m, m.fail(msg.err)
+		}
+		items := make([]list.Item, len(msg.choices))
+		for i, c := range msg.choices {
+			items[i] = c
+		}
+		m.chooser.Title = msg.title
+		m.chooser.ResetFilter()
+		m.chooser.Select(0)
+		m.apply = msg.apply
+		m.mode = modeChoose
+		return m, m.chooser.SetItems(items)
+
+	case doneMsg:
+		m.busy--
+		if msg.err != nil {
+			return m, m.fail(msg.err)
+		}
+		delete(m.details, msg.key)
+		m.shown = ""
+		m.reselect = msg.key
+		return m, tea.Batch(m.notify(msg.text, false), m.loadIssues())
+
+	case clearStatusMsg:
+		if msg.seq == m.statusSeq {
+			m.status = ""
+		}
+		return m, nil
+	}
+
+	return m, m.forward(msg)
+}
+
+// handleKey routes a key press to the active pane.
+func (m *model) handleKey(msg tea.KeyMsg) tea.Cmd {
+	if msg.String() == "ctrl+c" {
+		return tea.Quit
+	}
+
+	switch m.mode {
+	case modeComment:
+		switch {
+		case key.Matches(msg, m.keys.Submit):
+			return m.submitComment()
+		case msg.String() == "esc":
+			m.closePane()
+			return nil
+		}
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return cmd
+
+	case modeChoose:
+		if m.chooser.FilterState() != list.Filtering {
+			switch {
+			case key.Matches(msg, m.keys.Choose):
+				c, ok := m.chooser.SelectedItem().(choice)
+				apply := m.apply
+				m.closePane()
+				if !ok || apply == nil {
+					return nil
+				}
+				return apply(c)
+			case msg.String() == "esc" && m.chooser.FilterState() == list.Unfiltered:
+				m.closePane()
+				return nil
+			}
+		}
+		var cmd tea.Cmd
+		m.chooser, cmd = m.chooser.Update(msg)
+		return cmd
+	}
+
+	if m.list.FilterState() == list.Filtering {
+		return m.updateList(msg)
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Open):
+		return m.open()
+	case key.Matches(msg, m.keys.Transition):
+		return m.chooseTransition()
+	case key.Matches(msg, m.keys.Assign):
+		return m.chooseAssignee()
+	case key.Matches(msg, m.keys.Comment):
+		return m.startComment()
+	case key.Matches(msg, m.keys.Copy):
+		return m.copyKey()
+	case key.Matches(msg, m.keys.Refresh):
+		m.details = map[string]*detail{}
+		m.shown = ""
+		m.reselect = m.selectedKey()
+		return m.loadIssues()
+	}
+
+	if m.detailFocus {
+		switch {
+		case key.Matches(msg, m.keys.Back):
+			m.detailFocus = false
+			return nil
+		case key.Matches(msg, m.keys.Quit):
+			return tea.Quit
+		}
+		var cmd tea.Cmd
+		m.viewer, cmd = m.viewer.Update(msg)
+		return cmd
+	}
+
+	if key.Matches(msg, m.keys.Focus) && m.selectedKey() != "" {
+		m.detailFocus = true
+		return nil
+	}
+	return m.updateList(msg)
+}
+
+// forward passes other messages, such as cursor blinks and filter
+// results, to the active components.
+func (m *model) forward(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	switch m.mode {
+	case modeChoose:
+		m.chooser, cmd = m.chooser.Update(msg)
+		return cmd
+	case modeComment:
+		m.input, cmd = m.input.Update(msg)
+	}
+	return tea.Batch(cmd, m.updateList(msg))
+}
+
+// updateList updates the list and schedules a detail load when the
+// selection changed.
+func (m *model) updateList(msg tea.Msg) tea.Cmd {
+	before := m.selectedKey()
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	if m.selectedKey() != before {
+		return tea.Batch(cmd, m.selectionChanged())
+	}
+	return cmd
+}
+
+// selectionChanged shows the selected issue, loading it after a short
+// delay so scrolling through the list does not fire a request per row.
+func (m *model) selectionChanged() tea.Cmd {
+	m.detailSeq++
+	m.viewer.GotoTop()
+	key := m.selectedKey()
+	if key == "" || m.details[key] != nil {
+		m.showDetail()
+		return nil
+	}
+	m.showDetail()
+	seq := m.detailSeq
+	return tea.Tick(detailDelay, func(time.Time) tea.Msg { return detailTickMsg{seq: seq} })
+}
+
+// selectedIssue returns the selected issue, if any.
+func (m *model) selectedIssue() (jira.Issue, bool) {
+	item, ok := m.list.SelectedItem().(issueItem)
+	return item.issue, ok
+}
+
+// selectedKey returns the key of the selected issue, or "".
+func (m *model) selectedKey() string {
+	issue, _ := m.selectedIssue()
+	return issue.Key
+}
+
+// startBusy counts a request and starts the spinner if it was idle.
+func (m *model) startBusy() tea.Cmd {
+	m.busy++
+	if m.busy == 1 {
+		return m.spinner.Tick
+	}
+	return nil
+}
+
+// loadIssues runs the search.
+func (m *model) loadIssues() tea.Cmd {
+	ctx, client, opts := m.ctx, m.opts.Client, m.opts
+	load := func() tea.Msg {
+		res, err := client.SearchIssues(ctx, opts.JQL, jira.WithLimit(opts.Limit))
+		if err != nil {
+			return issuesMsg{err: err}
+		}
+		return issuesMsg{issues: res.Issues}
+	}
+	return tea.Batch(m.startBusy(), load)
+}
+
+// loadDetail fetches an issue and its comments, cancelling the previous
+// detail request.
+func (m *model) loadDetail(key string) tea.Cmd {
+	if key == "" || m.details[key] != nil {
+		return nil
+	}
+	if m.cancelDetail != nil {
+		m.cancelDetail()
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.cancelDetail = cancel
+	client := m.opts.Client
+
+	load := func() tea.Msg {
+		issue, err := client.GetIssue(ctx, key)
+		if err != nil {
+			return detailMsg{key: key, err: err}
+		}
+		comments, err := client.GetComments(ctx, key)
+		if err != nil {
+			return detailMsg{key: key, err: err}
+		}
+		return detailMsg{key: key, detail: &detail{issue: issue, comments: comments}}
+	}
+	return tea.Batch(m.startBusy(), load)
+}
+
+// chooseTransition loads the transitions of the selected issue into the
+// chooser.
+func (m *model) chooseTransition() tea.Cmd {
+	issue, ok := m.selectedIssue()
+	if !ok {
+		return nil
+	}
+	ctx, client := m.ctx, m.opts.Client
+
+	load := func() tea.Msg {
+		transitions, err := client.GetTransitions(ctx, issue.Key)
+		if err != nil {
+			return choicesMsg{err: err}
+		}
+		if len(transitions) == 0 {
+			return choicesMsg{err: fmt.Errorf("no transitions available for %s", issue.Key)}
+		}
+		choices := make([]choice, len(transitions))
+		for i, t := range transitions {
+			choices[i] = choice{id: t.ID, title: t.Name, desc: "→ " + t.To.Name}
+		}
+		return choicesMsg{
+			title:   "Transition " + issue.Key,
+			choices: choices,
+			apply: func(c choice) tea.Cmd {
+				return m.mutate(issue.Key, fmt.Sprintf("Moved %s: %s", issue.Key, c.title), func(ctx context.Context) error {
+					return client.TransitionIssue(ctx, issue.Key, c.id)
+				})
+			},
+		}
+	}
+	return tea.Batch(m.startBusy(), load)
+}
+
+// chooseAssignee loads the assignable users of the selected issue into
+// the chooser.
+func (m *model) chooseAssignee() tea.Cmd {
+	issue, ok := m.selectedIssue()
+	if !ok {
+		return nil
+	}
+	ctx, client := m.ctx, m.opts.Client
+
+	load := func() tea.Msg {
+		users, err := client.FindAssignableUsers(ctx, issue.Key, "")
+		if err != nil {
+			return choicesMsg{err: err}
+		}
+		choices := []choice{{title: "Unassigned", desc: "Remove the assignee"}}
+		for _, u := range users {
+			choices = append(choices, choice{id: u.AccountID, title: u.DisplayName, desc: u.Email})
+		}
+		return choicesMsg{
+			title:   "Assign " + issue.Key,
+			choices: choices,
+			apply: func(c choice) tea.Cmd {
+				text := fmt.Sprintf("Assigned %s to %s", issue.Key, c.title)
+				if c.id == "" {
+					text = "Unassigned " + issue.Key
+				}
+				return m.mutate(issue.Key, text, func(ctx context.Context) error {
+					return client.AssignIssue(ctx, issue.Key, c.id)
+				})
+			},
+		}
+	}
+	return tea.Batch(m.startBusy(), load)
+}
+
+// startComment opens the comment editor for the selected issue.
+func (m *model) startComment() tea.Cmd {
+	if m.selectedKey() == "" {
+		return nil
+	}
+	m.mode = modeComment
+	m.input.Reset()
+	m.layout()
+	return m.input.Focus()
+}
+
+// submitComment posts the comment being edited.
+func (m *model) submitComment() tea.Cmd {
+	text := strings.TrimSpace(m.input.Value())
+	if text == "" {
+		return m.notify("Comment is empty", true)
+	}
+	key := m.selectedKey()
+	client := m.opts.Client
+	m.closePane()
+	return m.mutate(key, "Commented on "+key, func(ctx context.Context) error {
+		_, err := client.AddComment(ctx, key, jira.NewADF(text))
+		return err
+	})
+}
+
+// mutate runs a change against an issue; the list and the issue reload
+// when it succeeds.
+func (m *model) mutate(key, text string, fn func(context.Context) error) tea.Cmd {
+	ctx := m.ctx
+	run := func() tea.Msg {
+		return doneMsg{key: key, text: text, err: fn(ctx)}
+	}
+	return tea.Batch(m.startBusy(), run)
+}
+
+// open opens the selected issue in the browser.
+func (m *model) open() tea.Cmd {
+	key := m.selectedKey()
+	if key == "" {
+		return nil
+	}
+	if err := browser.Open(output.BrowseURL(m.opts.Domain, key)); err != nil {
+		return m.fail(err)
+	}
+	return m.notify("Opened "+key, false)
+}
+
+// copyKey copies the selected issue key to the clipboard.
+func (m *model) copyKey() tea.Cmd {
+	key := m.selectedKey()
+	if key == "" {
+		return nil
+	}
+	if err := clipboard.Copy(os.Stdout, key); err != nil {
+		return m.fail(err)
+	}
+	return m.notify("Copied "+key, false)
+}
+
+// closePane returns the right-hand pane to the issue detail.
+func (m *model) closePane() {
+	m.mode = modeDetail
+	m.apply = nil
+	m.input.Blur()
+}
+
+// notify shows a status message for a while.
+func (m *model) notify(text string, isErr bool) tea.Cmd {
+	m.statusSeq++
+	m.status, m.statusErr = text, isErr
+	seq := m.statusSeq
+	return tea.Tick(statusLifetime, func(time.Time) tea.Msg { return clearStatusMsg{seq: seq} })
+}
+
+// fail shows an error in the status line.
+func (m *model) fail(err error) tea.Cmd {
+	return m.notify(err.Error(), true)
+}
+
+// Pane styles.
+var (
+	paneStyle = lipgloss.NewStyle().
+			Border(lipgloss.NormalBorder(), false, false, false, true).
+			BorderForeground(lipgloss.Color("8")).
+			PaddingLeft(1)
+	focusedPaneStyle = paneStyle.BorderForeground(lipgloss.Color("5"))
+	errorStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	hintStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+)
+
+// paneWidths splits the window between the list and the detail pane.
+func (m *model) paneWidths() (int, int) {
+	left := max(m.width*2/5, 30)
+	if left > m.width-20 {
+		left = m.width / 2
+	}
+	return left, max(m.width-left-paneStyle.GetHorizontalFrameSize(), 10)
+}
+
+// layout sizes the components to the window.
+func (m *model) layout() {
+	left, right := m.paneWidths()
+	height := max(m.height-1, 1)
+
+	m.list.SetSize(left, height)
+	m.viewer.Width, m.viewer.Height = right, height
+	m.chooser.SetSize(right, height)
+	m.input.SetWidth(right)
+	m.input.SetHeight(max(height-2, 1))
+}
+
+// showDetail renders the selected issue into the viewer.
+func (m *model) showDetail() {
+	key := m.selectedKey()
+	d := m.details[key]
+	switch {
+	case key == "":
+		m.shown = ""
+		m.viewer.SetContent(hintStyle.Render("No issues"))
+	case d == nil:
+		m.shown = ""
+		m.viewer.SetContent(hintStyle.Render("Loading " + key + "…"))
+	case m.shown != key || m.render.width != m.viewer.Width:
+		m.shown = key
+		m.viewer.SetContent(m.render.render(d.markdown(m.opts.Theme), m.viewer.Width))
+	}
+}
+
+// View implements tea.Model.
+func (m *model) View() string {
+	if m.width == 0 {
+		return ""
+	}
+
+	var right string
+	switch m.mode {
+	case modeChoose:
+		right = m.chooser.View()
+	case modeComment:
+		header := hintStyle.Render(fmt.Sprintf("Comment on %s · ctrl+s send · esc cancel", m.selectedKey()))
+		right = lipgloss.JoinVertical(lipgloss.Left, header, "", m.input.View())
+	default:
+		right = m.viewer.View()
+	}
+
+	left, _ := m.paneWidths()
+	pane := paneStyle
+	if m.detailFocus || m.mode != modeDetail {
+		pane = focusedPaneStyle
+	}
+	body := lipgloss.JoinHorizontal(lipgloss.Top,
+		lipgloss.NewStyle().Width(left).Render(m.list.View()),
+		pane.Height(max(m.height-1, 1)).Render(right),
+	)
+	return lipgloss.JoinVertical(lipgloss.Left, body, m.statusLine())
+}
+
+// statusLine renders the spinner and the latest message.
+func (m *model) statusLine() string {
+	var b strings.Builder
+	if m.busy > 0 {
+		b.WriteString(m.spinner.View() + " ")
+	}
+	switch {
+	case m.status != "" && m.statusErr:
+		b.WriteString(errorStyle.Render(m.status))
+	case m.status != "":
+		b.WriteString(m.status)
+	case m.detailFocus:
+		b.WriteString(hintStyle.Render("↑/↓ scroll · esc back to list"))
+	}
+	return lipgloss.NewStyle().MaxWidth(m.width).Render(b.String())
+}
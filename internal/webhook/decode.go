@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"jirar/internal/jira"
+	"jirar/internal/jira/watcher"
+)
+
+// Jira webhook event names that produce events.
+const (
+	issueCreated   = "jira:issue_created"
+	issueUpdated   = "jira:issue_updated"
+	commentCreated = "comment_created"
+)
+
+// payload is a Jira webhook or an Automation web request. Automation
+// requests carry the event type in "event" or the query, and either an
+// "issue" or the issue itself as with "Issue data (Jira format)".
+type payload struct {
+	WebhookEvent string        `json:"webhookEvent"`
+	Timestamp    int64         `json:"timestamp"`
+	Issue        *jira.Issue   `json:"issue"`
+	Comment      *jira.Comment `json:"comment"`
+	Changelog    struct {
+		Items []changeItem `json:"items"`
+	} `json:"changelog"`
+
+	Event string `json:"event"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Key   string `json:"key"`
+}
+
+// changeEvents maps changelog fields to the events of their changes.
+var changeEvents = map[string]watcher.EventType{
+	"status":   watcher.EventStatus,
+	"assignee": watcher.EventAssigned,
+	"priority": watcher.EventPriority,
+}
+
+// changeItem is a field change of a changelog.
+type changeItem struct {
+	Field      string `json:"field"`
+	FieldID    string `json:"fieldId"`
+	FromString string `json:"fromString"`
+	ToString   string `json:"toString"`
+}
+
+// Decode turns a webhook body into events. accountID identifies the user:
+// their own comments are skipped and comments mentioning them become
+// mention events. Webhook events without a counterpart, such as
+// jira:issue_deleted, yield no events.
+func Decode(body []byte, query url.Values, accountID string) ([]watcher.Event, error) {
+	var p payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	if p.Issue == nil && p.Key != "" {
+		p.Issue = &jira.Issue{}
+		if err := json.Unmarshal(body, p.Issue); err != nil {
+			return nil, fmt.Errorf("invalid issue: %w", err)
+		}
+	}
+	if p.Issue == nil || p.Issue.Key == "" {
+		return nil, fmt.Errorf("payload has no issue")
+	}
+
+	issue := *p.Issue
+	at := issue.Fields.Updated.Time
+	if p.Timestamp > 0 {
+		at = time.UnixMilli(p.Timestamp)
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	event := func(t watcher.EventType, from, to string) watcher.Event {
+		return watcher.Event{Type: t, Key: issue.Key, Time: at, From: from, To: to, Issue: issue}
+	}
+
+	var events []watcher.Event
+	switch p.WebhookEvent {
+	case "":
+		return automation(p, query, event("", "", ""), accountID)
+	case issueCreated:
+		if a := issue.Fields.Assignee; a.AccountID != "" && a.AccountID == accountID {
+			events = append(events, event(watcher.EventAssigned, "", a.DisplayName))
+		}
+	case issueUpdated:
+		for _, item := range p.Changelog.Items {
+			if t, ok := changeEvents[cmp.Or(item.FieldID, item.Field)]; ok {
+				events = append(events, event(t, item.FromString, item.ToString))
+			}
+		}
+	}
+	if p.Comment != nil && (p.WebhookEvent == issueUpdated || p.WebhookEvent == commentCreated) {
+		if e, ok := commentEvent(issue, p.Comment, accountID); ok {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// automation decodes an Automation web request, whose event type comes
+// from the payload or the query, e.g. ?event=status. base carries the
+// issue and time of the request.
+func automation(p payload, query url.Values, base watcher.Event, accountID string) ([]watcher.Event, error) {
+	name := p.Event
+	if name == "" {
+		name = query.Get("event")
+	}
+	t := watcher.EventType(name)
+	if !slices.Contains(watcher.EventTypes, t) {
+		return nil, fmt.Errorf("unknown event type %q (set event in the body or the query, e.g. ?event=status)", name)
+	}
+
+	f := base.Issue.Fields
+	to := p.To
+	switch t {
+	case watcher.EventComment, watcher.EventMention:
+		if p.Comment == nil {
+			return nil, fmt.Errorf("%s event has no comment", t)
+		}
+		e, ok := commentEvent(base.Issue, p.Comment, accountID)
+		if !ok {
+			return nil, nil
+		}
+		return []watcher.Event{e}, nil
+	case watcher.EventStatus:
+		to = cmp.Or(to, f.Status.Name)
+	case watcher.EventAssigned:
+		to = cmp.Or(to, f.Assignee.DisplayName)
+	case watcher.EventPriority:
+		to = cmp.Or(to, f.Priority.Name)
+	}
+	base.Type, base.From, base.To = t, p.From, to
+	return []watcher.Event{base}, nil
+}
+
+// commentEvent returns the event of a new comment, unless the user wrote
+// it.
+func commentEvent(issue jira.Issue, c *jira.Comment, accountID string) (watcher.Event, bool) {
+	if c.Author.AccountID != "" && c.Author.AccountID == accountID {
+		return watcher.Event{}, false
+	}
+	t := watcher.EventComment
+	if accountID != "" && c.Body != nil && c.Body.Mentions(accountID) {
+		t = watcher.EventMention
+	}
+	at := c.Created.Time
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return watcher.Event{Type: t, Key: issue.Key, Time: at, Comment: c, Issue: issue}, true
+}
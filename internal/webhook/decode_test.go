@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"jirar/internal/jira/watcher"
+)
+
+// Account IDs of the recorded payloads.
+const (
+	jane = "557058:jane"
+	bob  = "557058:bob"
+)
+
+// recorded reads a payload of testdata.
+func recorded(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// change is the part of an event a test checks.
+type change struct {
+	typ      watcher.EventType
+	key      string
+	from, to string
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   string
+		query     string
+		accountID string
+		want      []change
+	}{
+		{
+			name: "issue updated with a comment mentioning the user", payload: "issue_updated.json", accountID: jane,
+			want: []change{
+				{watcher.EventStatus, "PROJ-42", "To Do", "In Progress"},
+				{watcher.EventPriority, "PROJ-42", "Medium", "High"},
+				{typ: watcher.EventMention, key: "PROJ-42"},
+			},
+		},
+		{
+			name: "mentions need the user", payload: "issue_updated.json",
+			want: []change{
+				{watcher.EventStatus, "PROJ-42", "To Do", "In Progress"},
+				{watcher.EventPriority, "PROJ-42", "Medium", "High"},
+				{typ: watcher.EventComment, key: "PROJ-42"},
+			},
+		},
+		{
+			name: "the user's own comments are skipped", payload: "issue_updated.json", accountID: bob,
+			want: []change{
+				{watcher.EventStatus, "PROJ-42", "To Do", "In Progress"},
+				{watcher.EventPriority, "PROJ-42", "Medium", "High"},
+			},
+		},
+		{
+			name: "comment created", payload: "comment_created.json", accountID: jane,
+			want: []change{{typ: watcher.EventMention, key: "PROJ-42"}},
+		},
+		{
+			name: "automation event in the body", payload: "automation_status.json", accountID: jane,
+			want: []change{{watcher.EventStatus, "PROJ-7", "In Review", "Done"}},
+		},
+		{
+			name: "automation issue data with the event in the query", payload: "automation_issue.json", query: "event=assigned", accountID: jane,
+			want: []change{{typ: watcher.EventAssigned, key: "PROJ-7", to: "Jane Doe"}},
+		},
+		{
+			name: "automation comment by the user", payload: "automation_comment.json", accountID: jane,
+		},
+		{
+			name: "automation comment by others", payload: "automation_comment.json", accountID: bob,
+			want: []change{{typ: watcher.EventComment, key: "PROJ-7"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			events, err := Decode(recorded(t, tt.payload), query, tt.accountID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []change
+			for _, e := range events {
+				got = append(got, change{e.Type, e.Key, e.From, e.To})
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("events = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeTimes(t *testing.T) {
+	events, err := Decode(recorded(t, "issue_updated.json"), nil, jane)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Changes happened at the webhook timestamp, comments when written
+	if want := time.UnixMilli(1792000000000); !events[0].Time.Equal(want) {
+		t.Errorf("change at %v, want %v", events[0].Time, want)
+	}
+	if want := time.Date(2026, 10, 13, 8, 26, 40, 0, time.UTC); !events[2].Time.Equal(want) {
+		t.Errorf("comment at %v, want %v", events[2].Time, want)
+	}
+	if events[2].Comment == nil || events[2].Comment.ID != "30001" {
+		t.Errorf("comment = %+v, want 30001", events[2].Comment)
+	}
+	if events[0].Issue.Fields.Summary != "Login fails with SSO" {
+		t.Errorf("issue = %+v, want PROJ-42 as sent", events[0].Issue)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		query string
+	}{
+		{"not JSON", `{"webhookEvent":`, ""},
+		{"no issue", `{"webhookEvent":"jira:issue_updated"}`, ""},
+		{"automation without event", string(recorded(t, "automation_issue.json")), ""},
+		{"automation with an unknown event", string(recorded(t, "automation_issue.json")), "event=deleted"},
+		{"automation comment without a comment", `{"event":"comment","issue":{"key":"PROJ-1"}}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			if events, err := Decode([]byte(tt.body), query, jane); err == nil {
+				t.Errorf("Decode = %+v, want an error", events)
+			}
+		})
+	}
+}
+
+func TestDecodeIgnored(t *testing.T) {
+	body := `{"webhookEvent":"jira:issue_deleted","issue":{"key":"PROJ-1","fields":{"summary":"Gone"}}}`
+	events, err := Decode([]byte(body), nil, jane)
+	if err != nil || len(events) != 0 {
+		t.Errorf("Decode = %+v, %v, want no events", events, err)
+	}
+}
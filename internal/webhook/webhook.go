@@ -0,0 +1,156 @@
+// Package webhook receives Jira webhooks and Automation "Send web request"
+// calls and turns them into watch events, so changes arrive as they happen
+// instead of at the next poll.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"jirar/internal/jira/watcher"
+	"jirar/internal/notify"
+)
+
+// Request limits.
+const (
+	// maxBody caps the size of a payload
+	maxBody = 4 << 20
+	// seenFor is how long comment IDs are remembered, since Jira reports
+	// a comment by both jira:issue_updated and comment_created
+	seenFor = time.Hour
+)
+
+// Headers of signed and token-authenticated requests.
+const (
+	// SignatureHeader carries the HMAC-SHA256 of Jira webhooks with a
+	// secret, as "sha256=" followed by the hex digest
+	SignatureHeader = "X-Hub-Signature"
+	// TokenHeader carries the secret of Automation web requests
+	TokenHeader = "X-Automation-Webhook-Token"
+)
+
+// Options configure a Handler.
+type Options struct {
+	// Secret verifies requests, see Verify; empty accepts any request
+	Secret string
+	// AccountID identifies the user, for mentions and their own comments
+	AccountID string
+	// Handle is called for every event, one request at a time
+	Handle func(watcher.Event)
+	Logger *logrus.Logger
+}
+
+// Handler is the HTTP handler receiving webhooks.
+type Handler struct {
+	opts   Options
+	logger *logrus.Logger
+
+	mu sync.Mutex
+	// seen holds the IDs of recently reported comments
+	seen map[string]time.Time
+}
+
+// New creates a Handler.
+func New(opts Options) *Handler {
+	if opts.Logger == nil {
+		opts.Logger = logrus.StandardLogger()
+	}
+	return &Handler{opts: opts, logger: opts.Logger, seen: map[string]time.Time{}}
+}
+
+// Verify reports whether a request carries the secret: as the HMAC
+// signature of the body in X-Hub-Signature, as set by Jira webhooks with a
+// secret, or as a token in X-Automation-Webhook-Token, a bearer token or
+// the secret query parameter.
+func Verify(secret string, r *http.Request, body []byte) bool {
+	if sig := r.Header.Get(SignatureHeader); sig != "" {
+		return hmac.Equal([]byte(sig), []byte(notify.Sign(secret, body)))
+	}
+	tokens := []string{
+		r.Header.Get(TokenHeader),
+		strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "),
+		r.URL.Query().Get("secret"),
+	}
+	for _, token := range tokens {
+		if token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP accepts a POSTed payload on any path and answers with the
+// number of events it produced. GET /healthz reports readiness.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && r.URL.Path == "/healthz" {
+		io.WriteString(w, "ok\n")
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+	if err != nil {
+		http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	log := h.logger.WithFields(logrus.Fields{"remote": r.RemoteAddr, "path": r.URL.Path})
+	if h.opts.Secret != "" && !Verify(h.opts.Secret, r, body) {
+		log.Warn("Rejected webhook with a wrong or missing secret")
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	events, err := Decode(body, r.URL.Query(), h.opts.AccountID)
+	if err != nil {
+		log.WithError(err).Warn("Rejected webhook")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.mu.Lock()
+	handled := 0
+	for _, e := range events {
+		if e.Comment != nil && !h.first(e.Comment.ID) {
+			continue
+		}
+		h.opts.Handle(e)
+		handled++
+	}
+	h.mu.Unlock()
+
+	log.WithField("events", handled).Debug("Received webhook")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]int{"events": handled})
+}
+
+// first reports whether a comment is reported for the first time, and
+// forgets comments seen long ago; h.mu must be held.
+func (h *Handler) first(id string) bool {
+	now := time.Now()
+	for seen, at := range h.seen {
+		if now.Sub(at) > seenFor {
+			delete(h.seen, seen)
+		}
+	}
+	if id == "" {
+		return true
+	}
+	if _, ok := h.seen[id]; ok {
+		return false
+	}
+	h.seen[id] = now
+	return true
+}
@@ -0,0 +1,155 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"jirar/internal/jira/watcher"
+	"jirar/internal/notify"
+)
+
+const testSecret = "s3cret"
+
+// testServer serves a Handler verifying testSecret for jane and returns
+// the events it handled.
+func testServer(t *testing.T) (*httptest.Server, func() []watcher.Event) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	var mu sync.Mutex
+	var handled []watcher.Event
+	srv := httptest.NewServer(New(Options{
+		Secret:    testSecret,
+		AccountID: jane,
+		Logger:    logger,
+		Handle: func(e watcher.Event) {
+			mu.Lock()
+			handled = append(handled, e)
+			mu.Unlock()
+		},
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []watcher.Event {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(handled)
+	}
+}
+
+// post sends body to srv with the query and headers given, returning the
+// status and the events counted in the answer.
+func post(t *testing.T, srv *httptest.Server, query string, body []byte, header http.Header) (int, int) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/jira"+query, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var answer struct {
+		Events int `json:"events"`
+	}
+	if resp.StatusCode == http.StatusAccepted {
+		if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, answer.Events
+}
+
+func TestVerify(t *testing.T) {
+	body := recorded(t, "automation_status.json")
+	tests := []struct {
+		name   string
+		query  string
+		header http.Header
+		want   int
+	}{
+		{"HMAC signature", "", http.Header{SignatureHeader: {notify.Sign(testSecret, body)}}, http.StatusAccepted},
+		{"HMAC signature of another body", "", http.Header{SignatureHeader: {notify.Sign(testSecret, []byte("{}"))}}, http.StatusUnauthorized},
+		{"HMAC signature with another secret", "", http.Header{SignatureHeader: {notify.Sign("other", body)}}, http.StatusUnauthorized},
+		{"wrong signature despite a valid token", "", http.Header{SignatureHeader: {"sha256=00"}, TokenHeader: {testSecret}}, http.StatusUnauthorized},
+		{"automation token header", "", http.Header{TokenHeader: {testSecret}}, http.StatusAccepted},
+		{"wrong automation token", "", http.Header{TokenHeader: {"guess"}}, http.StatusUnauthorized},
+		{"bearer token", "", http.Header{"Authorization": {"Bearer " + testSecret}}, http.StatusAccepted},
+		{"wrong bearer token", "", http.Header{"Authorization": {"Bearer guess"}}, http.StatusUnauthorized},
+		{"query secret", "?secret=" + testSecret, nil, http.StatusAccepted},
+		{"wrong query secret", "?secret=guess", nil, http.StatusUnauthorized},
+		{"no secret", "", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, handled := testServer(t)
+			status, _ := post(t, srv, tt.query, body, tt.header)
+			if status != tt.want {
+				t.Errorf("status = %d, want %d", status, tt.want)
+			}
+			if n := len(handled()); (status == http.StatusAccepted) != (n == 1) {
+				t.Errorf("handled %d events with status %d", n, status)
+			}
+		})
+	}
+}
+
+func TestServeCommentsOnce(t *testing.T) {
+	srv, handled := testServer(t)
+	auth := http.Header{TokenHeader: {testSecret}}
+
+	// Jira reports a comment by both events; only the first counts
+	if status, n := post(t, srv, "", recorded(t, "issue_updated.json"), auth); status != http.StatusAccepted || n != 3 {
+		t.Fatalf("issue updated = %d with %d events, want 202 with 3", status, n)
+	}
+	if status, n := post(t, srv, "", recorded(t, "comment_created.json"), auth); status != http.StatusAccepted || n != 0 {
+		t.Fatalf("comment created = %d with %d events, want 202 with 0", status, n)
+	}
+	events := handled()
+	if len(events) != 3 || events[2].Type != watcher.EventMention {
+		t.Errorf("handled %+v, want status, priority and mention", events)
+	}
+}
+
+func TestServeRejects(t *testing.T) {
+	srv, handled := testServer(t)
+	auth := http.Header{TokenHeader: {testSecret}}
+
+	if status, _ := post(t, srv, "", []byte(`{"webhookEvent":`), auth); status != http.StatusBadRequest {
+		t.Errorf("invalid payload = %d, want 400", status)
+	}
+	if status, _ := post(t, srv, "?event=deleted", recorded(t, "automation_issue.json"), auth); status != http.StatusBadRequest {
+		t.Errorf("unknown event = %d, want 400", status)
+	}
+	resp, err := srv.Client().Get(srv.URL + "/jira")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET = %d, want 405", resp.StatusCode)
+	}
+	resp, err = srv.Client().Get(srv.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /healthz = %d, want 200", resp.StatusCode)
+	}
+	if events := handled(); len(events) != 0 {
+		t.Errorf("handled %+v, want none", events)
+	}
+}